@@ -14,6 +14,8 @@ import (
 	"analytics-service/internal/handlers"
 	"analytics-service/internal/middleware"
 	"analytics-service/internal/services"
+	"analytics-service/pkg/ratelimit"
+	"analytics-service/pkg/sessions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -35,6 +37,19 @@ func main() {
 	}
 	defer postgresDB.Close()
 
+	// Connect to Redis (shared rate limit counters)
+	redisClient := database.ConnectRedis(cfg)
+	defer redisClient.Close()
+
+	rateLimits, err := ratelimit.ParsePolicies(cfg.RateLimits)
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMITS:", err)
+	}
+	limiter := ratelimit.New(redisClient, cfg.ServiceName, rateLimits)
+
+	// Revoked sessions are marked in Redis by auth-service
+	sessionChecker := sessions.NewChecker(redisClient)
+
 	// Initialize services (without ClickHouse)
 	analyticsService := services.NewAnalyticsService(postgresDB, nil)
 	logService := services.NewLogService(nil)
@@ -52,6 +67,9 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORSMiddleware())
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -60,6 +78,7 @@ func main() {
 
 	// Public routes for Grafana
 	public := router.Group("/api/v1/metrics")
+	public.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByIP))
 	{
 		public.GET("/dashboard", metricsHandler.GetDashboardMetrics)
 		public.GET("/users", metricsHandler.GetUserMetrics)
@@ -69,7 +88,9 @@ func main() {
 
 	// Protected API routes
 	api := router.Group("/api/v1")
-	api.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	api.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByIP))
+	api.Use(middleware.AuthMiddleware(cfg.JWTSecret, sessionChecker))
+	api.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByUser))
 	{
 		// Analytics routes
 		api.GET("/analytics/overview", analyticsHandler.GetOverview)
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -23,8 +24,22 @@ type Config struct {
 	ClickHousePassword string
 	ClickHouseDB       string
 
+	// Redis
+	RedisHost     string
+	RedisPort     string
+	RedisPassword string
+
 	// JWT
 	JWTSecret string
+
+	// Proxies whose X-Forwarded-For is believed when taking the client IP.
+	// None by default: set it to the address of the reverse proxy in front
+	// of the service. Trusting whole private ranges would let anyone reaching
+	// a published port through the Docker gateway pick their own IP.
+	TrustedProxies []string
+
+	// Rate limit policies in the pkg/ratelimit format; empty for the defaults
+	RateLimits string
 }
 
 func Load() *Config {
@@ -41,7 +56,12 @@ func Load() *Config {
 		ClickHouseUser:     getEnv("CLICKHOUSE_USER", "default"),
 		ClickHousePassword: getEnv("CLICKHOUSE_PASSWORD", ""),
 		ClickHouseDB:       getEnv("CLICKHOUSE_DB", "fintrack_analytics"),
+		RedisHost:          getEnv("REDIS_HOST", "redis"),
+		RedisPort:          getEnv("REDIS_PORT", "6379"),
+		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
 		JWTSecret:          getEnv("JWT_SECRET", ""),
+		TrustedProxies:     splitList(getEnv("TRUSTED_PROXIES", "")),
+		RateLimits:         getEnv("RATE_LIMITS", ""),
 	}
 }
 
@@ -51,3 +71,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
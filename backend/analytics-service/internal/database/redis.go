@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"analytics-service/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ConnectRedis doesn't fail without Redis: it only holds the shared rate
+// limit counters and revoked sessions. Until Redis is up the limiter counts
+// in memory and sessions aren't checked for revocation.
+func ConnectRedis(cfg *config.Config) *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort),
+		Password: cfg.RedisPassword,
+		DB:       0,
+	})
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Printf("Failed to connect to Redis, rate limits are kept in memory: %v", err)
+		return client
+	}
+
+	log.Println("Successfully connected to Redis")
+	return client
+}
@@ -1,15 +1,17 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
 	"analytics-service/pkg/jwt"
+	"analytics-service/pkg/sessions"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(jwtSecret string, sessionChecker *sessions.Checker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -40,9 +42,43 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		// A token without a session couldn't be revoked
+		if claims.SessionID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		revoked, err := sessionChecker.Revoked(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			// Fail closed for staff, whose sessions reach other users' data;
+			// fail open for everyone else rather than take the service down
+			// with Redis
+			if claims.Role != jwt.RoleUser {
+				log.Printf("Refusing %s session while sessions can't be checked: %v", claims.Role, err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Session check unavailable",
+				})
+				c.Abort()
+				return
+			}
+			log.Printf("Letting session through unchecked: %v", err)
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("sessionID", claims.SessionID)
 
 		c.Next()
 	}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"analytics-service/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware applies the limiter's policies of one kind: by client
+// IP on any route, or by user after AuthMiddleware.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, by ratelimit.KeyKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := c.ClientIP() // honours the trusted proxies set on the router
+		if by == ratelimit.ByUser {
+			subject = c.GetString("userID")
+			if subject == "" {
+				c.Next()
+				return
+			}
+		}
+
+		result := limiter.Allow(c.Request.Context(), by, subject, c.Request.Method, c.FullPath())
+		if result == nil {
+			c.Next()
+			return
+		}
+
+		ratelimit.SetHeaders(c.Writer.Header(), result)
+		if !result.Allowed {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests",
+				"retry_after": int(result.RetryAfter.Seconds()),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -524,10 +524,76 @@ func (s *AnalyticsService) GetInsights(ctx context.Context, userID string) ([]*m
 		log.Printf("Added insight: Savings rate - %.2f%%", savingsRate)
 	}
 
+	// Insight 4: Savings goals falling behind schedule
+	insights = append(insights, s.getGoalInsights(ctx, userID)...)
+
 	log.Printf("=== GetInsights END - Generated %d insights ===", len(insights))
 	return insights, nil
 }
 
+func (s *AnalyticsService) getGoalInsights(ctx context.Context, userID string) []*models.Insight {
+	insights := []*models.Insight{}
+
+	rows, err := s.postgresDB.QueryContext(ctx, `
+        SELECT g.name, g.target_amount, g.target_date, g.created_at, COALESCE(SUM(gc.amount), 0)
+        FROM goals g
+        LEFT JOIN goal_contributions gc ON gc.goal_id = g.id
+        WHERE g.user_id = $1 AND g.target_date IS NOT NULL
+        GROUP BY g.id, g.name, g.target_amount, g.target_date, g.created_at`,
+		userID)
+	if err != nil {
+		log.Printf("Error getting goals: %v", err)
+		return insights
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	for rows.Next() {
+		var name string
+		var target, saved float64
+		var targetDate, createdAt time.Time
+		if err := rows.Scan(&name, &target, &targetDate, &createdAt, &saved); err != nil {
+			log.Printf("Error scanning goal: %v", err)
+			continue
+		}
+
+		if saved >= target {
+			continue
+		}
+
+		// Expected progress on a linear schedule from creation to the target date
+		expected := target
+		if total := targetDate.Sub(createdAt); total > 0 && now.Before(targetDate) {
+			expected = target * float64(now.Sub(createdAt)) / float64(total)
+		}
+
+		if saved >= expected*0.9 {
+			continue
+		}
+
+		monthsLeft := math.Max(targetDate.Sub(now).Hours()/24/30.44, 1)
+		required := (target - saved) / monthsLeft
+
+		priority := "medium"
+		if now.After(targetDate) || saved < expected*0.5 {
+			priority = "high"
+		}
+
+		insights = append(insights, &models.Insight{
+			Type:  "goal_behind_schedule",
+			Title: "Цель отстаёт от графика",
+			Description: fmt.Sprintf("Цель '%s': накоплено %.0f ₽ из %.0f ₽. Чтобы успеть к %s, откладывайте %.0f ₽ в месяц",
+				name, saved, target, targetDate.Format("02.01.2006"), required),
+			Value:    required,
+			Priority: priority,
+			Date:     now,
+		})
+		log.Printf("Added insight: Goal behind schedule - %s", name)
+	}
+
+	return insights
+}
+
 func (s *AnalyticsService) GetCashflow(ctx context.Context, userID string, startDate, endDate time.Time) ([]*models.Cashflow, error) {
 	// Simplified implementation
 	return []*models.Cashflow{}, nil
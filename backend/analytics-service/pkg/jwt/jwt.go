@@ -7,12 +7,25 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims of an access token. The token's own ID is the registered jti
+// claim; SessionID is the login session it belongs to. Tokens issued before
+// sessions existed have none and are rejected, since they can't be revoked.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Roles a user can hold. Tokens issued before roles existed carry no role
+// and are treated as RoleUser.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 func ValidateToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -30,5 +43,9 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+
 	return claims, nil
 }
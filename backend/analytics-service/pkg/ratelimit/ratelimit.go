@@ -0,0 +1,373 @@
+// Package ratelimit limits request rates with sliding window counters kept
+// in Redis, so every replica of a service counts against the same limits.
+// While Redis is unavailable each replica counts on its own in memory.
+//
+// The same package is copied into every service; keep the copies in sync.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyKind is what a policy counts requests by.
+type KeyKind string
+
+const (
+	ByIP   KeyKind = "ip"
+	ByUser KeyKind = "user"
+)
+
+// DefaultPolicies apply to all services unless RATE_LIMITS is set. Each entry
+// is "<ip|user> <limit>/<window> [<METHOD> <route>]"; entries without a route
+// cover every route of the service. Routes are Gin route patterns, a trailing
+// * matches every route under the prefix. Set RATE_LIMITS=off to disable.
+const DefaultPolicies = `
+ip   600/1m;
+user 300/1m;
+
+ip   20/1m  POST /api/v1/auth/login;
+ip   10/1m  POST /api/v1/auth/register;
+ip   20/1m  POST /api/v1/auth/verify-email;
+ip   5/1m   POST /api/v1/auth/resend-code;
+
+user 60/1m  GET  /api/v1/transactions;
+user 10/1m  GET  /api/v1/logs/export;
+user 10/1m  GET  /api/v1/logs/all/export;
+user 10/1m  GET  /api/v1/export/*;
+`
+
+// Policy allows Limit requests per Window for every IP or user.
+type Policy struct {
+	By     KeyKind
+	Limit  int
+	Window time.Duration
+	Method string // empty when the policy covers every route
+	Route  string
+}
+
+func (p Policy) String() string {
+	s := fmt.Sprintf("%s %d/%s", p.By, p.Limit, p.Window)
+	if p.Route != "" {
+		s += " " + p.Method + " " + p.Route
+	}
+	return s
+}
+
+func (p Policy) matches(method, route string) bool {
+	if p.Route == "" {
+		return true
+	}
+	if p.Method != method {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(p.Route, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return p.Route == route
+}
+
+// ParsePolicies reads policies in the DefaultPolicies format. An empty spec
+// gives the default policies, "off" none.
+func ParsePolicies(spec string) ([]Policy, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultPolicies
+	}
+	if strings.TrimSpace(spec) == "off" {
+		return nil, nil
+	}
+
+	policies := []Policy{}
+	entries := strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' })
+	for _, entry := range entries {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 && len(fields) != 4 {
+			return nil, fmt.Errorf("invalid rate limit %q", strings.TrimSpace(entry))
+		}
+
+		policy := Policy{By: KeyKind(fields[0])}
+		if policy.By != ByIP && policy.By != ByUser {
+			return nil, fmt.Errorf("invalid rate limit %q: key must be ip or user", strings.TrimSpace(entry))
+		}
+
+		limit, window, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: expected <limit>/<window>", strings.TrimSpace(entry))
+		}
+		var err error
+		if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: bad limit", strings.TrimSpace(entry))
+		}
+		if policy.Window, err = time.ParseDuration(window); err != nil || policy.Window < time.Second {
+			return nil, fmt.Errorf("invalid rate limit %q: window must be at least 1s", strings.TrimSpace(entry))
+		}
+
+		if len(fields) == 4 {
+			policy.Method = strings.ToUpper(fields[2])
+			policy.Route = fields[3]
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// Result is the outcome of a request against the policies that cover it.
+type Result struct {
+	Allowed    bool
+	Policy     Policy        // the policy closest to its limit
+	Remaining  int           // requests left under Policy
+	Reset      time.Duration // until Policy's current window ends
+	RetryAfter time.Duration // set when the request is refused
+}
+
+// window is one policy's pair of counters around the current time. The
+// previous window's count is weighted by how much of it still falls within
+// the sliding window.
+type window struct {
+	policy  Policy
+	curr    string
+	prev    string
+	weight  float64
+	elapsed time.Duration
+}
+
+// Both counters are read first and the request counted only when every
+// policy allows it, so refused requests don't use up the limits.
+var takeScript = redis.NewScript(`
+local counts = {}
+local allowed = 1
+for i = 1, #KEYS / 2 do
+	local curr = tonumber(redis.call('GET', KEYS[2 * i - 1]) or '0')
+	local prev = tonumber(redis.call('GET', KEYS[2 * i]) or '0')
+	counts[2 * i] = curr
+	counts[2 * i + 1] = prev
+	if prev * tonumber(ARGV[3 * i - 1]) + curr >= tonumber(ARGV[3 * i - 2]) then
+		allowed = 0
+	end
+end
+if allowed == 1 then
+	for i = 1, #KEYS / 2 do
+		redis.call('INCR', KEYS[2 * i - 1])
+		redis.call('PEXPIRE', KEYS[2 * i - 1], ARGV[3 * i])
+	end
+end
+counts[1] = allowed
+return counts
+`)
+
+type Limiter struct {
+	redis    *redis.Client // nil keeps the counters in memory only
+	service  string
+	policies []Policy
+	memory   *memoryStore
+	degraded atomic.Bool
+}
+
+func New(redisClient *redis.Client, service string, policies []Policy) *Limiter {
+	return &Limiter{
+		redis:    redisClient,
+		service:  service,
+		policies: policies,
+		memory:   &memoryStore{counters: map[string]*memoryCounter{}},
+	}
+}
+
+// Allow counts a request from subject (an IP or a user ID) to the route
+// against the policies of the kind that cover it. It returns nil when no
+// policy does.
+func (l *Limiter) Allow(ctx context.Context, by KeyKind, subject, method, route string) *Result {
+	now := time.Now()
+
+	windows := []window{}
+	for _, policy := range l.policies {
+		if policy.By != by || !policy.matches(method, route) {
+			continue
+		}
+		size := policy.Window.Milliseconds()
+		index := now.UnixMilli() / size
+		elapsed := time.Duration(now.UnixMilli()-index*size) * time.Millisecond
+		prefix := fmt.Sprintf("ratelimit:%s:%s:%d", l.service, policy, index)
+		prevPrefix := fmt.Sprintf("ratelimit:%s:%s:%d", l.service, policy, index-1)
+		windows = append(windows, window{
+			policy:  policy,
+			curr:    prefix + ":" + subject,
+			prev:    prevPrefix + ":" + subject,
+			weight:  1 - float64(elapsed)/float64(policy.Window),
+			elapsed: elapsed,
+		})
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	allowed, counts := l.take(ctx, windows, now)
+	return result(windows, allowed, counts)
+}
+
+func (l *Limiter) take(ctx context.Context, windows []window, now time.Time) (bool, []int64) {
+	if l.redis == nil {
+		return l.memory.take(windows, now)
+	}
+
+	keys := make([]string, 0, 2*len(windows))
+	args := make([]interface{}, 0, 3*len(windows))
+	for _, w := range windows {
+		keys = append(keys, w.curr, w.prev)
+		// Kept for two windows: the next window still weighs this one.
+		args = append(args, w.policy.Limit, strconv.FormatFloat(w.weight, 'f', 6, 64), (2 * w.policy.Window).Milliseconds())
+	}
+
+	values, err := takeScript.Run(ctx, l.redis, keys, args...).Int64Slice()
+	if err != nil {
+		if l.degraded.CompareAndSwap(false, true) {
+			log.Printf("Rate limiter: Redis unavailable, counting in memory: %v", err)
+		}
+		return l.memory.take(windows, now)
+	}
+	if l.degraded.CompareAndSwap(true, false) {
+		log.Println("Rate limiter: Redis is back")
+	}
+	return values[0] == 1, values[1:]
+}
+
+// result picks the policy to report from the counts read before the request
+// was counted: the one with the fewest requests left, or when refused, the
+// one that takes longest to allow requests again.
+func result(windows []window, allowed bool, counts []int64) *Result {
+	var res *Result
+	for i, w := range windows {
+		curr, prev := counts[2*i], counts[2*i+1]
+		used := float64(prev)*w.weight + float64(curr)
+
+		r := &Result{
+			Allowed: allowed,
+			Policy:  w.policy,
+			Reset:   w.policy.Window - w.elapsed,
+		}
+		if allowed {
+			used++
+		}
+		r.Remaining = max(w.policy.Limit-int(math.Ceil(used)), 0)
+		if !allowed && used >= float64(w.policy.Limit) {
+			r.RetryAfter = retryAfter(w, curr, prev)
+		}
+
+		switch {
+		case res == nil:
+			res = r
+		case !allowed && r.RetryAfter > res.RetryAfter:
+			res = r
+		case allowed && r.Remaining < res.Remaining:
+			res = r
+		}
+	}
+	return res
+}
+
+// retryAfter is how long until the weighted count of a full window drops
+// below the limit.
+func retryAfter(w window, curr, prev int64) time.Duration {
+	limit := float64(w.policy.Limit)
+	size := float64(w.policy.Window)
+
+	var wait float64
+	if float64(curr) < limit {
+		// The previous window still weighs too much
+		wait = size*(1-(limit-float64(curr))/float64(prev)) - float64(w.elapsed)
+	} else {
+		// Wait for the next window, where this one weighs less
+		wait = size - float64(w.elapsed) + size*(1-limit/float64(curr))
+	}
+
+	retry := time.Duration(math.Ceil(wait/float64(time.Second))) * time.Second
+	return max(retry, time.Second)
+}
+
+// SetHeaders reports the result in RateLimit-* headers, and Retry-After when
+// the request is refused. A result with more requests left than the one
+// already reported is not shown.
+func SetHeaders(header http.Header, r *Result) {
+	if reported := header.Get("RateLimit-Remaining"); reported != "" {
+		if remaining, err := strconv.Atoi(reported); err == nil && remaining < r.Remaining {
+			return
+		}
+	}
+
+	header.Set("RateLimit-Limit", strconv.Itoa(r.Policy.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(r.Reset.Seconds()))))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", r.Policy.Limit, int(r.Policy.Window.Seconds())))
+	if !r.Allowed {
+		header.Set("Retry-After", strconv.Itoa(int(r.RetryAfter.Seconds())))
+	}
+}
+
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+// memoryStore is the fallback for when Redis is unavailable. Its limits
+// hold per replica only.
+type memoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+}
+
+func (m *memoryStore) take(windows []window, now time.Time) (bool, []int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > time.Minute {
+		for key, counter := range m.counters {
+			if now.After(counter.expires) {
+				delete(m.counters, key)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	count := func(key string) int64 {
+		if counter, ok := m.counters[key]; ok && now.Before(counter.expires) {
+			return counter.count
+		}
+		return 0
+	}
+
+	allowed := true
+	counts := make([]int64, 0, 2*len(windows))
+	for _, w := range windows {
+		curr, prev := count(w.curr), count(w.prev)
+		counts = append(counts, curr, prev)
+		if float64(prev)*w.weight+float64(curr) >= float64(w.policy.Limit) {
+			allowed = false
+		}
+	}
+
+	if allowed {
+		for _, w := range windows {
+			counter, ok := m.counters[w.curr]
+			if !ok || !now.Before(counter.expires) {
+				counter = &memoryCounter{}
+				m.counters[w.curr] = counter
+			}
+			counter.count++
+			counter.expires = now.Add(2 * w.policy.Window)
+		}
+	}
+	return allowed, counts
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,6 +15,10 @@ import (
 	"api-service/internal/handlers"
 	"api-service/internal/middleware"
 	"api-service/internal/services"
+	"api-service/pkg/events"
+	"api-service/pkg/jwt"
+	"api-service/pkg/ratelimit"
+	"api-service/pkg/sessions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -35,44 +40,144 @@ func main() {
 	}
 	defer db.Close()
 
+	// "main verify-audit [user_id]" checks the audit hash chains and exits
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		code := verifyAudit(db, cfg, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
 	// Connect to Redis
 	redisClient := database.ConnectRedis(cfg)
 	defer redisClient.Close()
 
+	// Rate limit counters are shared through Redis by all replicas
+	rateLimits, err := ratelimit.ParsePolicies(cfg.RateLimits)
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMITS:", err)
+	}
+	limiter := ratelimit.New(redisClient, cfg.ServiceName, rateLimits)
+
+	// Revoked sessions are marked in Redis by auth-service
+	sessionChecker := sessions.NewChecker(redisClient)
+
+	// Domain events are published to Redis Streams
+	publisher := events.NewPublisher(redisClient, cfg.ServiceName)
+
 	// Initialize services
-	logService := services.NewLogService(db)
-	transactionService := services.NewTransactionService(db, logService)
-	accountService := services.NewAccountService(db, logService)
-	categoryService := services.NewCategoryService(db, logService)
-	statsService := services.NewStatsService(db)
+	logService := services.NewLogService(db, cfg)
+	statsCache := services.NewStatsCache(redisClient, cfg)
+	suggestionService := services.NewSuggestionService(db)
+	payeeService := services.NewPayeeService(db, logService)
+	transactionService := services.NewTransactionService(db, logService, suggestionService, payeeService, publisher, statsCache)
+	accountService := services.NewAccountService(db, logService, publisher, statsCache)
+	categoryService := services.NewCategoryService(db, logService, statsCache)
+	statsService := services.NewStatsService(db, statsCache)
+	historyService := services.NewHistoryService(db)
+	budgetService := services.NewBudgetService(db, logService)
+	envelopeService := services.NewEnvelopeService(db, logService)
+	goalService := services.NewGoalService(db, logService)
+	debtService := services.NewDebtService(db, logService, statsCache)
+	loanService := services.NewLoanService(db, logService, statsCache)
+	billService := services.NewBillService(db, logService)
+	accountMemberService := services.NewAccountMemberService(db, logService, statsCache)
+	workspaceService := services.NewWorkspaceService(db, logService)
+	webhookService, err := services.NewWebhookService(db, logService, cfg)
+	if err != nil {
+		log.Fatal("Invalid WEBHOOK_ALLOWED_NETWORKS:", err)
+	}
+	outboxRelay := services.NewOutboxRelay(db, logService, publisher)
+	auditChainService := services.NewAuditChainService(db, cfg)
+
+	// Background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Audit entries and events are written to the outbox with each change
+	// and delivered by the relay
+	runWorker(outboxRelay.Run)
+
+	// Webhooks are queued from the event streams and sent by the dispatcher
+	hostname, _ := os.Hostname()
+	for _, stream := range []string{events.Stream(events.TransactionCreated), events.Stream(events.AccountCreated)} {
+		consumer := events.NewConsumer(redisClient, events.ConsumerConfig{
+			Stream:   stream,
+			Group:    "webhooks",
+			Consumer: hostname,
+		}, webhookService.HandleEvent)
+		runWorker(func(ctx context.Context) {
+			if err := consumer.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Webhook consumer stopped: %v", err)
+			}
+		})
+	}
+	runWorker(webhookService.Run)
+
+	// Audit hash chains are anchored with signed checkpoints
+	runWorker(auditChainService.Run)
 
 	// Initialize handlers
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 	accountHandler := handlers.NewAccountHandler(accountService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	statsHandler := handlers.NewStatsHandler(statsService)
-	logHandler := handlers.NewLogHandler(logService)
+	statsHandler := handlers.NewStatsHandler(statsService, statsCache)
+	logHandler := handlers.NewLogHandler(logService, auditChainService)
+	suggestionHandler := handlers.NewSuggestionHandler(suggestionService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
+	envelopeHandler := handlers.NewEnvelopeHandler(envelopeService)
+	goalHandler := handlers.NewGoalHandler(goalService)
+	payeeHandler := handlers.NewPayeeHandler(payeeService)
+	debtHandler := handlers.NewDebtHandler(debtService)
+	loanHandler := handlers.NewLoanHandler(loanService)
+	billHandler := handlers.NewBillHandler(billService)
+	accountMemberHandler := handlers.NewAccountMemberHandler(accountMemberService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+	outboxHandler := handlers.NewOutboxHandler(outboxRelay)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	historyHandler := handlers.NewHistoryHandler(historyService, transactionService, accountService, categoryService)
 
 	// Setup Gin router
 	router := gin.New()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.AuditContextMiddleware())
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "api-service"})
 	})
 
-	// ✅ ДОБАВЬ ЭТО: Internal routes (для других микросервисов)
+	// Internal routes (для других микросервисов), подписанные SERVICE_SECRET
+	if cfg.ServiceSecret == "" {
+		log.Println("SERVICE_SECRET is not set, internal routes will reject all requests")
+	}
+
 	internal := router.Group("/api/v1/internal")
+	internal.Use(middleware.ServiceAuthMiddleware(cfg.ServiceSecret, redisClient))
 	{
 		internal.POST("/logs", logHandler.LogInternalAction)
 	}
 
+	// Public calendar feed, authorised by the secret token in the URL
+	router.GET("/api/v1/calendar/:token", middleware.RateLimitMiddleware(limiter, ratelimit.ByIP), billHandler.GetCalendarFeed)
+
 	// Protected API routes
 	api := router.Group("/api/v1")
-	api.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	api.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByIP))
+	api.Use(middleware.AuthMiddleware(cfg.JWTSecret, sessionChecker))
+	api.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByUser))
+	api.Use(middleware.WorkspaceMiddleware(workspaceService.VerifyMember))
 	{
 		// Transaction routes
 		api.POST("/transactions", transactionHandler.CreateTransaction)
@@ -80,6 +185,7 @@ func main() {
 		api.GET("/transactions/:id", transactionHandler.GetTransaction)
 		api.PUT("/transactions/:id", transactionHandler.UpdateTransaction)
 		api.DELETE("/transactions/:id", transactionHandler.DeleteTransaction)
+		api.GET("/transactions/:id/history", historyHandler.GetTransactionHistory)
 
 		// Account routes
 		api.POST("/accounts", accountHandler.CreateAccount)
@@ -88,24 +194,136 @@ func main() {
 		api.PUT("/accounts/:id", accountHandler.UpdateAccount)
 		api.DELETE("/accounts/:id", accountHandler.DeleteAccount)
 		api.POST("/accounts/:id/set-default", accountHandler.SetDefaultAccount)
+		api.GET("/accounts/:id/history", historyHandler.GetAccountHistory)
+		api.GET("/accounts/:id/members", accountMemberHandler.GetMembers)
+		api.POST("/accounts/:id/members", accountMemberHandler.InviteMember)
+		api.PUT("/accounts/:id/members/:userId", accountMemberHandler.UpdateMember)
+		api.DELETE("/accounts/:id/members/:userId", accountMemberHandler.RemoveMember)
+
+		// Workspace routes
+		api.POST("/workspaces", workspaceHandler.CreateWorkspace)
+		api.GET("/workspaces", workspaceHandler.GetWorkspaces)
+		api.GET("/workspaces/:id", workspaceHandler.GetWorkspace)
+		api.PUT("/workspaces/:id", workspaceHandler.UpdateWorkspace)
+		api.DELETE("/workspaces/:id", workspaceHandler.DeleteWorkspace)
+		api.GET("/workspaces/:id/members", workspaceHandler.GetMembers)
+		api.POST("/workspaces/:id/members", workspaceHandler.InviteMember)
+		api.PUT("/workspaces/:id/members/:userId", workspaceHandler.UpdateMember)
+		api.DELETE("/workspaces/:id/members/:userId", workspaceHandler.RemoveMember)
 
 		// Category routes
 		api.POST("/categories", categoryHandler.CreateCategory)
 		api.GET("/categories", categoryHandler.GetCategories)
+		api.GET("/categories/suggest", suggestionHandler.SuggestCategories)
 		api.GET("/categories/:id", categoryHandler.GetCategory)
 		api.PUT("/categories/:id", categoryHandler.UpdateCategory)
 		api.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		api.GET("/categories/:id/history", historyHandler.GetCategoryHistory)
+
+		// Budget routes
+		api.POST("/budgets", budgetHandler.CreateBudget)
+		api.GET("/budgets", budgetHandler.GetBudgets)
+		api.GET("/budgets/current", budgetHandler.GetCurrentBudgets)
+		api.GET("/budgets/:id", budgetHandler.GetBudget)
+		api.PUT("/budgets/:id", budgetHandler.UpdateBudget)
+		api.DELETE("/budgets/:id", budgetHandler.DeleteBudget)
+
+		// Envelope budgeting routes
+		api.GET("/envelopes/settings", envelopeHandler.GetSettings)
+		api.PUT("/envelopes/settings", envelopeHandler.UpdateSettings)
+		api.GET("/envelopes", envelopeHandler.GetGrid)
+		api.PUT("/envelopes/assign", envelopeHandler.Assign)
+		api.POST("/envelopes/move", envelopeHandler.Move)
+		api.GET("/envelopes/age-of-money", envelopeHandler.GetAgeOfMoney)
+
+		// Savings goal routes
+		api.POST("/goals", goalHandler.CreateGoal)
+		api.GET("/goals", goalHandler.GetGoals)
+		api.GET("/goals/:id", goalHandler.GetGoal)
+		api.PUT("/goals/:id", goalHandler.UpdateGoal)
+		api.DELETE("/goals/:id", goalHandler.DeleteGoal)
+		api.POST("/goals/:id/contributions", goalHandler.AddContribution)
+		api.GET("/goals/:id/contributions", goalHandler.GetContributions)
+		api.DELETE("/goals/:id/contributions/:contributionId", goalHandler.DeleteContribution)
+
+		// Payee routes
+		api.POST("/payees", payeeHandler.CreatePayee)
+		api.GET("/payees", payeeHandler.GetPayees)
+		api.GET("/payees/:id", payeeHandler.GetPayee)
+		api.PUT("/payees/:id", payeeHandler.UpdatePayee)
+		api.DELETE("/payees/:id", payeeHandler.DeletePayee)
+		api.POST("/payees/:id/merge", payeeHandler.MergePayees)
+		api.POST("/payees/:id/aliases", payeeHandler.AddAlias)
+		api.DELETE("/payees/:id/aliases/:aliasId", payeeHandler.DeleteAlias)
+
+		// Debt routes
+		api.POST("/counterparties", debtHandler.CreateCounterparty)
+		api.GET("/counterparties", debtHandler.GetCounterparties)
+		api.GET("/counterparties/:id", debtHandler.GetCounterparty)
+		api.PUT("/counterparties/:id", debtHandler.UpdateCounterparty)
+		api.DELETE("/counterparties/:id", debtHandler.DeleteCounterparty)
+		api.POST("/debts", debtHandler.CreateDebt)
+		api.GET("/debts", debtHandler.GetDebts)
+		api.GET("/debts/summary", debtHandler.GetDebtSummary)
+		api.GET("/debts/:id", debtHandler.GetDebt)
+		api.PUT("/debts/:id", debtHandler.UpdateDebt)
+		api.DELETE("/debts/:id", debtHandler.DeleteDebt)
+		api.POST("/debts/:id/repayments", debtHandler.AddRepayment)
+		api.DELETE("/debts/:id/repayments/:repaymentId", debtHandler.DeleteRepayment)
+
+		// Loan routes
+		api.POST("/loans", loanHandler.CreateLoan)
+		api.GET("/loans", loanHandler.GetLoans)
+		api.GET("/loans/:id", loanHandler.GetLoan)
+		api.PUT("/loans/:id", loanHandler.UpdateLoan)
+		api.DELETE("/loans/:id", loanHandler.DeleteLoan)
+		api.GET("/loans/:id/schedule", loanHandler.GetSchedule)
+		api.POST("/loans/:id/payments", loanHandler.AddPayment)
+		api.DELETE("/loans/:id/payments/:paymentId", loanHandler.DeletePayment)
+
+		// Bill routes
+		api.POST("/bills", billHandler.CreateBill)
+		api.GET("/bills", billHandler.GetBills)
+		api.GET("/bills/upcoming", billHandler.GetUpcoming)
+		api.GET("/bills/calendar", billHandler.GetCalendarToken)
+		api.POST("/bills/calendar/rotate", billHandler.RotateCalendarToken)
+		api.GET("/bills/:id", billHandler.GetBill)
+		api.PUT("/bills/:id", billHandler.UpdateBill)
+		api.DELETE("/bills/:id", billHandler.DeleteBill)
+		api.POST("/bills/:id/pay", billHandler.PayBill)
+		api.DELETE("/bills/:id/payments/:paymentId", billHandler.DeletePayment)
+
+		// Webhook routes
+		api.POST("/webhooks", webhookHandler.CreateWebhook)
+		api.GET("/webhooks", webhookHandler.GetWebhooks)
+		api.GET("/webhooks/:id", webhookHandler.GetWebhook)
+		api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+		api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		api.POST("/webhooks/:id/test", webhookHandler.SendTest)
+		api.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
 
 		// Statistics routes
 		api.GET("/stats/summary", statsHandler.GetSummary)
 		api.GET("/stats/monthly", statsHandler.GetMonthlyStats)
 		api.GET("/stats/category", statsHandler.GetCategoryStats)
 		api.GET("/stats/balance-history", statsHandler.GetBalanceHistory)
+		api.GET("/stats/payees", statsHandler.GetPayeeStats)
 
 		// Log routes
 		api.GET("/logs", logHandler.GetMyLogs)        // Мои логи
 		api.GET("/logs/stats", logHandler.GetMyStats) // Моя статистика
-		api.GET("/logs/all", logHandler.GetAllLogs)   // Все логи (для админа)
+		api.GET("/logs/export", logHandler.ExportMyLogs)
+		api.GET("/logs/verify", logHandler.VerifyMyLogs)
+
+		// Admin routes
+		staff := middleware.RequireRole(jwt.RoleSupport, jwt.RoleAdmin)
+		api.GET("/logs/all", staff, logHandler.GetAllLogs) // Все логи (для поддержки и админа)
+		api.GET("/logs/all/export", staff, logHandler.ExportAllLogs)
+		api.GET("/logs/all/verify", staff, logHandler.VerifyAllLogs)
+		api.GET("/logs/writer/stats", staff, logHandler.GetWriterStats)
+		api.GET("/outbox/stats", staff, outboxHandler.GetStats)
+		api.GET("/stats/cache", staff, statsHandler.GetCacheStats)
 	}
 
 	// Server setup
@@ -135,5 +353,17 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Unfinished outbox entries and webhook deliveries are picked up
+	// after the restart
+	stopWorkers()
+	workers.Wait()
+
+	// Write the audit entries still queued
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelDrain()
+	if err := logService.Close(drainCtx); err != nil {
+		log.Printf("Audit log: %v", err)
+	}
+
 	log.Println("Server exiting")
 }
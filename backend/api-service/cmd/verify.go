@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"api-service/internal/config"
+	"api-service/internal/models"
+	"api-service/internal/services"
+)
+
+// verifyAudit verifies the chain of the given user, or of every user, and
+// prints one line per chain. It returns the process exit code: 0 when all
+// chains are intact, 1 when one is broken and 2 when verification failed.
+func verifyAudit(db *sql.DB, cfg *config.Config, args []string) int {
+	auditChainService := services.NewAuditChainService(db, cfg)
+	ctx := context.Background()
+
+	var (
+		reports []*models.AuditChainReport
+		err     error
+	)
+	if len(args) > 0 {
+		var report *models.AuditChainReport
+		report, err = auditChainService.Verify(ctx, args[0])
+		reports = []*models.AuditChainReport{report}
+	} else {
+		reports, err = auditChainService.VerifyAll(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Audit verification failed: %v\n", err)
+		return 2
+	}
+
+	if cfg.AuditCheckpointKey == "" {
+		fmt.Fprintln(os.Stderr, "AUDIT_CHECKPOINT_KEY is not set, checkpoint signatures are not verified")
+	}
+
+	broken := 0
+	for _, r := range reports {
+		if r.Valid {
+			fmt.Printf("ok      %s  entries=%d unchained=%d checkpoints=%d\n",
+				r.UserID, r.Entries, r.Unchained, r.Checkpoints)
+			continue
+		}
+		broken++
+		fmt.Printf("BROKEN  %s  seq=%d entry=%s: %s\n",
+			r.UserID, r.Break.Seq, r.Break.EntryID, r.Break.Reason)
+	}
+	fmt.Printf("%d chains verified, %d broken\n", len(reports), broken)
+
+	if broken > 0 {
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -23,9 +26,44 @@ type Config struct {
 
 	// JWT
 	JWTSecret string
+
+	// Shared secret for signed service-to-service requests
+	ServiceSecret string
+
+	// Proxies whose X-Forwarded-For is believed when taking the client IP.
+	// None by default: set it to the address of the reverse proxy in front
+	// of the service. Trusting whole private ranges would let anyone reaching
+	// a published port through the Docker gateway pick their own IP.
+	TrustedProxies []string
+
+	// Rate limit policies in the pkg/ratelimit format; empty for the defaults
+	RateLimits string
+
+	// Audit log writer
+	AuditQueueSize     int
+	AuditFlushSize     int
+	AuditFlushInterval time.Duration
+	AuditOverflow      string // "block" waits for room in the queue, "drop" discards the entry
+
+	// How long stats results stay in Redis; writes invalidate them earlier
+	StatsCacheTTL time.Duration
+
+	// Non-public networks webhooks may still be sent to, e.g. a local
+	// receiver during development; public addresses are always allowed
+	WebhookAllowedNetworks []string
+
+	// Signed checkpoints of the audit hash chains
+	AuditCheckpointKey      string
+	AuditCheckpointInterval time.Duration
 }
 
 func Load() *Config {
+	auditQueueSize, _ := strconv.Atoi(getEnv("AUDIT_QUEUE_SIZE", "10000"))
+	auditFlushSize, _ := strconv.Atoi(getEnv("AUDIT_FLUSH_SIZE", "200"))
+	auditFlushMs, _ := strconv.Atoi(getEnv("AUDIT_FLUSH_INTERVAL_MS", "500"))
+	auditCheckpointMin, _ := strconv.Atoi(getEnv("AUDIT_CHECKPOINT_INTERVAL_MIN", "60"))
+	statsCacheTTLSec, _ := strconv.Atoi(getEnv("STATS_CACHE_TTL_SEC", "600"))
+
 	return &Config{
 		ServiceName:      getEnv("SERVICE_NAME", "api-service"),
 		ServicePort:      getEnv("SERVICE_PORT", "8082"),
@@ -38,6 +76,21 @@ func Load() *Config {
 		RedisPort:        getEnv("REDIS_PORT", "6379"),
 		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
 		JWTSecret:        getEnv("JWT_SECRET", ""),
+		ServiceSecret:    getEnv("SERVICE_SECRET", ""),
+		TrustedProxies:   splitList(getEnv("TRUSTED_PROXIES", "")),
+		RateLimits:       getEnv("RATE_LIMITS", ""),
+
+		AuditQueueSize:     auditQueueSize,
+		AuditFlushSize:     auditFlushSize,
+		AuditFlushInterval: time.Duration(auditFlushMs) * time.Millisecond,
+		AuditOverflow:      getEnv("AUDIT_OVERFLOW", "block"),
+
+		StatsCacheTTL: time.Duration(statsCacheTTLSec) * time.Second,
+
+		WebhookAllowedNetworks: splitList(getEnv("WEBHOOK_ALLOWED_NETWORKS", "")),
+
+		AuditCheckpointKey:      getEnv("AUDIT_CHECKPOINT_KEY", ""),
+		AuditCheckpointInterval: time.Duration(auditCheckpointMin) * time.Minute,
 	}
 }
 
@@ -47,3 +100,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -78,7 +78,7 @@ func createTables(db *sql.DB) error {
 				action VARCHAR(50) NOT NULL,
 				entity VARCHAR(50) NOT NULL,
 				entity_id UUID,
-				details TEXT,
+				details JSONB,
 				ip VARCHAR(45),
 				user_agent TEXT,
 				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
@@ -88,6 +88,388 @@ func createTables(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_user_actions_created_at ON user_actions(created_at);`,
 		`CREATE INDEX IF NOT EXISTS idx_user_actions_action ON user_actions(action);`,
 		`CREATE INDEX IF NOT EXISTS idx_user_actions_entity ON user_actions(entity);`,
+
+		`CREATE TABLE IF NOT EXISTS budgets (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+				period VARCHAR(20) NOT NULL DEFAULT 'monthly' CHECK (period IN ('weekly', 'monthly', 'yearly')),
+				rollover BOOLEAN DEFAULT FALSE,
+				start_date DATE NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS budget_categories (
+				budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+				category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+				PRIMARY KEY (budget_id, category_id)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_budget_categories_category_id ON budget_categories(category_id);`,
+
+		`CREATE TABLE IF NOT EXISTS envelope_settings (
+				user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				enabled BOOLEAN DEFAULT FALSE,
+				start_month DATE NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS envelope_assignments (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+				month DATE NOT NULL,
+				assigned DECIMAL(15, 2) NOT NULL DEFAULT 0,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (user_id, category_id, month)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_envelope_assignments_user_month ON envelope_assignments(user_id, month);`,
+
+		`CREATE TABLE IF NOT EXISTS goals (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				target_amount DECIMAL(15, 2) NOT NULL CHECK (target_amount > 0),
+				target_date DATE,
+				account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS goal_contributions (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				goal_id UUID NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				amount DECIMAL(15, 2) NOT NULL CHECK (amount <> 0),
+				date DATE NOT NULL,
+				transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL,
+				note VARCHAR(255),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_goal_contributions_goal_id ON goal_contributions(goal_id, date);`,
+
+		`CREATE TABLE IF NOT EXISTS payees (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_payees_user_id ON payees(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS payee_aliases (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				payee_id UUID NOT NULL REFERENCES payees(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				pattern VARCHAR(255) NOT NULL,
+				match_type VARCHAR(10) NOT NULL DEFAULT 'prefix' CHECK (match_type IN ('exact', 'prefix', 'contains')),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (user_id, pattern)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_payee_aliases_payee_id ON payee_aliases(payee_id);`,
+
+		`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payee_id UUID REFERENCES payees(id) ON DELETE SET NULL;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_transactions_payee_id ON transactions(payee_id);`,
+
+		`CREATE TABLE IF NOT EXISTS counterparties (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				note VARCHAR(255),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_counterparties_user_id ON counterparties(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS debts (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				counterparty_id UUID NOT NULL REFERENCES counterparties(id) ON DELETE RESTRICT,
+				account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+				direction VARCHAR(10) NOT NULL CHECK (direction IN ('lent', 'borrowed')),
+				amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+				description VARCHAR(255),
+				date DATE NOT NULL,
+				due_date DATE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_debts_user_id ON debts(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_debts_counterparty_id ON debts(counterparty_id);`,
+
+		`CREATE TABLE IF NOT EXISTS debt_repayments (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				debt_id UUID NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+				amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+				date DATE NOT NULL,
+				note VARCHAR(255),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_debt_repayments_debt_id ON debt_repayments(debt_id);`,
+
+		`CREATE TABLE IF NOT EXISTS loans (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				principal DECIMAL(15, 2) NOT NULL CHECK (principal > 0),
+				annual_rate DECIMAL(7, 4) NOT NULL DEFAULT 0 CHECK (annual_rate >= 0),
+				term_months INTEGER NOT NULL CHECK (term_months > 0),
+				payment_type VARCHAR(20) NOT NULL CHECK (payment_type IN ('annuity', 'differentiated')),
+				start_date DATE NOT NULL,
+				account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_loans_user_id ON loans(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS loan_payments (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				loan_id UUID NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				kind VARCHAR(10) NOT NULL CHECK (kind IN ('regular', 'early')),
+				strategy VARCHAR(20) CHECK (strategy IN ('', 'shorten_term', 'reduce_payment')),
+				amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+				date DATE NOT NULL,
+				account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_loan_payments_loan_id ON loan_payments(loan_id, date);`,
+
+		`CREATE TABLE IF NOT EXISTS bills (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				payee_id UUID REFERENCES payees(id) ON DELETE SET NULL,
+				category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+				expected_amount DECIMAL(15, 2) NOT NULL CHECK (expected_amount > 0),
+				frequency VARCHAR(20) NOT NULL CHECK (frequency IN ('once', 'weekly', 'monthly', 'quarterly', 'yearly')),
+				interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+				first_due_date DATE NOT NULL,
+				end_date DATE,
+				reminder_days INTEGER NOT NULL DEFAULT 0,
+				autopay BOOLEAN NOT NULL DEFAULT FALSE,
+				is_active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_bills_user_id ON bills(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS bill_payments (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				bill_id UUID NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				due_date DATE NOT NULL,
+				transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (bill_id, due_date)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_bill_payments_user_due ON bill_payments(user_id, due_date);`,
+
+		`CREATE TABLE IF NOT EXISTS calendar_feeds (
+				user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				token VARCHAR(64) NOT NULL UNIQUE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS account_members (
+				account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+				invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (account_id, user_id)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_account_members_user ON account_members(user_id);`,
+
+		`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;`,
+
+		`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES users(id) ON DELETE SET NULL;`,
+
+		`CREATE TABLE IF NOT EXISTS workspaces (
+				id UUID PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS workspace_members (
+				workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+				invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+				joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (workspace_id, user_id)
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members(user_id);`,
+
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+
+		`ALTER TABLE budgets ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+
+		`ALTER TABLE goals ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_accounts_workspace ON accounts(workspace_id);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_categories_workspace ON categories(workspace_id);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_budgets_workspace ON budgets(workspace_id);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_goals_workspace ON goals(workspace_id);`,
+
+		`CREATE TABLE IF NOT EXISTS outbox (
+				id BIGSERIAL PRIMARY KEY,
+				kind VARCHAR(10) NOT NULL CHECK (kind IN ('audit', 'event')),
+				aggregate_type VARCHAR(50) NOT NULL,
+				aggregate_id VARCHAR(64) NOT NULL,
+				payload JSONB NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				delivered_at TIMESTAMP WITH TIME ZONE,
+				failed_at TIMESTAMP WITH TIME ZONE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE delivered_at IS NULL AND failed_at IS NULL;`,
+
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS outbox_id BIGINT UNIQUE;`,
+
+		// "<service>:<key>" of entries delivered by other services
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(128) UNIQUE;`,
+
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS request_id VARCHAR(64);`,
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS session_id VARCHAR(64);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_actions_request_id ON user_actions(request_id);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_user_actions_entity_history ON user_actions(entity, entity_id, created_at);`,
+
+		// details was TEXT; rows that aren't valid JSON are kept as a JSON string
+		`CREATE OR REPLACE FUNCTION audit_details_to_jsonb(details TEXT) RETURNS JSONB AS $$
+		BEGIN
+			RETURN NULLIF(details, '')::jsonb;
+		EXCEPTION WHEN others THEN
+			RETURN to_jsonb(details);
+		END;
+		$$ LANGUAGE plpgsql IMMUTABLE;`,
+		`DO $$
+		BEGIN
+			IF (SELECT data_type FROM information_schema.columns
+				WHERE table_name = 'user_actions' AND column_name = 'details') = 'text' THEN
+				ALTER TABLE user_actions ALTER COLUMN details TYPE JSONB USING audit_details_to_jsonb(details);
+			END IF;
+		END $$;`,
+		`CREATE INDEX IF NOT EXISTS idx_user_actions_entity_id ON user_actions(entity_id, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_actions_details ON user_actions USING GIN (details jsonb_path_ops);`,
+
+		`CREATE TABLE IF NOT EXISTS webhooks (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				url VARCHAR(500) NOT NULL,
+				secret VARCHAR(128) NOT NULL,
+				event_types TEXT[] NOT NULL,
+				is_active BOOLEAN NOT NULL DEFAULT TRUE,
+				failure_count INTEGER NOT NULL DEFAULT 0,
+				disabled_at TIMESTAMP WITH TIME ZONE,
+				disabled_reason VARCHAR(255),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+				webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+				event_id VARCHAR(64) NOT NULL,
+				event_type VARCHAR(50) NOT NULL,
+				payload JSONB NOT NULL,
+				status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+				attempts INTEGER NOT NULL DEFAULT 0,
+				response_code INTEGER,
+				response_body TEXT,
+				error TEXT,
+				duration_ms INTEGER,
+				next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				delivered_at TIMESTAMP WITH TIME ZONE
+		);`,
+
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id) WHERE redelivery_of IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);`,
+
+		// Per-user hash chain over the audit log; entries written before it
+		// have no chain_seq
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS chain_seq BIGINT;`,
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);`,
+		`ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS hash VARCHAR(64);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_actions_chain ON user_actions(user_id, chain_seq) WHERE chain_seq IS NOT NULL;`,
+
+		`CREATE TABLE IF NOT EXISTS audit_chain_heads (
+				user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+				seq BIGINT NOT NULL,
+				hash VARCHAR(64) NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS audit_checkpoints (
+				id BIGSERIAL PRIMARY KEY,
+				user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				seq BIGINT NOT NULL,
+				hash VARCHAR(64) NOT NULL,
+				prev_signature VARCHAR(64) NOT NULL,
+				signature VARCHAR(64) NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_user ON audit_checkpoints(user_id, id);`,
+
+		`ALTER TABLE envelope_settings ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+		`ALTER TABLE envelope_assignments ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+		`ALTER TABLE payees ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+		`ALTER TABLE payee_aliases ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+		`ALTER TABLE bills ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+		`ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_payees_workspace ON payees(workspace_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_bills_workspace ON bills(workspace_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_workspace ON webhooks(workspace_id);`,
+
+		// Envelope settings, assignments and payee aliases are unique per
+		// space: the workspace, or the user's personal data outside one
+		`ALTER TABLE envelope_settings DROP CONSTRAINT IF EXISTS envelope_settings_pkey;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_envelope_settings_space ON envelope_settings ((COALESCE(workspace_id, user_id)));`,
+		`ALTER TABLE envelope_assignments DROP CONSTRAINT IF EXISTS envelope_assignments_user_id_category_id_month_key;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_envelope_assignments_space ON envelope_assignments ((COALESCE(workspace_id, user_id)), category_id, month);`,
+		`ALTER TABLE payee_aliases DROP CONSTRAINT IF EXISTS payee_aliases_user_id_pattern_key;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payee_aliases_space ON payee_aliases ((COALESCE(workspace_id, user_id)), pattern);`,
 	}
 
 	for _, query := range queries {
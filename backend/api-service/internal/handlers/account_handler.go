@@ -35,9 +35,14 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := h.accountService.CreateAccount(c.Request.Context(), userID.(string), &req)
+	account, err := h.accountService.CreateAccount(c.Request.Context(), requestScope(c, userID), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -54,7 +59,7 @@ func (h *AccountHandler) GetAccounts(c *gin.Context) {
 		return
 	}
 
-	accounts, err := h.accountService.GetAccounts(c.Request.Context(), userID.(string))
+	accounts, err := h.accountService.GetAccounts(c.Request.Context(), requestScope(c, userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -63,15 +68,17 @@ func (h *AccountHandler) GetAccounts(c *gin.Context) {
 	// Get stats for each account
 	accountsWithStats := make([]map[string]interface{}, len(accounts))
 	for i, account := range accounts {
-		stats, _ := h.accountService.GetAccountStats(c.Request.Context(), userID.(string), account.ID)
+		stats, _ := h.accountService.GetAccountStats(c.Request.Context(), requestScope(c, userID), account.ID)
 
 		accountsWithStats[i] = map[string]interface{}{
-			"id":         account.ID,
-			"name":       account.Name,
-			"balance":    account.Balance,
-			"is_default": account.IsDefault,
-			"created_at": account.CreatedAt,
-			"updated_at": account.UpdatedAt,
+			"id":           account.ID,
+			"name":         account.Name,
+			"balance":      account.Balance,
+			"is_default":   account.IsDefault,
+			"role":         account.Role,
+			"member_count": account.MemberCount,
+			"created_at":   account.CreatedAt,
+			"updated_at":   account.UpdatedAt,
 		}
 
 		if stats != nil {
@@ -98,7 +105,7 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := h.accountService.GetAccount(c.Request.Context(), userID.(string), accountID)
+	account, err := h.accountService.GetAccount(c.Request.Context(), requestScope(c, userID), accountID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "account not found" {
@@ -109,7 +116,7 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 		return
 	}
 
-	stats, _ := h.accountService.GetAccountStats(c.Request.Context(), userID.(string), accountID)
+	stats, _ := h.accountService.GetAccountStats(c.Request.Context(), requestScope(c, userID), accountID)
 
 	response := gin.H{
 		"account": account,
@@ -144,11 +151,13 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := h.accountService.UpdateAccount(c.Request.Context(), userID.(string), accountID, &req)
+	account, err := h.accountService.UpdateAccount(c.Request.Context(), requestScope(c, userID), accountID, &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "account not found" {
 			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient account permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -181,7 +190,7 @@ func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 			statusCode = http.StatusNotFound
 		} else if err.Error() == "cannot delete the only account" {
 			statusCode = http.StatusBadRequest
-		} else if err.Error() == "cannot delete account with existing transactions" {
+		} else if err.Error() == "cannot delete account with existing transactions" || err.Error() == "cannot delete account with existing debts" {
 			statusCode = http.StatusConflict
 		}
 
@@ -212,6 +221,8 @@ func (h *AccountHandler) SetDefaultAccount(c *gin.Context) {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "account not found" {
 			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
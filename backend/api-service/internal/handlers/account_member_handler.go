@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountMemberHandler struct {
+	accountMemberService *services.AccountMemberService
+}
+
+func NewAccountMemberHandler(accountMemberService *services.AccountMemberService) *AccountMemberHandler {
+	return &AccountMemberHandler{
+		accountMemberService: accountMemberService,
+	}
+}
+
+func (h *AccountMemberHandler) GetMembers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID := c.Param("id")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID is required"})
+		return
+	}
+
+	members, err := h.accountMemberService.GetMembers(c.Request.Context(), userID.(string), accountID)
+	if err != nil {
+		c.JSON(accountMemberErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+		"count":   len(members),
+	})
+}
+
+func (h *AccountMemberHandler) InviteMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID := c.Param("id")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID is required"})
+		return
+	}
+
+	var req models.InviteAccountMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	member, err := h.accountMemberService.InviteMember(c.Request.Context(), userID.(string), accountID, &req)
+	if err != nil {
+		c.JSON(accountMemberErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (h *AccountMemberHandler) UpdateMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID := c.Param("id")
+	memberID := c.Param("userId")
+	if accountID == "" || memberID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID and user ID are required"})
+		return
+	}
+
+	var req models.UpdateAccountMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.accountMemberService.UpdateMemberRole(c.Request.Context(), userID.(string), accountID, memberID, &req); err != nil {
+		c.JSON(accountMemberErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+	})
+}
+
+func (h *AccountMemberHandler) RemoveMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID := c.Param("id")
+	memberID := c.Param("userId")
+	if accountID == "" || memberID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID and user ID are required"})
+		return
+	}
+
+	if err := h.accountMemberService.RemoveMember(c.Request.Context(), userID.(string), accountID, memberID); err != nil {
+		c.JSON(accountMemberErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+func accountMemberErrorStatus(err error) int {
+	switch err.Error() {
+	case "account not found", "user not found", "member not found":
+		return http.StatusNotFound
+	case "insufficient account permissions":
+		return http.StatusForbidden
+	case "user already has access to this account":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
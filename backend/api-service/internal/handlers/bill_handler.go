@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BillHandler struct {
+	billService *services.BillService
+}
+
+func NewBillHandler(billService *services.BillService) *BillHandler {
+	return &BillHandler{
+		billService: billService,
+	}
+}
+
+func (h *BillHandler) CreateBill(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateBillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	bill, err := h.billService.CreateBill(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Bill created successfully",
+		"bill":    bill,
+	})
+}
+
+func (h *BillHandler) GetBills(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	bills, err := h.billService.GetBills(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bills": bills,
+		"count": len(bills),
+	})
+}
+
+func (h *BillHandler) GetUpcoming(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	days := 30 // Default to 30 days ahead
+	if d := c.Query("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+
+	upcoming, err := h.billService.GetUpcoming(c.Request.Context(), requestScope(c, userID), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var dueTotal float64
+	overdue := 0
+	for _, o := range upcoming {
+		if o.Status != "paid" {
+			dueTotal += o.Amount
+		}
+		if o.Status == "overdue" {
+			overdue++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bills":     upcoming,
+		"days":      days,
+		"due_total": dueTotal,
+		"overdue":   overdue,
+	})
+}
+
+func (h *BillHandler) GetBill(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID := c.Param("id")
+	if billID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bill ID is required"})
+		return
+	}
+
+	bill, err := h.billService.GetBill(c.Request.Context(), requestScope(c, userID), billID)
+	if err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bill": bill,
+	})
+}
+
+func (h *BillHandler) UpdateBill(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID := c.Param("id")
+	if billID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bill ID is required"})
+		return
+	}
+
+	var req models.UpdateBillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	bill, err := h.billService.UpdateBill(c.Request.Context(), requestScope(c, userID), billID, &req)
+	if err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bill updated successfully",
+		"bill":    bill,
+	})
+}
+
+func (h *BillHandler) DeleteBill(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID := c.Param("id")
+	if billID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bill ID is required"})
+		return
+	}
+
+	if err := h.billService.DeleteBill(c.Request.Context(), requestScope(c, userID), billID); err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bill deleted successfully",
+	})
+}
+
+func (h *BillHandler) PayBill(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID := c.Param("id")
+	if billID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bill ID is required"})
+		return
+	}
+
+	var req models.PayBillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payment, err := h.billService.PayBill(c.Request.Context(), requestScope(c, userID), billID, &req)
+	if err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Bill marked as paid",
+		"payment": payment,
+	})
+}
+
+func (h *BillHandler) DeletePayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID := c.Param("id")
+	paymentID := c.Param("paymentId")
+	if billID == "" || paymentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bill ID and payment ID are required"})
+		return
+	}
+
+	if err := h.billService.DeletePayment(c.Request.Context(), requestScope(c, userID), billID, paymentID); err != nil {
+		c.JSON(billErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment deleted successfully",
+	})
+}
+
+func (h *BillHandler) GetCalendarToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	token, err := h.billService.GetCalendarToken(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": token,
+		"url":   "/api/v1/calendar/" + token + ".ics",
+	})
+}
+
+func (h *BillHandler) RotateCalendarToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	token, err := h.billService.RotateCalendarToken(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar link rotated successfully",
+		"token":   token,
+		"url":     "/api/v1/calendar/" + token + ".ics",
+	})
+}
+
+// GetCalendarFeed serves the iCalendar feed. It is public: the secret token
+// in the URL is the only credential, as calendar apps can't send headers.
+func (h *BillHandler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.String(http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	feed, err := h.billService.GetCalendarFeed(c.Request.Context(), token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "calendar not found" {
+			status = http.StatusNotFound
+		}
+		c.String(status, err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(feed))
+}
+
+func billErrorStatus(err error) int {
+	switch {
+	case err.Error() == "bill not found",
+		err.Error() == "payee not found",
+		err.Error() == "category not found",
+		err.Error() == "transaction not found",
+		err.Error() == "payment not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient workspace permissions":
+		return http.StatusForbidden
+	case err.Error() == "bill is already paid for this date",
+		err.Error() == "transaction is already linked to a bill":
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid date format"),
+		err.Error() == "end date is before the first due date",
+		err.Error() == "due date does not match the bill schedule",
+		err.Error() == "bill has no unpaid occurrences":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
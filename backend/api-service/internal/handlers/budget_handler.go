@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	budgetService *services.BudgetService
+}
+
+func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+	}
+}
+
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	budget, err := h.budgetService.CreateBudget(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "category not found" {
+			statusCode = http.StatusNotFound
+		} else if strings.HasPrefix(err.Error(), "invalid date format") {
+			statusCode = http.StatusBadRequest
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Budget created successfully",
+		"budget":  budget,
+	})
+}
+
+func (h *BudgetHandler) GetBudgets(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budgets, err := h.budgetService.GetBudgets(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+		"count":   len(budgets),
+	})
+}
+
+func (h *BudgetHandler) GetCurrentBudgets(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	progress, err := h.budgetService.GetCurrentProgress(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": progress,
+		"count":   len(progress),
+	})
+}
+
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budgetID := c.Param("id")
+	if budgetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget ID is required"})
+		return
+	}
+
+	progress, err := h.budgetService.GetBudgetProgress(c.Request.Context(), requestScope(c, userID), budgetID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "budget not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget":   progress.Budget,
+		"progress": progress,
+	})
+}
+
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budgetID := c.Param("id")
+	if budgetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget ID is required"})
+		return
+	}
+
+	var req models.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	budget, err := h.budgetService.UpdateBudget(c.Request.Context(), requestScope(c, userID), budgetID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "budget not found" || err.Error() == "category not found" {
+			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Budget updated successfully",
+		"budget":  budget,
+	})
+}
+
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	budgetID := c.Param("id")
+	if budgetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget ID is required"})
+		return
+	}
+
+	err := h.budgetService.DeleteBudget(c.Request.Context(), requestScope(c, userID), budgetID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "budget not found" {
+			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Budget deleted successfully",
+	})
+}
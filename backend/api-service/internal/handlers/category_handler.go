@@ -36,11 +36,13 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := h.categoryService.CreateCategory(c.Request.Context(), userID.(string), &req)
+	category, err := h.categoryService.CreateCategory(c.Request.Context(), requestScope(c, userID), &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "category with this name already exists" {
 			statusCode = http.StatusConflict
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -70,9 +72,9 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category type"})
 			return
 		}
-		categories, err = h.categoryService.GetCategoriesByType(c.Request.Context(), userID.(string), categoryType)
+		categories, err = h.categoryService.GetCategoriesByType(c.Request.Context(), requestScope(c, userID), categoryType)
 	} else {
-		categories, err = h.categoryService.GetCategories(c.Request.Context(), userID.(string))
+		categories, err = h.categoryService.GetCategories(c.Request.Context(), requestScope(c, userID))
 	}
 
 	if err != nil {
@@ -110,7 +112,7 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := h.categoryService.GetCategory(c.Request.Context(), userID.(string), categoryID)
+	category, err := h.categoryService.GetCategory(c.Request.Context(), requestScope(c, userID), categoryID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "category not found" {
@@ -125,7 +127,7 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 	startDate := time.Now().AddDate(0, -1, 0)
 	endDate := time.Now()
 
-	stats, _ := h.categoryService.GetCategoryStats(c.Request.Context(), userID.(string), startDate, endDate)
+	stats, _ := h.categoryService.GetCategoryStats(c.Request.Context(), requestScope(c, userID), startDate, endDate)
 
 	var categoryStats *models.CategoryStats
 	for _, stat := range stats {
@@ -168,13 +170,15 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := h.categoryService.UpdateCategory(c.Request.Context(), userID.(string), categoryID, &req)
+	category, err := h.categoryService.UpdateCategory(c.Request.Context(), requestScope(c, userID), categoryID, &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "category not found" {
 			statusCode = http.StatusNotFound
 		} else if err.Error() == "cannot modify system category" {
 			statusCode = http.StatusForbidden
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -200,7 +204,7 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	err := h.categoryService.DeleteCategory(c.Request.Context(), userID.(string), categoryID)
+	err := h.categoryService.DeleteCategory(c.Request.Context(), requestScope(c, userID), categoryID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "category not found" {
@@ -209,6 +213,8 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 			statusCode = http.StatusForbidden
 		} else if err.Error() == "cannot delete category with existing transactions" {
 			statusCode = http.StatusConflict
+		} else if err.Error() == "insufficient workspace permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
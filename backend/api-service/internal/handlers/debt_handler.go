@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DebtHandler struct {
+	debtService *services.DebtService
+}
+
+func NewDebtHandler(debtService *services.DebtService) *DebtHandler {
+	return &DebtHandler{
+		debtService: debtService,
+	}
+}
+
+func (h *DebtHandler) CreateCounterparty(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateCounterpartyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	counterparty, err := h.debtService.CreateCounterparty(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Counterparty created successfully",
+		"counterparty": counterparty,
+	})
+}
+
+func (h *DebtHandler) GetCounterparties(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	counterparties, err := h.debtService.GetCounterparties(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"counterparties": counterparties,
+		"count":          len(counterparties),
+	})
+}
+
+func (h *DebtHandler) GetCounterparty(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	counterpartyID := c.Param("id")
+	if counterpartyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Counterparty ID is required"})
+		return
+	}
+
+	counterparty, err := h.debtService.GetCounterparty(c.Request.Context(), userID.(string), counterpartyID)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	debts, err := h.debtService.GetDebts(c.Request.Context(), &models.DebtFilter{
+		UserID:         userID.(string),
+		CounterpartyID: counterpartyID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"counterparty": counterparty,
+		"debts":        debts,
+	})
+}
+
+func (h *DebtHandler) UpdateCounterparty(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	counterpartyID := c.Param("id")
+	if counterpartyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Counterparty ID is required"})
+		return
+	}
+
+	var req models.UpdateCounterpartyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	counterparty, err := h.debtService.UpdateCounterparty(c.Request.Context(), userID.(string), counterpartyID, &req)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Counterparty updated successfully",
+		"counterparty": counterparty,
+	})
+}
+
+func (h *DebtHandler) DeleteCounterparty(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	counterpartyID := c.Param("id")
+	if counterpartyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Counterparty ID is required"})
+		return
+	}
+
+	if err := h.debtService.DeleteCounterparty(c.Request.Context(), userID.(string), counterpartyID); err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Counterparty deleted successfully",
+	})
+}
+
+func (h *DebtHandler) CreateDebt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateDebtRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	debt, err := h.debtService.CreateDebt(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Debt created successfully",
+		"debt":    debt,
+	})
+}
+
+func (h *DebtHandler) GetDebts(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter := &models.DebtFilter{
+		UserID:         userID.(string),
+		CounterpartyID: c.Query("counterparty_id"),
+		Direction:      c.Query("direction"),
+		Status:         c.Query("status"),
+	}
+
+	if filter.Direction != "" && filter.Direction != "lent" && filter.Direction != "borrowed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt direction"})
+		return
+	}
+
+	if filter.Status != "" && filter.Status != "open" && filter.Status != "overdue" && filter.Status != "repaid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt status"})
+		return
+	}
+
+	debts, err := h.debtService.GetDebts(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"debts": debts,
+		"count": len(debts),
+	})
+}
+
+func (h *DebtHandler) GetDebtSummary(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	summary, err := h.debtService.GetSummary(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary": summary,
+	})
+}
+
+func (h *DebtHandler) GetDebt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	debtID := c.Param("id")
+	if debtID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debt ID is required"})
+		return
+	}
+
+	debt, err := h.debtService.GetDebt(c.Request.Context(), userID.(string), debtID)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"debt": debt,
+	})
+}
+
+func (h *DebtHandler) UpdateDebt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	debtID := c.Param("id")
+	if debtID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debt ID is required"})
+		return
+	}
+
+	var req models.UpdateDebtRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	debt, err := h.debtService.UpdateDebt(c.Request.Context(), userID.(string), debtID, &req)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Debt updated successfully",
+		"debt":    debt,
+	})
+}
+
+func (h *DebtHandler) DeleteDebt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	debtID := c.Param("id")
+	if debtID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debt ID is required"})
+		return
+	}
+
+	if err := h.debtService.DeleteDebt(c.Request.Context(), userID.(string), debtID); err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Debt deleted successfully",
+	})
+}
+
+func (h *DebtHandler) AddRepayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	debtID := c.Param("id")
+	if debtID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debt ID is required"})
+		return
+	}
+
+	var req models.CreateDebtRepaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	repayment, err := h.debtService.AddRepayment(c.Request.Context(), requestScope(c, userID), debtID, &req)
+	if err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	debt, _ := h.debtService.GetDebt(c.Request.Context(), userID.(string), debtID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Repayment added successfully",
+		"repayment": repayment,
+		"debt":      debt,
+	})
+}
+
+func (h *DebtHandler) DeleteRepayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	debtID := c.Param("id")
+	repaymentID := c.Param("repaymentId")
+	if debtID == "" || repaymentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debt ID and repayment ID are required"})
+		return
+	}
+
+	if err := h.debtService.DeleteRepayment(c.Request.Context(), userID.(string), debtID, repaymentID); err != nil {
+		c.JSON(debtErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Repayment deleted successfully",
+	})
+}
+
+func debtErrorStatus(err error) int {
+	switch {
+	case err.Error() == "debt not found",
+		err.Error() == "counterparty not found",
+		err.Error() == "account not found",
+		err.Error() == "repayment not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient account permissions":
+		return http.StatusForbidden
+	case err.Error() == "cannot delete counterparty with existing debts":
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid date format"),
+		err.Error() == "due date is before the debt date",
+		err.Error() == "repayment exceeds outstanding amount":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
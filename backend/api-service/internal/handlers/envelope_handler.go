@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EnvelopeHandler struct {
+	envelopeService *services.EnvelopeService
+}
+
+func NewEnvelopeHandler(envelopeService *services.EnvelopeService) *EnvelopeHandler {
+	return &EnvelopeHandler{
+		envelopeService: envelopeService,
+	}
+}
+
+func (h *EnvelopeHandler) GetSettings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	settings, err := h.envelopeService.GetSettings(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": settings,
+	})
+}
+
+func (h *EnvelopeHandler) UpdateSettings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateEnvelopeSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.envelopeService.UpdateSettings(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(envelopeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Envelope settings updated successfully",
+		"settings": settings,
+	})
+}
+
+func (h *EnvelopeHandler) GetGrid(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	grid, err := h.envelopeService.GetGrid(c.Request.Context(), requestScope(c, userID), c.Query("month"))
+	if err != nil {
+		c.JSON(envelopeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
+
+func (h *EnvelopeHandler) Assign(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.AssignEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.envelopeService.Assign(c.Request.Context(), requestScope(c, userID), &req); err != nil {
+		c.JSON(envelopeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Envelope assigned successfully",
+	})
+}
+
+func (h *EnvelopeHandler) Move(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.MoveEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.envelopeService.Move(c.Request.Context(), requestScope(c, userID), &req); err != nil {
+		c.JSON(envelopeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Money moved successfully",
+	})
+}
+
+func (h *EnvelopeHandler) GetAgeOfMoney(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	days, err := h.envelopeService.GetAgeOfMoney(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"age_of_money": days,
+	})
+}
+
+func envelopeErrorStatus(err error) int {
+	switch {
+	case err.Error() == "category not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient workspace permissions":
+		return http.StatusForbidden
+	case err.Error() == "envelope mode is not enabled",
+		err.Error() == "not enough money ready to assign",
+		err.Error() == "not enough money in source envelope":
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid month format"),
+		err.Error() == "month is before envelope budgeting start",
+		err.Error() == "source or target envelope is required",
+		err.Error() == "source and target envelopes must differ":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GoalHandler struct {
+	goalService *services.GoalService
+}
+
+func NewGoalHandler(goalService *services.GoalService) *GoalHandler {
+	return &GoalHandler{
+		goalService: goalService,
+	}
+}
+
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	goal, err := h.goalService.CreateGoal(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Goal created successfully",
+		"goal":    goal,
+	})
+}
+
+func (h *GoalHandler) GetGoals(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goals, err := h.goalService.GetGoals(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"goals": goals,
+		"count": len(goals),
+	})
+}
+
+func (h *GoalHandler) GetGoal(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	if goalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID is required"})
+		return
+	}
+
+	goal, err := h.goalService.GetGoal(c.Request.Context(), requestScope(c, userID), goalID)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"goal": goal,
+	})
+}
+
+func (h *GoalHandler) UpdateGoal(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	if goalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID is required"})
+		return
+	}
+
+	var req models.UpdateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	goal, err := h.goalService.UpdateGoal(c.Request.Context(), requestScope(c, userID), goalID, &req)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goal updated successfully",
+		"goal":    goal,
+	})
+}
+
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	if goalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID is required"})
+		return
+	}
+
+	if err := h.goalService.DeleteGoal(c.Request.Context(), requestScope(c, userID), goalID); err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goal deleted successfully",
+	})
+}
+
+func (h *GoalHandler) AddContribution(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	if goalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID is required"})
+		return
+	}
+
+	var req models.CreateGoalContributionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	contribution, err := h.goalService.AddContribution(c.Request.Context(), requestScope(c, userID), goalID, &req)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	goal, _ := h.goalService.GetGoal(c.Request.Context(), requestScope(c, userID), goalID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Contribution added successfully",
+		"contribution": contribution,
+		"goal":         goal,
+	})
+}
+
+func (h *GoalHandler) GetContributions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	if goalID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID is required"})
+		return
+	}
+
+	contributions, err := h.goalService.GetContributions(c.Request.Context(), requestScope(c, userID), goalID)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contributions": contributions,
+		"count":         len(contributions),
+	})
+}
+
+func (h *GoalHandler) DeleteContribution(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	goalID := c.Param("id")
+	contributionID := c.Param("contributionId")
+	if goalID == "" || contributionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal ID and contribution ID are required"})
+		return
+	}
+
+	err := h.goalService.DeleteContribution(c.Request.Context(), requestScope(c, userID), goalID, contributionID)
+	if err != nil {
+		c.JSON(goalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contribution deleted successfully",
+	})
+}
+
+func goalErrorStatus(err error) int {
+	switch {
+	case err.Error() == "goal not found",
+		err.Error() == "account not found",
+		err.Error() == "transaction not found",
+		err.Error() == "contribution not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient workspace permissions":
+		return http.StatusForbidden
+	case strings.HasPrefix(err.Error(), "invalid date format"),
+		err.Error() == "contribution amount is required",
+		err.Error() == "transaction does not belong to the goal account":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
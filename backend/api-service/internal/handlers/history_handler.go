@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type HistoryHandler struct {
+	historyService     *services.HistoryService
+	transactionService *services.TransactionService
+	accountService     *services.AccountService
+	categoryService    *services.CategoryService
+}
+
+func NewHistoryHandler(historyService *services.HistoryService, transactionService *services.TransactionService,
+	accountService *services.AccountService, categoryService *services.CategoryService) *HistoryHandler {
+	return &HistoryHandler{
+		historyService:     historyService,
+		transactionService: transactionService,
+		accountService:     accountService,
+		categoryService:    categoryService,
+	}
+}
+
+func (h *HistoryHandler) GetTransactionHistory(c *gin.Context) {
+	h.getHistory(c, "transaction", func(ctx context.Context, scope services.Scope, id string) error {
+		_, err := h.transactionService.GetTransaction(ctx, scope, id)
+		return err
+	})
+}
+
+func (h *HistoryHandler) GetAccountHistory(c *gin.Context) {
+	h.getHistory(c, "account", func(ctx context.Context, scope services.Scope, id string) error {
+		_, err := h.accountService.GetAccount(ctx, scope, id)
+		return err
+	})
+}
+
+func (h *HistoryHandler) GetCategoryHistory(c *gin.Context) {
+	h.getHistory(c, "category", func(ctx context.Context, scope services.Scope, id string) error {
+		_, err := h.categoryService.GetCategory(ctx, scope, id)
+		return err
+	})
+}
+
+// getHistory returns the history of the entity in the "id" parameter, with
+// its state at the time given by the "at" query parameter (RFC 3339 or
+// YYYY-MM-DD, meaning the end of that day). Anyone who can see the entity
+// in the active space can see its history. Once it is deleted, users who
+// acted on it see its history up to their last action, when they could
+// still see it.
+func (h *HistoryHandler) getHistory(c *gin.Context, entity string, canView func(ctx context.Context, scope services.Scope, id string) error) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	entityID := c.Param("id")
+	if _, err := uuid.Parse(entityID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": entity + " not found"})
+		return
+	}
+
+	var at *time.Time
+	if value := c.Query("at"); value != "" {
+		t, err := parseTimeParam(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at, expected RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+		at = &t
+	}
+
+	ctx := c.Request.Context()
+	var until *time.Time
+	if err := canView(ctx, requestScope(c, userID), entityID); err != nil {
+		if !errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// An entity that still exists is only hidden from the user
+		stillExists, checkErr := h.historyService.Exists(ctx, entity, entityID)
+		if checkErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": checkErr.Error()})
+			return
+		}
+		if stillExists {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		until, checkErr = h.historyService.LastActed(ctx, userID.(string), entity, entityID)
+		if checkErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": checkErr.Error()})
+			return
+		}
+		if until == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	history, err := h.historyService.GetHistory(ctx, userID.(string), entity, entityID, at, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
+
+// parseTimeParam parses an RFC 3339 time or a YYYY-MM-DD date. With
+// endOfDay a date means its last moment rather than its start.
+func parseTimeParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		day = day.Add(24*time.Hour - time.Nanosecond)
+	}
+	return day, nil
+}
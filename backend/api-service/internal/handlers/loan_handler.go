@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoanHandler struct {
+	loanService *services.LoanService
+}
+
+func NewLoanHandler(loanService *services.LoanService) *LoanHandler {
+	return &LoanHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *LoanHandler) CreateLoan(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	loan, err := h.loanService.CreateLoan(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Loan created successfully",
+		"loan":    loan,
+	})
+}
+
+func (h *LoanHandler) GetLoans(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loans, err := h.loanService.GetLoans(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loans": loans,
+		"count": len(loans),
+	})
+}
+
+func (h *LoanHandler) GetLoan(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	if loanID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID is required"})
+		return
+	}
+
+	loan, err := h.loanService.GetLoan(c.Request.Context(), userID.(string), loanID)
+	if err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loan": loan,
+	})
+}
+
+func (h *LoanHandler) GetSchedule(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	if loanID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID is required"})
+		return
+	}
+
+	loan, schedule, err := h.loanService.GetSchedule(c.Request.Context(), userID.(string), loanID)
+	if err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	loan.Payments = nil
+
+	c.JSON(http.StatusOK, gin.H{
+		"loan":     loan,
+		"schedule": schedule,
+	})
+}
+
+func (h *LoanHandler) UpdateLoan(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	if loanID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID is required"})
+		return
+	}
+
+	var req models.UpdateLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	loan, err := h.loanService.UpdateLoan(c.Request.Context(), requestScope(c, userID), loanID, &req)
+	if err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Loan updated successfully",
+		"loan":    loan,
+	})
+}
+
+func (h *LoanHandler) DeleteLoan(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	if loanID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID is required"})
+		return
+	}
+
+	if err := h.loanService.DeleteLoan(c.Request.Context(), userID.(string), loanID); err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Loan deleted successfully",
+	})
+}
+
+func (h *LoanHandler) AddPayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	if loanID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID is required"})
+		return
+	}
+
+	var req models.CreateLoanPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payment, err := h.loanService.AddPayment(c.Request.Context(), requestScope(c, userID), loanID, &req)
+	if err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	loan, _ := h.loanService.GetLoan(c.Request.Context(), userID.(string), loanID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment added successfully",
+		"payment": payment,
+		"loan":    loan,
+	})
+}
+
+func (h *LoanHandler) DeletePayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	loanID := c.Param("id")
+	paymentID := c.Param("paymentId")
+	if loanID == "" || paymentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan ID and payment ID are required"})
+		return
+	}
+
+	if err := h.loanService.DeletePayment(c.Request.Context(), userID.(string), loanID, paymentID); err != nil {
+		c.JSON(loanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment deleted successfully",
+	})
+}
+
+func loanErrorStatus(err error) int {
+	switch {
+	case err.Error() == "loan not found",
+		err.Error() == "account not found",
+		err.Error() == "payment not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient account permissions":
+		return http.StatusForbidden
+	case strings.HasPrefix(err.Error(), "invalid date format"),
+		err.Error() == "payment date is before the loan start date",
+		err.Error() == "payment exceeds remaining principal",
+		err.Error() == "loan is already paid off":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
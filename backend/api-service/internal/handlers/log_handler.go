@@ -1,21 +1,31 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"api-service/internal/models"
 	"api-service/internal/services"
+	"api-service/pkg/auditctx"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type LogHandler struct {
-	logService *services.LogService
+	logService        *services.LogService
+	auditChainService *services.AuditChainService
 }
 
-func NewLogHandler(logService *services.LogService) *LogHandler {
-	return &LogHandler{logService: logService}
+func NewLogHandler(logService *services.LogService, auditChainService *services.AuditChainService) *LogHandler {
+	return &LogHandler{logService: logService, auditChainService: auditChainService}
 }
 
 // GetMyLogs - получить свои логи (для авторизованного пользователя)
@@ -41,20 +51,16 @@ func (h *LogHandler) GetMyLogs(c *gin.Context) {
 		}
 	}
 
-	logs, err := h.logService.GetUserLogs(c.Request.Context(), userID.(string), limit, offset)
+	filter, err := parseLogFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Парсим JSON details для читаемости
-	for i, log := range logs {
-		if details, ok := log["details"].(string); ok && details != "" {
-			var parsedDetails map[string]interface{}
-			if err := json.Unmarshal([]byte(details), &parsedDetails); err == nil {
-				logs[i]["details_parsed"] = parsedDetails
-			}
-		}
+	logs, err := h.logService.GetUserLogs(c.Request.Context(), userID.(string), filter, limit, offset)
+	if err != nil {
+		c.JSON(logErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -65,6 +71,34 @@ func (h *LogHandler) GetMyLogs(c *gin.Context) {
 	})
 }
 
+// ExportMyLogs - выгрузка своих логов по тем же фильтрам (format=csv|json)
+func (h *LogHandler) ExportMyLogs(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	logs, err := h.logService.GetUserLogs(c.Request.Context(), userID.(string), filter, auditExportLimit, 0)
+	if err != nil {
+		c.JSON(logErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	writeLogsExport(c, logs, format)
+}
+
 // GetMyStats - получить статистику своих действий
 func (h *LogHandler) GetMyStats(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -89,11 +123,13 @@ func (h *LogHandler) GetMyStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// GetAllLogs - получить все логи (для админа)
+// GetAllLogs - получить все логи (для поддержки и админов, см. RequireRole)
 func (h *LogHandler) GetAllLogs(c *gin.Context) {
-	// TODO: Добавь проверку что пользователь — админ
-	// userID, _ := c.Get("userID")
-	// if !isAdmin(userID) { return error }
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
 	limit := 100
 	offset := 0
@@ -110,18 +146,27 @@ func (h *LogHandler) GetAllLogs(c *gin.Context) {
 		}
 	}
 
-	filters := map[string]string{
-		"action":  c.Query("action"),
-		"entity":  c.Query("entity"),
-		"user_id": c.Query("user_id"),
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	filter.UserID = c.Query("user_id")
 
-	logs, err := h.logService.GetAllLogs(c.Request.Context(), limit, offset, filters)
+	logs, err := h.logService.GetAllLogs(c.Request.Context(), filter, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(logErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	// Доступ к чужим логам тоже попадает в журнал
+	h.logAdminAccess(c, userID.(string), "logs/all", map[string]interface{}{
+		"filters": filter,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(logs),
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"logs":   logs,
 		"count":  len(logs),
@@ -130,20 +175,145 @@ func (h *LogHandler) GetAllLogs(c *gin.Context) {
 	})
 }
 
-// LogInternalAction - для логирования из других микросервисов (auth, analytics)
-func (h *LogHandler) LogInternalAction(c *gin.Context) {
-	// Проверяем что запрос от внутреннего сервиса
-	if c.GetHeader("X-Internal-Service") == "" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+// ExportAllLogs - выгрузка логов всех пользователей (для поддержки и админов)
+func (h *LogHandler) ExportAllLogs(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = c.Query("user_id")
+
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	logs, err := h.logService.GetAllLogs(c.Request.Context(), filter, auditExportLimit, 0)
+	if err != nil {
+		c.JSON(logErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logAdminAccess(c, userID.(string), "logs/all/export", map[string]interface{}{
+		"filters": filter,
+		"format":  format,
+		"count":   len(logs),
+	})
+
+	writeLogsExport(c, logs, format)
+}
+
+func (h *LogHandler) logAdminAccess(c *gin.Context, userID, route string, data map[string]interface{}) {
+	data["route"] = route
+	data["role"] = c.GetString("role")
+	detailsJSON, _ := json.Marshal(map[string]interface{}{
+		"action": "admin_access",
+		"data":   data,
+	})
+
+	if err := h.logService.Log(c.Request.Context(), &services.UserAction{
+		UserID:    userID,
+		Action:    "view",
+		Entity:    "audit_log",
+		Details:   string(detailsJSON),
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to log audit log access: %v", err)
+	}
+}
+
+// VerifyMyLogs - проверить цепочку хешей своих логов
+func (h *LogHandler) VerifyMyLogs(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	report, err := h.auditChainService.Verify(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// VerifyAllLogs - проверить цепочку пользователя из user_id или все цепочки
+func (h *LogHandler) VerifyAllLogs(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var (
+		reports []*models.AuditChainReport
+		err     error
+	)
+	if target := c.Query("user_id"); target != "" {
+		if _, parseErr := uuid.Parse(target); parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		var report *models.AuditChainReport
+		report, err = h.auditChainService.Verify(c.Request.Context(), target)
+		reports = []*models.AuditChainReport{report}
+	} else {
+		reports, err = h.auditChainService.VerifyAll(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	valid := true
+	for _, report := range reports {
+		valid = valid && report.Valid
+	}
+
+	h.logAdminAccess(c, userID.(string), "logs/all/verify", map[string]interface{}{
+		"user_id": c.Query("user_id"),
+		"chains":  len(reports),
+		"valid":   valid,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   valid,
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// LogInternalAction - для логирования из других микросервисов (auth, analytics).
+// Подпись запроса проверяет ServiceAuthMiddleware. Запись сохраняется до
+// ответа: получив 200, сервис считает её доставленной. Повторная доставка
+// с тем же idempotency_key не создаёт дубликат.
+func (h *LogHandler) LogInternalAction(c *gin.Context) {
 	var req struct {
-		UserID   string                 `json:"user_id"`
-		Action   string                 `json:"action"`
-		Entity   string                 `json:"entity"`
+		UserID   string                 `json:"user_id" binding:"required,uuid"`
+		Action   string                 `json:"action" binding:"required"`
+		Entity   string                 `json:"entity" binding:"required"`
 		EntityID string                 `json:"entity_id"`
 		Data     map[string]interface{} `json:"data"`
+
+		// Данные исходного запроса пользователя
+		IP        string `json:"ip" binding:"omitempty,ip"`
+		UserAgent string `json:"user_agent"`
+		SessionID string `json:"session_id" binding:"omitempty,max=64"`
+
+		// Ключ записи у отправителя, например ID в его outbox
+		IdempotencyKey string `json:"idempotency_key" binding:"omitempty,max=64"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -151,10 +321,31 @@ func (h *LogHandler) LogInternalAction(c *gin.Context) {
 		return
 	}
 
-	// Преобразуем data в JSON string
-	detailsJSON, _ := json.Marshal(req.Data)
+	// Действие сервиса хранится в общем формате details
+	detailsJSON, _ := json.Marshal(&models.AuditDetails{
+		Action: req.Action,
+		Data:   req.Data,
+	})
 
-	err := h.logService.Log(c.Request.Context(), &services.UserAction{
+	// IP и User-Agent берём из исходного запроса, а не от вызывающего
+	// сервиса; request ID приходит в заголовке
+	userAgent := req.UserAgent
+	if userAgent == "" {
+		userAgent = c.GetString("service")
+	}
+	ctx := auditctx.WithInfo(c.Request.Context(), &auditctx.Info{
+		IP:        req.IP,
+		UserAgent: userAgent,
+		RequestID: auditctx.FromContext(c.Request.Context()).RequestID,
+		SessionID: req.SessionID,
+	})
+
+	key := ""
+	if req.IdempotencyKey != "" {
+		key = c.GetString("service") + ":" + req.IdempotencyKey
+	}
+
+	err := h.logService.Write(ctx, key, &services.UserAction{
 		UserID:   req.UserID,
 		Action:   req.Action,
 		Entity:   req.Entity,
@@ -169,3 +360,121 @@ func (h *LogHandler) LogInternalAction(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged successfully"})
 }
+
+// GetWriterStats - очередь записи журнала и счётчики потерь
+func (h *LogHandler) GetWriterStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"stats": h.logService.GetWriterStats(),
+	})
+}
+
+// auditExportLimit caps a single export; narrow the filters to get more.
+const auditExportLimit = 10000
+
+// parseLogFilter reads the filters shared by the log list and export
+// endpoints. date_from and date_to take RFC 3339 or YYYY-MM-DD, where a
+// date_to day includes the whole day.
+func parseLogFilter(c *gin.Context) (*models.AuditLogFilter, error) {
+	filter := &models.AuditLogFilter{
+		Action:    c.Query("action"),
+		Entity:    c.Query("entity"),
+		EntityID:  c.Query("entity_id"),
+		RequestID: c.Query("request_id"),
+		Path:      c.Query("path"),
+		Field:     c.Query("field"),
+	}
+
+	if filter.EntityID != "" {
+		if _, err := uuid.Parse(filter.EntityID); err != nil {
+			return nil, fmt.Errorf("invalid entity_id")
+		}
+	}
+
+	if value := c.Query("date_from"); value != "" {
+		t, err := parseTimeParam(value, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date_from, expected RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &t
+	}
+
+	if value := c.Query("date_to"); value != "" {
+		t, err := parseTimeParam(value, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date_to, expected RFC 3339 or YYYY-MM-DD")
+		}
+		filter.To = &t
+	}
+
+	if value := c.Query("min_change"); value != "" {
+		minChange, err := strconv.ParseFloat(value, 64)
+		if err != nil || minChange < 0 {
+			return nil, fmt.Errorf("invalid min_change")
+		}
+		if filter.Field == "" {
+			return nil, fmt.Errorf("min_change requires field")
+		}
+		filter.MinChange = &minChange
+	}
+
+	return filter, nil
+}
+
+// exportFormat returns the requested export format, answering 400 itself
+// when it isn't supported.
+func exportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected csv or json"})
+		return "", false
+	}
+	return format, true
+}
+
+func writeLogsExport(c *gin.Context, logs []*models.AuditLogEntry, format string) {
+	filename := fmt.Sprintf("audit_log_%s.%s", time.Now().Format("20060102_150405"), format)
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		c.JSON(http.StatusOK, gin.H{
+			"logs":  logs,
+			"count": len(logs),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"id", "created_at", "user_id", "email", "action", "entity", "entity_id",
+		"ip", "user_agent", "request_id", "details"})
+	for _, e := range logs {
+		writer.Write([]string{
+			e.ID,
+			e.CreatedAt.Format(time.RFC3339),
+			e.UserID,
+			e.Email,
+			e.Action,
+			e.Entity,
+			e.EntityID,
+			e.IP,
+			e.UserAgent,
+			e.RequestID,
+			string(e.Details),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to write CSV: %v", err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+func logErrorStatus(err error) int {
+	if strings.HasPrefix(err.Error(), "invalid filter") {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OutboxHandler struct {
+	outboxRelay *services.OutboxRelay
+}
+
+func NewOutboxHandler(outboxRelay *services.OutboxRelay) *OutboxHandler {
+	return &OutboxHandler{outboxRelay: outboxRelay}
+}
+
+// GetStats - очередь outbox и задержка доставки
+func (h *OutboxHandler) GetStats(c *gin.Context) {
+	stats, err := h.outboxRelay.GetStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": stats,
+	})
+}
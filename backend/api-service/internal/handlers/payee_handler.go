@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"net/http"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PayeeHandler struct {
+	payeeService *services.PayeeService
+}
+
+func NewPayeeHandler(payeeService *services.PayeeService) *PayeeHandler {
+	return &PayeeHandler{
+		payeeService: payeeService,
+	}
+}
+
+func (h *PayeeHandler) CreatePayee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreatePayeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payee, err := h.payeeService.CreatePayee(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payee created successfully",
+		"payee":   payee,
+	})
+}
+
+func (h *PayeeHandler) GetPayees(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payees, err := h.payeeService.GetPayees(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payees": payees,
+		"count":  len(payees),
+	})
+}
+
+func (h *PayeeHandler) GetPayee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	if payeeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID is required"})
+		return
+	}
+
+	payee, err := h.payeeService.GetPayee(c.Request.Context(), requestScope(c, userID), payeeID)
+	if err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payee": payee,
+	})
+}
+
+func (h *PayeeHandler) UpdatePayee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	if payeeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID is required"})
+		return
+	}
+
+	var req models.UpdatePayeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payee, err := h.payeeService.RenamePayee(c.Request.Context(), requestScope(c, userID), payeeID, &req)
+	if err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payee updated successfully",
+		"payee":   payee,
+	})
+}
+
+func (h *PayeeHandler) DeletePayee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	if payeeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID is required"})
+		return
+	}
+
+	if err := h.payeeService.DeletePayee(c.Request.Context(), requestScope(c, userID), payeeID); err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payee deleted successfully",
+	})
+}
+
+func (h *PayeeHandler) MergePayees(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	if payeeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID is required"})
+		return
+	}
+
+	var req models.MergePayeesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	payee, err := h.payeeService.MergePayees(c.Request.Context(), requestScope(c, userID), payeeID, &req)
+	if err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payees merged successfully",
+		"payee":   payee,
+	})
+}
+
+func (h *PayeeHandler) AddAlias(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	if payeeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID is required"})
+		return
+	}
+
+	var req models.CreatePayeeAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	alias, err := h.payeeService.AddAlias(c.Request.Context(), requestScope(c, userID), payeeID, &req)
+	if err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Alias added successfully",
+		"alias":   alias,
+	})
+}
+
+func (h *PayeeHandler) DeleteAlias(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	payeeID := c.Param("id")
+	aliasID := c.Param("aliasId")
+	if payeeID == "" || aliasID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payee ID and alias ID are required"})
+		return
+	}
+
+	if err := h.payeeService.DeleteAlias(c.Request.Context(), requestScope(c, userID), payeeID, aliasID); err != nil {
+		c.JSON(payeeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alias deleted successfully",
+	})
+}
+
+func payeeErrorStatus(err error) int {
+	switch err.Error() {
+	case "payee not found", "alias not found":
+		return http.StatusNotFound
+	case "insufficient workspace permissions":
+		return http.StatusForbidden
+	case "no payees to merge", "alias pattern is empty after normalisation":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
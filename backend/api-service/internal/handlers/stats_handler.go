@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -9,14 +10,27 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// statsCacheBypassHeader set to true makes a stats request skip the cache,
+// e.g. right after an import.
+const statsCacheBypassHeader = "X-Cache-Bypass"
+
 type StatsHandler struct {
 	statsService *services.StatsService
+	statsCache   *services.StatsCache
 }
 
-func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+func NewStatsHandler(statsService *services.StatsService, statsCache *services.StatsCache) *StatsHandler {
 	return &StatsHandler{
 		statsService: statsService,
+		statsCache:   statsCache,
+	}
+}
+
+func statsContext(c *gin.Context) context.Context {
+	if bypass, _ := strconv.ParseBool(c.GetHeader(statsCacheBypassHeader)); bypass {
+		return services.BypassStatsCache(c.Request.Context())
 	}
+	return c.Request.Context()
 }
 
 func (h *StatsHandler) GetSummary(c *gin.Context) {
@@ -31,7 +45,7 @@ func (h *StatsHandler) GetSummary(c *gin.Context) {
 	c.Header("Pragma", "no-cache")
 	c.Header("Expires", "0")
 
-	summary, err := h.statsService.GetSummary(c.Request.Context(), userID.(string))
+	summary, err := h.statsService.GetSummary(statsContext(c), requestScope(c, userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -56,7 +70,7 @@ func (h *StatsHandler) GetMonthlyStats(c *gin.Context) {
 		}
 	}
 
-	stats, err := h.statsService.GetMonthlyStats(c.Request.Context(), userID.(string), months)
+	stats, err := h.statsService.GetMonthlyStats(statsContext(c), requestScope(c, userID), months)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -90,7 +104,7 @@ func (h *StatsHandler) GetCategoryStats(c *gin.Context) {
 		return
 	}
 
-	breakdown, err := h.statsService.GetCategoryBreakdown(c.Request.Context(), userID.(string), transactionType, period)
+	breakdown, err := h.statsService.GetCategoryBreakdown(statsContext(c), requestScope(c, userID), transactionType, period)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -113,7 +127,7 @@ func (h *StatsHandler) GetBalanceHistory(c *gin.Context) {
 		}
 	}
 
-	history, err := h.statsService.GetBalanceHistory(c.Request.Context(), userID.(string), days)
+	history, err := h.statsService.GetBalanceHistory(statsContext(c), requestScope(c, userID), days)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -124,3 +138,41 @@ func (h *StatsHandler) GetBalanceHistory(c *gin.Context) {
 		"days":    days,
 	})
 }
+
+func (h *StatsHandler) GetPayeeStats(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	period := c.Query("period")
+	if period == "" {
+		period = "quarter"
+	}
+
+	limit := 20 // Default to top 20 payees
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	stats, err := h.statsService.GetPayeeStats(c.Request.Context(), requestScope(c, userID), period, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payees": stats,
+		"period": period,
+	})
+}
+
+// GetCacheStats - попадания и промахи кэша статистики
+func (h *StatsHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"stats": h.statsCache.GetStats(),
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuggestionHandler struct {
+	suggestionService *services.SuggestionService
+}
+
+func NewSuggestionHandler(suggestionService *services.SuggestionService) *SuggestionHandler {
+	return &SuggestionHandler{
+		suggestionService: suggestionService,
+	}
+}
+
+func (h *SuggestionHandler) SuggestCategories(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	description := c.Query("description")
+
+	var amount float64
+	if a := c.Query("amount"); a != "" {
+		parsed, err := strconv.ParseFloat(a, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+			return
+		}
+		amount = parsed
+	}
+
+	if description == "" && amount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Description or amount is required"})
+		return
+	}
+
+	categoryType := c.Query("type")
+	if categoryType != "" && categoryType != "income" && categoryType != "expense" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category type"})
+		return
+	}
+
+	limit := 3
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 20 {
+			limit = parsed
+		}
+	}
+
+	suggestions, err := h.suggestionService.Suggest(c.Request.Context(), requestScope(c, userID), description, amount, categoryType, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+	})
+}
@@ -37,11 +37,13 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.transactionService.CreateTransaction(c.Request.Context(), userID.(string), &req)
+	transaction, err := h.transactionService.CreateTransaction(c.Request.Context(), requestScope(c, userID), &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "category not found" || err.Error() == "account not found" {
+		if err.Error() == "category not found" || err.Error() == "account not found" || err.Error() == "payee not found" {
 			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient account permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -62,7 +64,8 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 	}
 
 	filter := &models.TransactionFilter{
-		UserID: userID.(string),
+		UserID:      userID.(string),
+		WorkspaceID: c.GetString("workspaceID"),
 	}
 
 	// Parse query parameters
@@ -133,7 +136,7 @@ func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.transactionService.GetTransaction(c.Request.Context(), userID.(string), transactionID)
+	transaction, err := h.transactionService.GetTransaction(c.Request.Context(), requestScope(c, userID), transactionID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "transaction not found" {
@@ -171,11 +174,13 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.transactionService.UpdateTransaction(c.Request.Context(), userID.(string), transactionID, &req)
+	transaction, err := h.transactionService.UpdateTransaction(c.Request.Context(), requestScope(c, userID), transactionID, &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "transaction not found" {
+		if err.Error() == "transaction not found" || err.Error() == "account not found" || err.Error() == "category not found" || err.Error() == "payee not found" {
 			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient account permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
@@ -201,11 +206,13 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
-	err := h.transactionService.DeleteTransaction(c.Request.Context(), userID.(string), transactionID)
+	err := h.transactionService.DeleteTransaction(c.Request.Context(), requestScope(c, userID), transactionID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "transaction not found" {
 			statusCode = http.StatusNotFound
+		} else if err.Error() == "insufficient account permissions" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{"error": err.Error()})
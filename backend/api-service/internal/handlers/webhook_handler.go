@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), requestScope(c, userID), &req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": webhook,
+	})
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	webhooks, err := h.webhookService.GetWebhooks(c.Request.Context(), requestScope(c, userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+		"count":    len(webhooks),
+	})
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), requestScope(c, userID), c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": webhook,
+	})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), requestScope(c, userID), c.Param("id"), &req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"webhook": webhook,
+	})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), requestScope(c, userID), c.Param("id")); err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// SendTest delivers a webhook.test event and returns the delivery result.
+func (h *WebhookHandler) SendTest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	delivery, err := h.webhookService.SendTest(c.Request.Context(), requestScope(c, userID), c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery": delivery,
+	})
+}
+
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request.Context(), requestScope(c, userID), c.Param("id"), limit, offset)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), requestScope(c, userID), c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Redelivery queued",
+		"delivery": delivery,
+	})
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case err.Error() == "webhook not found",
+		err.Error() == "delivery not found":
+		return http.StatusNotFound
+	case err.Error() == "insufficient workspace permissions":
+		return http.StatusForbidden
+	case err.Error() == "webhook is disabled":
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "webhook url must"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
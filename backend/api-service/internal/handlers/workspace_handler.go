@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+
+	"api-service/internal/models"
+	"api-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *services.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+// requestScope combines the authenticated user with the workspace selected
+// by the workspace middleware; no workspace means the personal space.
+func requestScope(c *gin.Context, userID interface{}) services.Scope {
+	return services.Scope{
+		UserID:      userID.(string),
+		WorkspaceID: c.GetString("workspaceID"),
+	}
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Workspace created successfully",
+		"workspace": workspace,
+	})
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaces, err := h.workspaceService.GetWorkspaces(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspaces": workspaces,
+		"count":      len(workspaces),
+		"active":     c.GetString("workspaceID"),
+	})
+}
+
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID is required"})
+		return
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(c.Request.Context(), userID.(string), workspaceID)
+	if err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace": workspace,
+	})
+}
+
+func (h *WorkspaceHandler) UpdateWorkspace(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID is required"})
+		return
+	}
+
+	var req models.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	workspace, err := h.workspaceService.UpdateWorkspace(c.Request.Context(), userID.(string), workspaceID, &req)
+	if err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Workspace updated successfully",
+		"workspace": workspace,
+	})
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID is required"})
+		return
+	}
+
+	if err := h.workspaceService.DeleteWorkspace(c.Request.Context(), userID.(string), workspaceID); err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workspace deleted successfully",
+	})
+}
+
+func (h *WorkspaceHandler) GetMembers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID is required"})
+		return
+	}
+
+	members, err := h.workspaceService.GetMembers(c.Request.Context(), userID.(string), workspaceID)
+	if err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+		"count":   len(members),
+	})
+}
+
+func (h *WorkspaceHandler) InviteMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID is required"})
+		return
+	}
+
+	var req models.InviteWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	member, err := h.workspaceService.InviteMember(c.Request.Context(), userID.(string), workspaceID, &req)
+	if err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+		"member":  member,
+	})
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	memberID := c.Param("userId")
+	if workspaceID == "" || memberID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID and user ID are required"})
+		return
+	}
+
+	var req models.UpdateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.workspaceService.UpdateMemberRole(c.Request.Context(), userID.(string), workspaceID, memberID, &req); err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+	})
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceID := c.Param("id")
+	memberID := c.Param("userId")
+	if workspaceID == "" || memberID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workspace ID and user ID are required"})
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(c.Request.Context(), userID.(string), workspaceID, memberID); err != nil {
+		c.JSON(workspaceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+func workspaceErrorStatus(err error) int {
+	switch err.Error() {
+	case "workspace not found", "user not found", "member not found":
+		return http.StatusNotFound
+	case "insufficient workspace permissions":
+		return http.StatusForbidden
+	case "user is already a member of this workspace",
+		"cannot delete workspace with existing accounts":
+		return http.StatusConflict
+	case "workspace owner cannot leave the workspace":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
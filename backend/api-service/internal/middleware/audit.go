@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"api-service/pkg/auditctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuditContextMiddleware puts the client IP, user agent, request ID and
+// device ID into the request context, where the audit log picks them up.
+// An incoming X-Request-ID is kept so one ID follows a request across
+// services; otherwise a new one is generated. Either way it is returned in
+// the response.
+func AuditContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(auditctx.HeaderRequestID)
+		if !auditctx.ValidID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		deviceID := c.GetHeader(auditctx.HeaderDeviceID)
+		if !auditctx.ValidID(deviceID) {
+			deviceID = ""
+		}
+
+		info := &auditctx.Info{
+			IP:        c.ClientIP(), // honours the trusted proxies set on the router
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestID,
+			SessionID: deviceID,
+		}
+		c.Request = c.Request.WithContext(auditctx.WithInfo(c.Request.Context(), info))
+
+		c.Set("requestID", requestID)
+		c.Header(auditctx.HeaderRequestID, requestID)
+
+		c.Next()
+	}
+}
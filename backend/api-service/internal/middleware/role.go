@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets the request through only if the token's role is one of
+// roles. It must run after AuthMiddleware. Roles are read from the token, so
+// a role change takes effect when the user's access token is next refreshed.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Insufficient permissions",
+		})
+		c.Abort()
+	}
+}
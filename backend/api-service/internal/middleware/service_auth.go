@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"api-service/pkg/svcauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxServiceBody caps the body read to check a signature, which happens
+// before the caller is known.
+const maxServiceBody = 1 << 20
+
+// ServiceAuthMiddleware accepts only requests signed by another service with
+// the shared secret. Each nonce is accepted once; Redis remembers it for the
+// whole window in which its timestamp would still be valid.
+func ServiceAuthMiddleware(secret string, redisClient *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxServiceBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		service, nonce, err := svcauth.Verify(c.Request, secret, body, time.Now())
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Invalid service credentials",
+				"details": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		nonceKey := fmt.Sprintf("service_nonce:%s:%s", service, nonce)
+		fresh, err := redisClient.SetNX(c.Request.Context(), nonceKey, 1, 2*svcauth.MaxClockSkew).Result()
+		if err != nil {
+			log.Printf("Failed to check service nonce: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to verify request"})
+			c.Abort()
+			return
+		}
+		if !fresh {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Replayed request"})
+			c.Abort()
+			return
+		}
+
+		c.Set("service", service)
+
+		c.Next()
+	}
+}
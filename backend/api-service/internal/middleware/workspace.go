@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHeader selects the workspace a request works in. Requests
+// without it work in the user's personal space.
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceMiddleware checks the user belongs to the requested workspace and
+// exposes it to handlers as "workspaceID". It must run after AuthMiddleware.
+func WorkspaceMiddleware(verifyMember func(ctx context.Context, userID, workspaceID string) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceID := c.GetHeader(WorkspaceHeader)
+		if workspaceID == "" {
+			c.Next()
+			return
+		}
+
+		if err := verifyMember(c.Request.Context(), c.GetString("userID"), workspaceID); err != nil {
+			status := http.StatusInternalServerError
+			if err.Error() == "workspace not found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("workspaceID", workspaceID)
+
+		c.Next()
+	}
+}
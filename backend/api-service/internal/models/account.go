@@ -5,13 +5,18 @@ import (
 )
 
 type Account struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	Name      string    `json:"name" db:"name"`
-	Balance   float64   `json:"balance" db:"balance"`
-	IsDefault bool      `json:"is_default" db:"is_default"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	WorkspaceID *string   `json:"workspace_id" db:"workspace_id"`
+	Name        string    `json:"name" db:"name"`
+	Balance     float64   `json:"balance" db:"balance"`
+	IsDefault   bool      `json:"is_default" db:"is_default"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// Sharing
+	Role        string `json:"role"`         // owner, admin, editor or viewer
+	MemberCount int    `json:"member_count"` // members besides the owner
 }
 
 type CreateAccountRequest struct {
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+type AccountMember struct {
+	AccountID string    `json:"account_id" db:"account_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"` // owner, admin, editor or viewer
+	InvitedBy *string   `json:"invited_by,omitempty" db:"invited_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type InviteAccountMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
+
+type UpdateAccountMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditDetails is the JSON stored in user_actions.details. Its shape depends
+// on the entry's action:
+//
+//	create:  {"action": "created", "data": {"<field>": <value>, ...}}
+//	update:  {"action": "updated", "changes": {"<field>": {"old": <value>, "new": <value>}, ...}}
+//	delete:  {"action": "deleted", "data": {"<field>": <value>, ...}}
+//
+// Other entries carry their own event name and a data object, for example
+// {"action": "member_added", "data": {...}} on an account, {"action":
+// "admin_access", "data": {...}} on the audit log itself, or the action and
+// data sent by auth and analytics through /internal/logs. Field names are
+// the entity's JSON field names; amounts are numbers, dates are strings.
+type AuditDetails struct {
+	Action  string                 `json:"action"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Changes map[string]interface{} `json:"changes,omitempty"` // values are {"old", "new"} pairs
+}
+
+type AuditLogEntry struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Email     string          `json:"email,omitempty"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Details   json.RawMessage `json:"details"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	RequestID string          `json:"request_id"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditLogFilter selects audit entries. Empty fields don't filter.
+type AuditLogFilter struct {
+	UserID    string     `json:"user_id,omitempty"`
+	Action    string     `json:"action,omitempty"`
+	Entity    string     `json:"entity,omitempty"`
+	EntityID  string     `json:"entity_id,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	From      *time.Time `json:"date_from,omitempty"`
+	To        *time.Time `json:"date_to,omitempty"`
+
+	// Path is an SQL/JSON path predicate on details, for example
+	// `$.changes.amount.new > 10000`.
+	Path string `json:"path,omitempty"`
+
+	// Field keeps updates that changed the field; with MinChange, only
+	// numeric changes of at least MinChange in either direction.
+	Field     string   `json:"field,omitempty"`
+	MinChange *float64 `json:"min_change,omitempty"`
+}
+
+// AuditChainReport is the result of verifying one user's audit hash chain.
+type AuditChainReport struct {
+	UserID             string           `json:"user_id"`
+	Valid              bool             `json:"valid"`
+	Entries            int64            `json:"entries"`   // chained entries verified
+	Unchained          int64            `json:"unchained"` // written before hash chaining
+	Checkpoints        int              `json:"checkpoints"`
+	LastCheckpointAt   *time.Time       `json:"last_checkpoint_at,omitempty"`
+	SignaturesVerified bool             `json:"signatures_verified"` // false without AUDIT_CHECKPOINT_KEY
+	Break              *AuditChainBreak `json:"break,omitempty"`
+}
+
+// AuditChainBreak is the first link of a chain that doesn't verify.
+type AuditChainBreak struct {
+	Seq     int64  `json:"seq"`
+	EntryID string `json:"entry_id,omitempty"`
+	Reason  string `json:"reason"`
+}
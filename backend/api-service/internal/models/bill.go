@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+)
+
+type Bill struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	WorkspaceID    *string    `json:"workspace_id" db:"workspace_id"`
+	Name           string     `json:"name" db:"name"`
+	PayeeID        *string    `json:"payee_id" db:"payee_id"`
+	CategoryID     *string    `json:"category_id" db:"category_id"`
+	ExpectedAmount float64    `json:"expected_amount" db:"expected_amount"`
+	Frequency      string     `json:"frequency" db:"frequency"` // once, weekly, monthly, quarterly or yearly
+	Interval       int        `json:"interval" db:"interval"`   // every N periods
+	FirstDueDate   time.Time  `json:"first_due_date" db:"first_due_date"`
+	EndDate        *time.Time `json:"end_date" db:"end_date"`
+	ReminderDays   int        `json:"reminder_days" db:"reminder_days"`
+	Autopay        bool       `json:"autopay" db:"autopay"`
+	IsActive       bool       `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Joined fields
+	PayeeName string `json:"payee_name,omitempty" db:"payee_name"`
+
+	// Calculated fields
+	NextDueDate *time.Time `json:"next_due_date"` // earliest unpaid occurrence
+}
+
+type CreateBillRequest struct {
+	Name           string  `json:"name" binding:"required,min=1,max=100"`
+	PayeeID        string  `json:"payee_id" binding:"omitempty,uuid"`
+	CategoryID     string  `json:"category_id" binding:"omitempty,uuid"`
+	ExpectedAmount float64 `json:"expected_amount" binding:"required,gt=0"`
+	Frequency      string  `json:"frequency" binding:"required,oneof=once weekly monthly quarterly yearly"`
+	Interval       int     `json:"interval" binding:"omitempty,gt=0,lte=12"`
+	FirstDueDate   string  `json:"first_due_date" binding:"required"`
+	EndDate        string  `json:"end_date"`
+	ReminderDays   int     `json:"reminder_days" binding:"gte=0,lte=60"`
+	Autopay        bool    `json:"autopay"`
+}
+
+type UpdateBillRequest struct {
+	Name           string  `json:"name" binding:"omitempty,min=1,max=100"`
+	PayeeID        *string `json:"payee_id"`    // empty string unlinks the payee
+	CategoryID     *string `json:"category_id"` // empty string unlinks the category
+	ExpectedAmount float64 `json:"expected_amount" binding:"omitempty,gt=0"`
+	EndDate        *string `json:"end_date"` // empty string removes the end date
+	ReminderDays   *int    `json:"reminder_days" binding:"omitempty,gte=0,lte=60"`
+	Autopay        *bool   `json:"autopay"`
+	IsActive       *bool   `json:"is_active"`
+}
+
+type BillPayment struct {
+	ID            string    `json:"id" db:"id"`
+	BillID        string    `json:"bill_id" db:"bill_id"`
+	DueDate       time.Time `json:"due_date" db:"due_date"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	Amount        float64   `json:"amount" db:"amount"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type PayBillRequest struct {
+	TransactionID string `json:"transaction_id" binding:"required,uuid"`
+	DueDate       string `json:"due_date"` // defaults to the earliest unpaid occurrence
+}
+
+// BillOccurrence is one due date of a bill on the calendar.
+type BillOccurrence struct {
+	BillID        string    `json:"bill_id"`
+	Name          string    `json:"name"`
+	PayeeName     string    `json:"payee_name,omitempty"`
+	Amount        float64   `json:"amount"`
+	DueDate       time.Time `json:"due_date"`
+	RemindOn      time.Time `json:"remind_on"`
+	Autopay       bool      `json:"autopay"`
+	Status        string    `json:"status"` // paid, overdue, due_soon or upcoming
+	TransactionID *string   `json:"transaction_id,omitempty"`
+}
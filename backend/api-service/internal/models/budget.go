@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+type Budget struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	WorkspaceID *string   `json:"workspace_id" db:"workspace_id"`
+	Name        string    `json:"name" db:"name"`
+	Amount      float64   `json:"amount" db:"amount"`
+	Period      string    `json:"period" db:"period"` // weekly, monthly or yearly
+	Rollover    bool      `json:"rollover" db:"rollover"`
+	StartDate   time.Time `json:"start_date" db:"start_date"`
+	CategoryIDs []string  `json:"category_ids"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateBudgetRequest struct {
+	Name        string   `json:"name" binding:"required,min=1,max=100"`
+	Amount      float64  `json:"amount" binding:"required,gt=0"`
+	Period      string   `json:"period" binding:"omitempty,oneof=weekly monthly yearly"`
+	Rollover    bool     `json:"rollover"`
+	StartDate   string   `json:"start_date"` // YYYY-MM-DD, defaults to the current period
+	CategoryIDs []string `json:"category_ids" binding:"required,min=1,dive,uuid"`
+}
+
+type UpdateBudgetRequest struct {
+	Name        string   `json:"name" binding:"omitempty,min=1,max=100"`
+	Amount      float64  `json:"amount" binding:"omitempty,gt=0"`
+	Rollover    *bool    `json:"rollover"`
+	CategoryIDs []string `json:"category_ids" binding:"omitempty,min=1,dive,uuid"`
+}
+
+type BudgetProgress struct {
+	Budget         *Budget   `json:"budget"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	Carryover      float64   `json:"carryover"`
+	Available      float64   `json:"available"`
+	Spent          float64   `json:"spent"`
+	Remaining      float64   `json:"remaining"`
+	PercentUsed    float64   `json:"percent_used"`
+	DaysLeft       int       `json:"days_left"`
+	DailyAllowance float64   `json:"daily_allowance"`
+	Overspent      bool      `json:"overspent"`
+}
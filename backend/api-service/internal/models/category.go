@@ -5,14 +5,15 @@ import (
 )
 
 type Category struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    *string   `json:"user_id" db:"user_id"`
-	Name      string    `json:"name" db:"name"`
-	Type      string    `json:"type" db:"type"` // income or expense
-	Icon      string    `json:"icon" db:"icon"`
-	Color     string    `json:"color" db:"color"`
-	IsSystem  bool      `json:"is_system" db:"is_system"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID          string    `json:"id" db:"id"`
+	UserID      *string   `json:"user_id" db:"user_id"`
+	WorkspaceID *string   `json:"workspace_id" db:"workspace_id"`
+	Name        string    `json:"name" db:"name"`
+	Type        string    `json:"type" db:"type"` // income or expense
+	Icon        string    `json:"icon" db:"icon"`
+	Color       string    `json:"color" db:"color"`
+	IsSystem    bool      `json:"is_system" db:"is_system"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 type CreateCategoryRequest struct {
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+)
+
+type Counterparty struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Calculated fields
+	OwedToMe   float64 `json:"owed_to_me"`  // outstanding on money I lent
+	OwedByMe   float64 `json:"owed_by_me"`  // outstanding on money I borrowed
+	Balance    float64 `json:"balance"`     // owed_to_me - owed_by_me
+	OpenDebts  int     `json:"open_debts"`  // debts not fully repaid
+	HasOverdue bool    `json:"has_overdue"` // any open debt past its due date
+}
+
+type CreateCounterpartyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	Note string `json:"note" binding:"max=255"`
+}
+
+type UpdateCounterpartyRequest struct {
+	Name string  `json:"name" binding:"omitempty,min=1,max=100"`
+	Note *string `json:"note" binding:"omitempty,max=255"`
+}
+
+type Debt struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	CounterpartyID string     `json:"counterparty_id" db:"counterparty_id"`
+	AccountID      string     `json:"account_id" db:"account_id"`
+	Direction      string     `json:"direction" db:"direction"` // lent or borrowed
+	Amount         float64    `json:"amount" db:"amount"`
+	Description    string     `json:"description" db:"description"`
+	Date           time.Time  `json:"date" db:"date"`
+	DueDate        *time.Time `json:"due_date" db:"due_date"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Joined fields
+	CounterpartyName string `json:"counterparty_name,omitempty" db:"counterparty_name"`
+	AccountName      string `json:"account_name,omitempty" db:"account_name"`
+
+	// Calculated fields
+	RepaidAmount float64          `json:"repaid_amount"`
+	Outstanding  float64          `json:"outstanding"`
+	Status       string           `json:"status"` // open, overdue or repaid
+	Repayments   []*DebtRepayment `json:"repayments,omitempty"`
+}
+
+type CreateDebtRequest struct {
+	CounterpartyID string  `json:"counterparty_id" binding:"required,uuid"`
+	AccountID      string  `json:"account_id" binding:"required,uuid"`
+	Direction      string  `json:"direction" binding:"required,oneof=lent borrowed"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Description    string  `json:"description" binding:"max=255"`
+	Date           string  `json:"date" binding:"required"`
+	DueDate        string  `json:"due_date"`
+}
+
+type UpdateDebtRequest struct {
+	Description *string `json:"description" binding:"omitempty,max=255"`
+	DueDate     *string `json:"due_date"` // empty string removes the due date
+}
+
+type DebtRepayment struct {
+	ID        string    `json:"id" db:"id"`
+	DebtID    string    `json:"debt_id" db:"debt_id"`
+	AccountID string    `json:"account_id" db:"account_id"`
+	Amount    float64   `json:"amount" db:"amount"`
+	Date      time.Time `json:"date" db:"date"`
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateDebtRepaymentRequest struct {
+	AccountID string  `json:"account_id" binding:"omitempty,uuid"` // defaults to the debt account
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Date      string  `json:"date" binding:"required"`
+	Note      string  `json:"note" binding:"max=255"`
+}
+
+type DebtFilter struct {
+	UserID         string
+	CounterpartyID string
+	Direction      string
+	Status         string
+}
+
+type DebtSummary struct {
+	OwedToMe     float64 `json:"owed_to_me"`
+	OwedByMe     float64 `json:"owed_by_me"`
+	Net          float64 `json:"net"`
+	OpenDebts    int     `json:"open_debts"`
+	OverdueDebts int     `json:"overdue_debts"`
+}
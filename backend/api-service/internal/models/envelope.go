@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+type EnvelopeSettings struct {
+	UserID      string    `json:"user_id" db:"user_id"`
+	WorkspaceID *string   `json:"workspace_id" db:"workspace_id"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	StartMonth  time.Time `json:"start_month" db:"start_month"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateEnvelopeSettingsRequest struct {
+	Enabled    bool   `json:"enabled"`
+	StartMonth string `json:"start_month"` // YYYY-MM, defaults to the current month
+}
+
+type AssignEnvelopeRequest struct {
+	Month      string  `json:"month" binding:"required"` // YYYY-MM
+	CategoryID string  `json:"category_id" binding:"required,uuid"`
+	Amount     float64 `json:"amount" binding:"gte=0"`
+}
+
+type MoveEnvelopeRequest struct {
+	Month          string  `json:"month" binding:"required"` // YYYY-MM
+	FromCategoryID string  `json:"from_category_id" binding:"omitempty,uuid"`
+	ToCategoryID   string  `json:"to_category_id" binding:"omitempty,uuid"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type Envelope struct {
+	CategoryID    string  `json:"category_id"`
+	CategoryName  string  `json:"category_name"`
+	CategoryIcon  string  `json:"category_icon"`
+	CategoryColor string  `json:"category_color"`
+	Assigned      float64 `json:"assigned"`
+	Activity      float64 `json:"activity"`
+	Available     float64 `json:"available"`
+	Overspent     bool    `json:"overspent"`
+}
+
+type EnvelopeGrid struct {
+	Month          string      `json:"month"`
+	ReadyToAssign  float64     `json:"ready_to_assign"`
+	Income         float64     `json:"income"`
+	TotalAssigned  float64     `json:"total_assigned"`
+	TotalActivity  float64     `json:"total_activity"`
+	TotalAvailable float64     `json:"total_available"`
+	Overspent      float64     `json:"overspent"`
+	AgeOfMoney     *int        `json:"age_of_money"`
+	Envelopes      []*Envelope `json:"envelopes"`
+}
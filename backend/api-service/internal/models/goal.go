@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+type Goal struct {
+	ID           string     `json:"id" db:"id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	WorkspaceID  *string    `json:"workspace_id" db:"workspace_id"`
+	Name         string     `json:"name" db:"name"`
+	TargetAmount float64    `json:"target_amount" db:"target_amount"`
+	TargetDate   *time.Time `json:"target_date" db:"target_date"`
+	AccountID    *string    `json:"account_id" db:"account_id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Calculated fields
+	SavedAmount                 float64    `json:"saved_amount"`
+	RemainingAmount             float64    `json:"remaining_amount"`
+	Progress                    float64    `json:"progress"` // percent
+	MonthlyContributionRate     float64    `json:"monthly_contribution_rate"`
+	RequiredMonthlyContribution float64    `json:"required_monthly_contribution"`
+	ProjectedCompletionDate     *time.Time `json:"projected_completion_date"`
+	BehindSchedule              bool       `json:"behind_schedule"`
+	Completed                   bool       `json:"completed"`
+}
+
+type CreateGoalRequest struct {
+	Name         string  `json:"name" binding:"required,min=1,max=100"`
+	TargetAmount float64 `json:"target_amount" binding:"required,gt=0"`
+	TargetDate   string  `json:"target_date"` // YYYY-MM-DD
+	AccountID    string  `json:"account_id" binding:"omitempty,uuid"`
+}
+
+type UpdateGoalRequest struct {
+	Name         string  `json:"name" binding:"omitempty,min=1,max=100"`
+	TargetAmount float64 `json:"target_amount" binding:"omitempty,gt=0"`
+	TargetDate   string  `json:"target_date"` // YYYY-MM-DD
+	AccountID    string  `json:"account_id" binding:"omitempty,uuid"`
+}
+
+type GoalContribution struct {
+	ID            string    `json:"id" db:"id"`
+	GoalID        string    `json:"goal_id" db:"goal_id"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	Amount        float64   `json:"amount" db:"amount"` // negative for withdrawals
+	Date          time.Time `json:"date" db:"date"`
+	TransactionID *string   `json:"transaction_id" db:"transaction_id"`
+	Note          string    `json:"note" db:"note"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateGoalContributionRequest struct {
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"` // YYYY-MM-DD, defaults to the transaction date or today
+	TransactionID string  `json:"transaction_id" binding:"omitempty,uuid"`
+	Note          string  `json:"note" binding:"max=255"`
+}
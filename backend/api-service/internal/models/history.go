@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type HistoryEntry struct {
+	ID        string         `json:"id"`
+	Action    string         `json:"action"` // create, update, delete
+	Event     string         `json:"event"`  // created, updated, deleted, member_added, ...
+	Changes   []*FieldChange `json:"changes"`
+	UserID    string         `json:"user_id"`
+	Email     string         `json:"email,omitempty"`
+	Source    string         `json:"source"` // request (made over HTTP) or system
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// EntitySnapshot is an entity as its history shows it at a point in time.
+// Only fields recorded in the audit log are known.
+type EntitySnapshot struct {
+	At      time.Time              `json:"at"`
+	Exists  bool                   `json:"exists"`
+	Deleted bool                   `json:"deleted"`
+	State   map[string]interface{} `json:"state"`
+}
+
+type EntityHistory struct {
+	Entity   string          `json:"entity"`
+	EntityID string          `json:"entity_id"`
+	Entries  []*HistoryEntry `json:"entries"`
+	Snapshot *EntitySnapshot `json:"snapshot,omitempty"` // set when a point in time is requested
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+type Loan struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Principal   float64   `json:"principal" db:"principal"`
+	AnnualRate  float64   `json:"annual_rate" db:"annual_rate"` // percent, e.g. 12.5
+	TermMonths  int       `json:"term_months" db:"term_months"`
+	PaymentType string    `json:"payment_type" db:"payment_type"` // annuity or differentiated
+	StartDate   time.Time `json:"start_date" db:"start_date"`
+	AccountID   *string   `json:"account_id" db:"account_id"` // default account payments are taken from
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// Calculated fields
+	RemainingPrincipal  float64        `json:"remaining_principal"`
+	PrincipalPaid       float64        `json:"principal_paid"`
+	InterestPaid        float64        `json:"interest_paid"`
+	ProjectedInterest   float64        `json:"projected_interest"` // paid plus still scheduled
+	NextPayment         float64        `json:"next_payment"`
+	NextPaymentDate     *time.Time     `json:"next_payment_date"`
+	PaymentsMade        int            `json:"payments_made"`
+	PaymentsRemaining   int            `json:"payments_remaining"`
+	ProjectedPayoffDate *time.Time     `json:"projected_payoff_date"`
+	Payments            []*LoanPayment `json:"payments,omitempty"`
+}
+
+type CreateLoanRequest struct {
+	Name        string  `json:"name" binding:"required,min=1,max=100"`
+	Principal   float64 `json:"principal" binding:"required,gt=0"`
+	AnnualRate  float64 `json:"annual_rate" binding:"gte=0,lte=100"`
+	TermMonths  int     `json:"term_months" binding:"required,gt=0,lte=600"`
+	PaymentType string  `json:"payment_type" binding:"required,oneof=annuity differentiated"`
+	StartDate   string  `json:"start_date" binding:"required"`
+	AccountID   string  `json:"account_id" binding:"omitempty,uuid"`
+}
+
+type UpdateLoanRequest struct {
+	Name      string  `json:"name" binding:"omitempty,min=1,max=100"`
+	AccountID *string `json:"account_id" binding:"omitempty"` // empty string unlinks the account
+}
+
+type LoanPayment struct {
+	ID        string    `json:"id" db:"id"`
+	LoanID    string    `json:"loan_id" db:"loan_id"`
+	Kind      string    `json:"kind" db:"kind"`         // regular or early
+	Strategy  string    `json:"strategy" db:"strategy"` // shorten_term or reduce_payment, early payments only
+	Amount    float64   `json:"amount" db:"amount"`
+	Date      time.Time `json:"date" db:"date"`
+	AccountID *string   `json:"account_id" db:"account_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Calculated fields
+	Interest           float64 `json:"interest"`
+	PrincipalPart      float64 `json:"principal"`
+	RemainingPrincipal float64 `json:"remaining_principal"`
+}
+
+type CreateLoanPaymentRequest struct {
+	Kind      string  `json:"kind" binding:"omitempty,oneof=regular early"`
+	Strategy  string  `json:"strategy" binding:"omitempty,oneof=shorten_term reduce_payment"`
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Date      string  `json:"date" binding:"required"`
+	AccountID string  `json:"account_id" binding:"omitempty,uuid"` // defaults to the loan account
+}
+
+type LoanScheduleEntry struct {
+	Number             int       `json:"number"`
+	Date               time.Time `json:"date"`
+	Payment            float64   `json:"payment"`
+	Principal          float64   `json:"principal"`
+	Interest           float64   `json:"interest"`
+	RemainingPrincipal float64   `json:"remaining_principal"`
+	Status             string    `json:"status"` // paid, early or scheduled
+}
@@ -0,0 +1,19 @@
+package models
+
+type OutboxStats struct {
+	Pending            int     `json:"pending"`
+	Retrying           int     `json:"retrying"` // pending entries that failed at least once
+	Failed             int     `json:"failed"`   // entries the relay gave up on
+	DeliveredLastHour  int     `json:"delivered_last_hour"`
+	LagSeconds         float64 `json:"lag_seconds"` // age of the oldest pending entry
+	AvgDeliverySeconds float64 `json:"avg_delivery_seconds"`
+}
+
+type AuditWriterStats struct {
+	Queued   int   `json:"queued"`
+	Capacity int   `json:"capacity"`
+	Enqueued int64 `json:"enqueued"`
+	Written  int64 `json:"written"`
+	Dropped  int64 `json:"dropped"` // queue full or writer closed
+	Failed   int64 `json:"failed"`  // lost after all write attempts
+}
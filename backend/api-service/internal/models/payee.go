@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+type Payee struct {
+	ID          string        `json:"id" db:"id"`
+	UserID      string        `json:"user_id" db:"user_id"`
+	WorkspaceID *string       `json:"workspace_id" db:"workspace_id"`
+	Name        string        `json:"name" db:"name"`
+	Aliases     []*PayeeAlias `json:"aliases,omitempty"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type PayeeAlias struct {
+	ID        string    `json:"id" db:"id"`
+	PayeeID   string    `json:"payee_id" db:"payee_id"`
+	Pattern   string    `json:"pattern" db:"pattern"`       // normalised text
+	MatchType string    `json:"match_type" db:"match_type"` // exact, prefix or contains
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreatePayeeRequest struct {
+	Name    string   `json:"name" binding:"required,min=1,max=100"`
+	Aliases []string `json:"aliases"`
+}
+
+type UpdatePayeeRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type CreatePayeeAliasRequest struct {
+	Pattern   string `json:"pattern" binding:"required,min=1,max=100"`
+	MatchType string `json:"match_type" binding:"omitempty,oneof=exact prefix contains"`
+}
+
+type MergePayeesRequest struct {
+	SourceIDs []string `json:"source_ids" binding:"required,min=1,dive,uuid"`
+}
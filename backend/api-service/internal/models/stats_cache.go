@@ -0,0 +1,14 @@
+package models
+
+type StatsCacheKindStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+type StatsCacheStats struct {
+	Kinds         map[string]*StatsCacheKindStats `json:"kinds"` // summary, monthly, category, balance_history
+	Bypassed      int64                           `json:"bypassed"`
+	Invalidations int64                           `json:"invalidations"` // scope versions bumped
+	Errors        int64                           `json:"errors"`        // Redis failures, served uncached
+}
@@ -12,15 +12,21 @@ type Transaction struct {
 	Type        string    `json:"type" db:"type"` // income or expense
 	Amount      float64   `json:"amount" db:"amount"`
 	Description string    `json:"description" db:"description"`
+	PayeeID     *string   `json:"payee_id" db:"payee_id"`
 	Date        time.Time `json:"date" db:"date"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	CreatedBy   *string   `json:"created_by" db:"created_by"` // member who created it
+	UpdatedBy   *string   `json:"updated_by" db:"updated_by"` // member who last edited it
 
 	// Joined fields
-	AccountName   string `json:"account_name,omitempty" db:"account_name"`
-	CategoryName  string `json:"category_name,omitempty" db:"category_name"`
-	CategoryIcon  string `json:"category_icon,omitempty" db:"category_icon"`
-	CategoryColor string `json:"category_color,omitempty" db:"category_color"`
+	AccountName    string `json:"account_name,omitempty" db:"account_name"`
+	CategoryName   string `json:"category_name,omitempty" db:"category_name"`
+	CategoryIcon   string `json:"category_icon,omitempty" db:"category_icon"`
+	CategoryColor  string `json:"category_color,omitempty" db:"category_color"`
+	PayeeName      string `json:"payee_name,omitempty" db:"payee_name"`
+	CreatedByEmail string `json:"created_by_email,omitempty" db:"created_by_email"`
+	UpdatedByEmail string `json:"updated_by_email,omitempty" db:"updated_by_email"`
 }
 
 type CreateTransactionRequest struct {
@@ -28,6 +34,7 @@ type CreateTransactionRequest struct {
 	CategoryID  string  `json:"category_id" binding:"required,uuid"`
 	Amount      float64 `json:"amount" binding:"required,gt=0"`
 	Description string  `json:"description"`
+	PayeeID     string  `json:"payee_id" binding:"omitempty,uuid"`
 	Date        string  `json:"date" binding:"required"` // Changed from time.Time to string
 }
 
@@ -36,16 +43,18 @@ type UpdateTransactionRequest struct {
 	CategoryID  string  `json:"category_id" binding:"omitempty,uuid"`
 	Amount      float64 `json:"amount" binding:"omitempty,gt=0"`
 	Description string  `json:"description"`
+	PayeeID     string  `json:"payee_id" binding:"omitempty,uuid"`
 	Date        string  `json:"date"` // Changed from time.Time to string
 }
 
 type TransactionFilter struct {
-	UserID     string
-	AccountID  string
-	CategoryID string
-	Type       string
-	DateFrom   time.Time
-	DateTo     time.Time
-	Limit      int
-	Offset     int
+	UserID      string
+	WorkspaceID string // empty for the personal space
+	AccountID   string
+	CategoryID  string
+	Type        string
+	DateFrom    time.Time
+	DateTo      time.Time
+	Limit       int
+	Offset      int
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	ID          string   `json:"id" db:"id"`
+	UserID      string   `json:"user_id" db:"user_id"`
+	WorkspaceID *string  `json:"workspace_id" db:"workspace_id"`
+	URL         string   `json:"url" db:"url"`
+	EventTypes  []string `json:"event_types" db:"event_types"`
+	IsActive    bool     `json:"is_active" db:"is_active"`
+
+	// Secret is only returned when the webhook is created
+	Secret string `json:"secret,omitempty" db:"secret"`
+
+	FailureCount   int        `json:"failure_count" db:"failure_count"` // consecutive failed attempts
+	DisabledAt     *time.Time `json:"disabled_at" db:"disabled_at"`
+	DisabledReason string     `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID            string          `json:"id" db:"id"`
+	WebhookID     string          `json:"webhook_id" db:"webhook_id"`
+	EventID       string          `json:"event_id" db:"event_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"` // pending, succeeded or failed
+	Attempts      int             `json:"attempts" db:"attempts"`
+	ResponseCode  *int            `json:"response_code" db:"response_code"`
+	ResponseBody  string          `json:"response_body,omitempty" db:"response_body"`
+	Error         string          `json:"error,omitempty" db:"error"`
+	DurationMs    *int            `json:"duration_ms" db:"duration_ms"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at" db:"next_attempt_at"`
+	RedeliveryOf  *string         `json:"redelivery_of" db:"redelivery_of"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at" db:"delivered_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=500"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=transaction.created transaction.updated transaction.deleted account.created account.updated account.deleted"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=128"` // generated when empty
+}
+
+type UpdateWebhookRequest struct {
+	URL        string   `json:"url" binding:"omitempty,url,max=500"`
+	EventTypes []string `json:"event_types" binding:"omitempty,min=1,dive,oneof=transaction.created transaction.updated transaction.deleted account.created account.updated account.deleted"`
+	IsActive   *bool    `json:"is_active"` // re-enabling resets the failure count
+}
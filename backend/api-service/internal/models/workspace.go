@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Workspace is a household space shared by several users. It owns accounts,
+// categories, budgets and goals; rows without a workspace belong to their
+// user's personal space.
+type Workspace struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	OwnerID   string    `json:"owner_id" db:"owner_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Membership of the requesting user
+	Role        string `json:"role"` // owner, admin, member or viewer
+	MemberCount int    `json:"member_count"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Email       string    `json:"email" db:"email"`
+	Role        string    `json:"role" db:"role"` // owner, admin, member or viewer
+	InvitedBy   *string   `json:"invited_by,omitempty" db:"invited_by"`
+	JoinedAt    time.Time `json:"joined_at" db:"joined_at"`
+}
+
+type InviteWorkspaceMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=viewer member admin"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer member admin"`
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Account roles from least to most privileged. The owner is the user in
+// accounts.user_id; everybody else is listed in account_members.
+var accountRoleRank = map[string]int{
+	"viewer": 1,
+	"editor": 2,
+	"admin":  3,
+	"owner":  4,
+}
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// accountAccess joins the ways the user in $1 can reach account a: directly
+// shared or through the account's workspace. Workspace admins act as account
+// admins, members as editors and viewers as viewers.
+const accountAccess = `LEFT JOIN account_members m ON m.account_id = a.id AND m.user_id = $1
+         LEFT JOIN workspace_members wm ON wm.workspace_id = a.workspace_id AND wm.user_id = $1`
+
+const accountRoleColumn = `CASE WHEN a.user_id = $1 THEN 'owner'
+		WHEN wm.role IN ('owner', 'admin') THEN 'admin'
+		WHEN wm.role = 'member' THEN 'editor'
+		WHEN wm.role = 'viewer' THEN 'viewer'
+		ELSE m.role END`
+
+const accountAccessible = `(a.user_id = $1 OR m.user_id IS NOT NULL OR wm.user_id IS NOT NULL)`
+
+// accountRole returns the user's role on the account and the account owner.
+// Accounts the user can't see are reported as not found.
+func accountRole(ctx context.Context, q rowQuerier, userID, accountID string) (string, string, error) {
+	var ownerID, role string
+	err := q.QueryRowContext(ctx,
+		`SELECT a.user_id, `+accountRoleColumn+`
+		FROM accounts a
+		`+accountAccess+`
+		WHERE a.id = $2 AND `+accountAccessible,
+		userID, accountID).Scan(&ownerID, &role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", fmt.Errorf("account not found")
+		}
+		return "", "", fmt.Errorf("failed to check account access: %w", err)
+	}
+	return role, ownerID, nil
+}
+
+// requireAccountRole checks the user has at least minRole on the account and
+// returns the account owner.
+func requireAccountRole(ctx context.Context, q rowQuerier, userID, accountID, minRole string) (string, error) {
+	role, ownerID, err := accountRole(ctx, q, userID, accountID)
+	if err != nil {
+		return "", err
+	}
+	if accountRoleRank[role] < accountRoleRank[minRole] {
+		return "", fmt.Errorf("insufficient account permissions")
+	}
+	return ownerID, nil
+}
+
+type AccountMemberService struct {
+	db         *sql.DB
+	logService *LogService
+	statsCache *StatsCache
+}
+
+func NewAccountMemberService(db *sql.DB, logService *LogService, statsCache *StatsCache) *AccountMemberService {
+	return &AccountMemberService{
+		db:         db,
+		logService: logService,
+		statsCache: statsCache,
+	}
+}
+
+// GetMembers lists everybody with access to the account, owner first.
+func (s *AccountMemberService) GetMembers(ctx context.Context, userID, accountID string) ([]*models.AccountMember, error) {
+	if _, err := requireAccountRole(ctx, s.db, userID, accountID, "viewer"); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT a.id, u.id, u.email, 'owner', NULL, a.created_at
+		FROM accounts a JOIN users u ON a.user_id = u.id
+		WHERE a.id = $1
+		UNION ALL
+		SELECT m.account_id, u.id, u.email, m.role, m.invited_by, m.created_at
+		FROM account_members m JOIN users u ON m.user_id = u.id
+		WHERE m.account_id = $1`,
+		accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.AccountMember{}
+	for rows.Next() {
+		var m models.AccountMember
+		var invitedBy sql.NullString
+		if err := rows.Scan(&m.AccountID, &m.UserID, &m.Email, &m.Role, &invitedBy, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account member: %w", err)
+		}
+		if invitedBy.Valid {
+			m.InvitedBy = &invitedBy.String
+		}
+		members = append(members, &m)
+	}
+
+	return members, nil
+}
+
+// InviteMember gives another registered user access to the account. Only
+// admins and the owner can invite, and only the owner can appoint admins.
+func (s *AccountMemberService) InviteMember(ctx context.Context, userID, accountID string, req *models.InviteAccountMemberRequest) (*models.AccountMember, error) {
+	role, ownerID, err := accountRole(ctx, s.db, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if accountRoleRank[role] < accountRoleRank["admin"] ||
+		(req.Role == "admin" && role != "owner") {
+		return nil, fmt.Errorf("insufficient account permissions")
+	}
+
+	member := &models.AccountMember{
+		AccountID: accountID,
+		Role:      req.Role,
+		InvitedBy: &userID,
+		CreatedAt: time.Now(),
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`SELECT id, email FROM users WHERE LOWER(email) = LOWER($1)`,
+		strings.TrimSpace(req.Email)).Scan(&member.UserID, &member.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if member.UserID == ownerID {
+		return nil, fmt.Errorf("user already has access to this account")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO account_members (account_id, user_id, role, invited_by, created_at)
+         VALUES ($1, $2, $3, $4, $5)`,
+		member.AccountID, member.UserID, member.Role, member.InvitedBy, member.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("user already has access to this account")
+		}
+		return nil, fmt.Errorf("failed to add account member: %w", err)
+	}
+
+	err = s.logMemberChange(ctx, tx, userID, accountID, map[string]interface{}{
+		"action": "member_added",
+		"data": map[string]interface{}{
+			"user_id": member.UserID,
+			"email":   member.Email,
+			"role":    member.Role,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// The account now shows up in the member's personal stats
+	s.statsCache.Invalidate(ctx, Scope{UserID: member.UserID})
+
+	return member, nil
+}
+
+func (s *AccountMemberService) UpdateMemberRole(ctx context.Context, userID, accountID, memberID string, req *models.UpdateAccountMemberRequest) error {
+	role, _, err := accountRole(ctx, s.db, userID, accountID)
+	if err != nil {
+		return err
+	}
+
+	var oldRole string
+	err = s.db.QueryRowContext(ctx,
+		`SELECT role FROM account_members WHERE account_id = $1 AND user_id = $2`,
+		accountID, memberID).Scan(&oldRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("member not found")
+		}
+		return fmt.Errorf("failed to get account member: %w", err)
+	}
+
+	// Admins manage viewers and editors; admins are managed by the owner
+	if accountRoleRank[role] < accountRoleRank["admin"] ||
+		(role != "owner" && (oldRole == "admin" || req.Role == "admin")) {
+		return fmt.Errorf("insufficient account permissions")
+	}
+
+	if oldRole == req.Role {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE account_members SET role = $1 WHERE account_id = $2 AND user_id = $3`,
+		req.Role, accountID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to update account member: %w", err)
+	}
+
+	err = s.logMemberChange(ctx, tx, userID, accountID, map[string]interface{}{
+		"action": "member_updated",
+		"changes": map[string]interface{}{
+			"role": map[string]interface{}{
+				"user_id": memberID,
+				"old":     oldRole,
+				"new":     req.Role,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember revokes a member's access. Members can always remove
+// themselves to leave a shared account.
+func (s *AccountMemberService) RemoveMember(ctx context.Context, userID, accountID, memberID string) error {
+	role, _, err := accountRole(ctx, s.db, userID, accountID)
+	if err != nil {
+		return err
+	}
+
+	var memberRole string
+	err = s.db.QueryRowContext(ctx,
+		`SELECT role FROM account_members WHERE account_id = $1 AND user_id = $2`,
+		accountID, memberID).Scan(&memberRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("member not found")
+		}
+		return fmt.Errorf("failed to get account member: %w", err)
+	}
+
+	if memberID != userID &&
+		(accountRoleRank[role] < accountRoleRank["admin"] || (role != "owner" && memberRole == "admin")) {
+		return fmt.Errorf("insufficient account permissions")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM account_members WHERE account_id = $1 AND user_id = $2`,
+		accountID, memberID); err != nil {
+		return fmt.Errorf("failed to remove account member: %w", err)
+	}
+
+	action := "member_removed"
+	if memberID == userID {
+		action = "member_left"
+	}
+
+	err = s.logMemberChange(ctx, tx, userID, accountID, map[string]interface{}{
+		"action": action,
+		"data": map[string]interface{}{
+			"user_id": memberID,
+			"role":    memberRole,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, Scope{UserID: memberID})
+
+	return nil
+}
+
+func (s *AccountMemberService) logMemberChange(ctx context.Context, tx *sql.Tx, userID, accountID string, details map[string]interface{}) error {
+	detailsJSON, _ := json.Marshal(details)
+
+	return s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "account",
+		EntityID: accountID,
+		Details:  string(detailsJSON),
+	})
+}
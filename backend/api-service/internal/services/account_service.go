@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"api-service/internal/models"
+	"api-service/pkg/events"
 
 	"github.com/google/uuid"
 )
@@ -15,84 +16,148 @@ import (
 type AccountService struct {
 	db         *sql.DB
 	logService *LogService
+	publisher  *events.Publisher
+	statsCache *StatsCache
 }
 
-func NewAccountService(db *sql.DB, logService *LogService) *AccountService {
+func NewAccountService(db *sql.DB, logService *LogService, publisher *events.Publisher, statsCache *StatsCache) *AccountService {
 	return &AccountService{
 		db:         db,
 		logService: logService,
+		publisher:  publisher,
+		statsCache: statsCache,
 	}
 }
 
-func (s *AccountService) CreateAccount(ctx context.Context, userID string, req *models.CreateAccountRequest) (*models.Account, error) {
+func accountPayload(a *models.Account) *events.AccountPayload {
+	return &events.AccountPayload{
+		ID:          a.ID,
+		OwnerID:     a.UserID,
+		WorkspaceID: a.WorkspaceID,
+		Name:        a.Name,
+		Balance:     a.Balance,
+		IsDefault:   a.IsDefault,
+	}
+}
+
+func (s *AccountService) CreateAccount(ctx context.Context, scope Scope, req *models.CreateAccountRequest) (*models.Account, error) {
+	userID := scope.UserID
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := requireWorkspaceRole(ctx, tx, scope, "member"); err != nil {
+		return nil, err
+	}
+
 	// If setting as default, unset other defaults
 	if req.IsDefault {
 		_, err = tx.ExecContext(ctx,
-			`UPDATE accounts SET is_default = false WHERE user_id = $1`,
-			userID)
+			`UPDATE accounts SET is_default = false WHERE `+scope.owns("", "$1"),
+			scope.arg())
 		if err != nil {
 			return nil, fmt.Errorf("failed to unset default accounts: %w", err)
 		}
 	}
 
 	account := &models.Account{
-		ID:        uuid.New().String(),
-		UserID:    userID,
-		Name:      req.Name,
-		Balance:   req.Balance,
-		IsDefault: req.IsDefault,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: scope.workspace(),
+		Name:        req.Name,
+		Balance:     req.Balance,
+		IsDefault:   req.IsDefault,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Role:        "owner",
 	}
 
 	_, err = tx.ExecContext(ctx,
-		`INSERT INTO accounts (id, user_id, name, balance, is_default, created_at, updated_at) 
-         VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		account.ID, account.UserID, account.Name, account.Balance,
+		`INSERT INTO accounts (id, user_id, workspace_id, name, balance, is_default, created_at, updated_at) 
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		account.ID, account.UserID, account.WorkspaceID, account.Name, account.Balance,
 		account.IsDefault, account.CreatedAt, account.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// ✅ Логирование
 	logDetails := map[string]interface{}{
 		"action": "created",
 		"data": map[string]interface{}{
-			"id":         account.ID,
-			"name":       account.Name,
-			"balance":    account.Balance,
-			"is_default": account.IsDefault,
+			"id":           account.ID,
+			"workspace_id": account.WorkspaceID,
+			"name":         account.Name,
+			"balance":      account.Balance,
+			"is_default":   account.IsDefault,
 		},
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "create",
 		Entity:   "account",
 		EntityID: account.ID,
 		Details:  string(detailsJSON),
-	})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := enqueueEvent(ctx, tx, s.publisher, events.AccountCreated, userID, account.ID, accountPayload(account)); err != nil {
+		return nil, err
+	}
+
+	scopes, err := statsScopes(ctx, tx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
 
 	return account, nil
 }
 
-func (s *AccountService) GetAccounts(ctx context.Context, userID string) ([]*models.Account, error) {
+// accountColumns selects an account as seen by the user in $1. Directly
+// shared accounts never count as the member's default.
+const accountColumns = `a.id, a.user_id, a.workspace_id, a.name, a.balance,
+		a.is_default AND (a.user_id = $1 OR a.workspace_id IS NOT NULL),
+		` + accountRoleColumn + `,
+		(SELECT COUNT(*) FROM account_members am WHERE am.account_id = a.id),
+		a.created_at, a.updated_at`
+
+func scanAccount(row rowScanner) (*models.Account, error) {
+	var a models.Account
+	var workspaceID sql.NullString
+	err := row.Scan(&a.ID, &a.UserID, &workspaceID, &a.Name, &a.Balance,
+		&a.IsDefault, &a.Role, &a.MemberCount, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID.Valid {
+		a.WorkspaceID = &workspaceID.String
+	}
+	return &a, nil
+}
+
+// GetAccounts returns the accounts of the active scope: the workspace's
+// accounts, or the user's own accounts followed by accounts shared with them.
+func (s *AccountService) GetAccounts(ctx context.Context, scope Scope) ([]*models.Account, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, name, balance, is_default, created_at, updated_at 
-         FROM accounts WHERE user_id = $1 ORDER BY is_default DESC, created_at ASC`,
-		userID)
+		`SELECT `+accountColumns+`
+         FROM accounts a
+         `+accountAccess+`
+         WHERE a.id IN `+scope.accounts("$2")+`
+         ORDER BY a.user_id = $1 DESC, a.is_default DESC, a.created_at ASC`,
+		scope.UserID, scope.arg())
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accounts: %w", err)
@@ -101,43 +166,48 @@ func (s *AccountService) GetAccounts(ctx context.Context, userID string) ([]*mod
 
 	var accounts []*models.Account
 	for rows.Next() {
-		var a models.Account
-		err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.Balance,
-			&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+		a, err := scanAccount(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan account: %w", err)
 		}
-		accounts = append(accounts, &a)
+		accounts = append(accounts, a)
 	}
 
 	return accounts, nil
 }
 
-func (s *AccountService) GetAccount(ctx context.Context, userID, accountID string) (*models.Account, error) {
-	var a models.Account
-	err := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, name, balance, is_default, created_at, updated_at 
-         FROM accounts WHERE id = $1 AND user_id = $2`,
-		accountID, userID).Scan(&a.ID, &a.UserID, &a.Name, &a.Balance,
-		&a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+func (s *AccountService) GetAccount(ctx context.Context, scope Scope, accountID string) (*models.Account, error) {
+	a, err := scanAccount(s.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+`
+         FROM accounts a
+         `+accountAccess+`
+         WHERE a.id = $2 AND `+accountAccessible+` AND a.id IN `+scope.accounts("$3"),
+		scope.UserID, accountID, scope.arg()))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("account not found")
+			return nil, fmt.Errorf("account %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 
-	return &a, nil
+	return a, nil
 }
 
-func (s *AccountService) UpdateAccount(ctx context.Context, userID, accountID string, req *models.UpdateAccountRequest) (*models.Account, error) {
+func (s *AccountService) UpdateAccount(ctx context.Context, scope Scope, accountID string, req *models.UpdateAccountRequest) (*models.Account, error) {
+	userID := scope.UserID
+
 	// ✅ ШАГ 1: Получаем СТАРЫЙ аккаунт (до изменений)
-	oldAccount, err := s.GetAccount(ctx, userID, accountID)
+	oldAccount, err := s.GetAccount(ctx, scope, accountID)
 	if err != nil {
 		return nil, err // Уже содержит "account not found" если не найден
 	}
 
+	// Renaming or correcting the balance of a shared account needs admin rights
+	if accountRoleRank[oldAccount.Role] < accountRoleRank["admin"] {
+		return nil, fmt.Errorf("insufficient account permissions")
+	}
+
 	// ✅ ШАГ 2: Build update query + отслеживаем изменения
 	updateFields := make(map[string]interface{})
 	changes := make(map[string]map[string]interface{}) // ← Для логов
@@ -181,47 +251,95 @@ func (s *AccountService) UpdateAccount(ctx context.Context, userID, accountID st
 		i++
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d AND user_id = $%d", i, i+1)
-	args = append(args, accountID, userID)
+	query += fmt.Sprintf(" WHERE id = $%d", i)
+	args = append(args, accountID)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err = s.db.ExecContext(ctx, query, args...)
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update account: %w", err)
 	}
 
 	// ✅ ШАГ 4: Логирование с деталями "было → стало"
-	if len(changes) > 0 {
-		logDetails := map[string]interface{}{
-			"action":  "updated",
-			"changes": changes,
-		}
-		detailsJSON, _ := json.Marshal(logDetails)
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
 
-		go s.logService.Log(context.Background(), &UserAction{
-			UserID:   userID,
-			Action:   "update",
-			Entity:   "account",
-			EntityID: accountID,
-			Details:  string(detailsJSON),
-		})
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "account",
+		EntityID: accountID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	updated := *oldAccount
+	if name, ok := updateFields["name"].(string); ok {
+		updated.Name = name
+	}
+	if balance, ok := updateFields["balance"].(float64); ok {
+		updated.Balance = balance
+	}
+	if err := enqueueEvent(ctx, tx, s.publisher, events.AccountUpdated, userID, accountID, accountPayload(&updated)); err != nil {
+		return nil, err
 	}
 
-	return s.GetAccount(ctx, userID, accountID)
+	scopes, err := statsScopes(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return s.GetAccount(ctx, scope, accountID)
 }
 
 func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID string) error {
-	// Check if it's the only account
-	var count int
+	// ✅ ШАГ 1: Сохраняем данные аккаунта ДО удаления (для логов)
+	var accountName string
+	var balance float64
+	var isDefault bool
+	var workspaceID sql.NullString
 	err := s.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM accounts WHERE user_id = $1`,
-		userID).Scan(&count)
+		`SELECT name, balance, is_default, workspace_id FROM accounts WHERE id = $1 AND user_id = $2`,
+		accountID, userID).Scan(&accountName, &balance, &isDefault, &workspaceID)
 
 	if err != nil {
-		return fmt.Errorf("failed to count accounts: %w", err)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("account %w", ErrNotFound)
+		}
+		return fmt.Errorf("failed to get account info: %w", err)
 	}
 
-	if count <= 1 {
-		return fmt.Errorf("cannot delete the only account")
+	scope := Scope{UserID: userID, WorkspaceID: workspaceID.String}
+
+	// Check if it's the only personal account; workspaces may be left empty
+	if !workspaceID.Valid {
+		var count int
+		err = s.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM accounts WHERE `+scope.owns("", "$1"),
+			scope.arg()).Scan(&count)
+
+		if err != nil {
+			return fmt.Errorf("failed to count accounts: %w", err)
+		}
+
+		if count <= 1 {
+			return fmt.Errorf("cannot delete the only account")
+		}
 	}
 
 	// Check if account has transactions
@@ -238,19 +356,19 @@ func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID st
 		return fmt.Errorf("cannot delete account with existing transactions")
 	}
 
-	// ✅ ШАГ 1: Сохраняем данные аккаунта ДО удаления (для логов)
-	var accountName string
-	var balance float64
-	var isDefault bool
+	// Check if account has debts or repayments
+	var debtCount int
 	err = s.db.QueryRowContext(ctx,
-		`SELECT name, balance, is_default FROM accounts WHERE id = $1 AND user_id = $2`,
-		accountID, userID).Scan(&accountName, &balance, &isDefault)
+		`SELECT (SELECT COUNT(*) FROM debts WHERE account_id = $1) +
+		        (SELECT COUNT(*) FROM debt_repayments WHERE account_id = $1)`,
+		accountID).Scan(&debtCount)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("account not found")
-		}
-		return fmt.Errorf("failed to get account info: %w", err)
+		return fmt.Errorf("failed to check debts: %w", err)
+	}
+
+	if debtCount > 0 {
+		return fmt.Errorf("cannot delete account with existing debts")
 	}
 
 	// ✅ ШАГ 2: Начинаем транзакцию
@@ -260,6 +378,12 @@ func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID st
 	}
 	defer tx.Rollback()
 
+	// Members are removed with the account
+	scopes, err := statsScopes(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+
 	// Delete account
 	result, err := tx.ExecContext(ctx,
 		`DELETE FROM accounts WHERE id = $1 AND user_id = $2`,
@@ -275,27 +399,25 @@ func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID st
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("account not found")
+		return fmt.Errorf("account %w", ErrNotFound)
 	}
 
-	// If it was default, set another as default
+	// If it was default, set another in the same space as default
 	if isDefault {
 		_, err = tx.ExecContext(ctx,
 			`UPDATE accounts SET is_default = true 
-			WHERE user_id = $1 
-			ORDER BY created_at ASC 
-			LIMIT 1`,
-			userID)
+			WHERE id = (
+				SELECT id FROM accounts WHERE `+scope.owns("", "$1")+`
+				ORDER BY created_at ASC 
+				LIMIT 1
+			)`,
+			scope.arg())
 
 		if err != nil {
 			return fmt.Errorf("failed to set new default account: %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// ✅ ШАГ 3: Логирование с деталями удалённого аккаунта
 	logDetails := map[string]interface{}{
 		"action": "deleted",
@@ -307,17 +429,41 @@ func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID st
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "delete",
 		Entity:   "account",
 		EntityID: accountID,
 		Details:  string(detailsJSON),
-	})
+	}); err != nil {
+		return err
+	}
+
+	account := &events.AccountPayload{
+		ID:        accountID,
+		OwnerID:   userID,
+		Name:      accountName,
+		Balance:   balance,
+		IsDefault: isDefault,
+	}
+	if workspaceID.Valid {
+		account.WorkspaceID = &workspaceID.String
+	}
+	if err := enqueueEvent(ctx, tx, s.publisher, events.AccountDeleted, userID, accountID, account); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
 
 	return nil
 }
 
+// SetDefaultAccount makes the account the default of its space: the owner's
+// personal space, or its workspace, where workspace admins may change it.
 func (s *AccountService) SetDefaultAccount(ctx context.Context, userID, accountID string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -325,24 +471,33 @@ func (s *AccountService) SetDefaultAccount(ctx context.Context, userID, accountI
 	}
 	defer tx.Rollback()
 
-	// Check if account exists and belongs to user
-	var exists bool
+	// Check if account exists and the user may manage it
+	account := models.Account{ID: accountID, IsDefault: true}
+	var workspaceID sql.NullString
+	var memberRole sql.NullString
 	err = tx.QueryRowContext(ctx,
-		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND user_id = $2)`,
-		accountID, userID).Scan(&exists)
+		`SELECT a.user_id, a.name, a.balance, a.workspace_id, wm.role FROM accounts a
+		LEFT JOIN workspace_members wm ON wm.workspace_id = a.workspace_id AND wm.user_id = $2
+		WHERE a.id = $1 AND ((a.workspace_id IS NULL AND a.user_id = $2) OR wm.user_id IS NOT NULL)`,
+		accountID, userID).Scan(&account.UserID, &account.Name, &account.Balance, &workspaceID, &memberRole)
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("account %w", ErrNotFound)
+		}
 		return fmt.Errorf("failed to check account: %w", err)
 	}
 
-	if !exists {
-		return fmt.Errorf("account not found")
+	if memberRole.Valid && workspaceRoleRank[memberRole.String] < workspaceRoleRank["admin"] {
+		return fmt.Errorf("insufficient workspace permissions")
 	}
 
+	scope := Scope{UserID: userID, WorkspaceID: workspaceID.String}
+
 	// Unset all defaults
 	_, err = tx.ExecContext(ctx,
-		`UPDATE accounts SET is_default = false WHERE user_id = $1`,
-		userID)
+		`UPDATE accounts SET is_default = false WHERE `+scope.owns("", "$1"),
+		scope.arg())
 
 	if err != nil {
 		return fmt.Errorf("failed to unset default accounts: %w", err)
@@ -350,13 +505,20 @@ func (s *AccountService) SetDefaultAccount(ctx context.Context, userID, accountI
 
 	// Set new default
 	_, err = tx.ExecContext(ctx,
-		`UPDATE accounts SET is_default = true WHERE id = $1 AND user_id = $2`,
-		accountID, userID)
+		`UPDATE accounts SET is_default = true WHERE id = $1`,
+		accountID)
 
 	if err != nil {
 		return fmt.Errorf("failed to set default account: %w", err)
 	}
 
+	if workspaceID.Valid {
+		account.WorkspaceID = &workspaceID.String
+	}
+	if err := enqueueEvent(ctx, tx, s.publisher, events.AccountUpdated, userID, accountID, accountPayload(&account)); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -364,17 +526,17 @@ func (s *AccountService) SetDefaultAccount(ctx context.Context, userID, accountI
 	return nil
 }
 
-func (s *AccountService) GetAccountStats(ctx context.Context, userID, accountID string) (*models.AccountStats, error) {
+func (s *AccountService) GetAccountStats(ctx context.Context, scope Scope, accountID string) (*models.AccountStats, error) {
 	var stats models.AccountStats
 
 	// Get current balance
 	err := s.db.QueryRowContext(ctx,
-		`SELECT balance FROM accounts WHERE id = $1 AND user_id = $2`,
-		accountID, userID).Scan(&stats.CurrentBalance)
+		`SELECT balance FROM accounts WHERE id = $1 AND id IN `+scope.accounts("$2"),
+		accountID, scope.arg()).Scan(&stats.CurrentBalance)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("account not found")
+			return nil, fmt.Errorf("account %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
@@ -382,8 +544,8 @@ func (s *AccountService) GetAccountStats(ctx context.Context, userID, accountID
 	// Get total income
 	err = s.db.QueryRowContext(ctx,
 		`SELECT COALESCE(SUM(amount), 0) FROM transactions 
-         WHERE account_id = $1 AND user_id = $2 AND type = 'income'`,
-		accountID, userID).Scan(&stats.TotalIncome)
+         WHERE account_id = $1 AND type = 'income'`,
+		accountID).Scan(&stats.TotalIncome)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total income: %w", err)
@@ -392,8 +554,8 @@ func (s *AccountService) GetAccountStats(ctx context.Context, userID, accountID
 	// Get total expense
 	err = s.db.QueryRowContext(ctx,
 		`SELECT COALESCE(SUM(amount), 0) FROM transactions 
-         WHERE account_id = $1 AND user_id = $2 AND type = 'expense'`,
-		accountID, userID).Scan(&stats.TotalExpense)
+         WHERE account_id = $1 AND type = 'expense'`,
+		accountID).Scan(&stats.TotalExpense)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total expense: %w", err)
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"api-service/internal/config"
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Only one instance writes checkpoints at a time
+const auditCheckpointLockKey = 7291002
+
+// chainTimeFormat keeps the microseconds Postgres stores, so a timestamp
+// reads back exactly as it was hashed.
+const chainTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// chainContent is what an entry's hash covers. Every field is in the form
+// it reads back from the database, so verification can rebuild it.
+type chainContent struct {
+	UserID    string          `json:"user_id"`
+	Seq       int64           `json:"seq"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Details   json.RawMessage `json:"details"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	RequestID string          `json:"request_id"`
+	SessionID string          `json:"session_id"`
+	CreatedAt string          `json:"created_at"`
+	PrevHash  string          `json:"prev_hash"`
+}
+
+func (c *chainContent) hash() (string, error) {
+	body, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalUUID returns id in the lowercase form Postgres prints, or id
+// unchanged when it isn't a UUID.
+func canonicalUUID(id string) string {
+	if parsed, err := uuid.Parse(id); err == nil {
+		return parsed.String()
+	}
+	return id
+}
+
+// canonicalDetails re-encodes details so the JSONB round trip doesn't change
+// them: keys sorted, no whitespace, numbers in plain decimal form.
+func canonicalDetails(details string) (json.RawMessage, error) {
+	if details == "" {
+		return json.RawMessage("null"), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(details)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid audit details: %w", err)
+	}
+
+	body, err := json.Marshal(canonicalNumbers(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit details: %w", err)
+	}
+	return body, nil
+}
+
+func canonicalNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = canonicalNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = canonicalNumbers(item)
+		}
+	case json.Number:
+		return json.Number(canonicalNumber(v.String()))
+	}
+	return value
+}
+
+// canonicalNumber writes a JSON number without exponent or trailing zeros,
+// so 1e3, 1000 and 1000.0 hash the same.
+func canonicalNumber(number string) string {
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return number
+	}
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// A decimal fraction has as many digits as its denominator's larger
+	// power of 2 or 5; 400 covers anything a float64 prints
+	for prec := 1; prec <= 400; prec++ {
+		s := r.FloatString(prec)
+		if back, _ := new(big.Rat).SetString(s); back.Cmp(r) == 0 {
+			return s
+		}
+	}
+	return number
+}
+
+// AuditChainService verifies the audit hash chains and anchors them with
+// signed checkpoints.
+//
+// Each user's entries form a chain: an entry stores its sequence number, the
+// hash of the previous entry and a hash over both and its own content, so
+// editing or removing an entry breaks every link after it. Removing the
+// newest entries leaves a valid but shorter chain; checkpoints catch that.
+// A checkpoint records a chain's head signed with AUDIT_CHECKPOINT_KEY and
+// the signature of the user's previous checkpoint, so they can be neither
+// forged nor dropped from the middle without the key.
+type AuditChainService struct {
+	db       *sql.DB
+	key      []byte
+	interval time.Duration
+}
+
+func NewAuditChainService(db *sql.DB, cfg *config.Config) *AuditChainService {
+	interval := cfg.AuditCheckpointInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &AuditChainService{
+		db:       db,
+		key:      []byte(cfg.AuditCheckpointKey),
+		interval: interval,
+	}
+}
+
+func (s *AuditChainService) sign(userID string, seq int64, hash string, createdAt time.Time, prevSignature string) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s\n%d\n%s\n%s\n%s", userID, seq, hash, createdAt.UTC().Format(chainTimeFormat), prevSignature)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run writes checkpoints every interval until ctx is cancelled.
+func (s *AuditChainService) Run(ctx context.Context) {
+	if len(s.key) == 0 {
+		log.Println("AUDIT_CHECKPOINT_KEY is not set, audit checkpoints are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Checkpoint(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Audit checkpoint failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Checkpoint signs the head of every chain that grew since its last
+// checkpoint. The digest of the new signatures goes to the service log as
+// well, which keeps a copy of the anchors outside the database.
+func (s *AuditChainService) Checkpoint(ctx context.Context) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, auditCheckpointLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock audit checkpoints: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, auditCheckpointLockKey)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT h.user_id, h.seq, h.hash, COALESCE(c.signature, '')
+		FROM audit_chain_heads h
+		LEFT JOIN LATERAL (
+			SELECT seq, signature FROM audit_checkpoints
+			WHERE user_id = h.user_id
+			ORDER BY id DESC
+			LIMIT 1
+		) c ON TRUE
+		WHERE h.seq > COALESCE(c.seq, 0)
+		ORDER BY h.user_id`)
+	if err != nil {
+		return fmt.Errorf("failed to read audit chain heads: %w", err)
+	}
+
+	type head struct {
+		userID        string
+		seq           int64
+		hash          string
+		prevSignature string
+	}
+	heads := []*head{}
+	for rows.Next() {
+		var h head
+		if err := rows.Scan(&h.userID, &h.seq, &h.hash, &h.prevSignature); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan audit chain head: %w", err)
+		}
+		heads = append(heads, &h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit chain heads: %w", err)
+	}
+
+	if len(heads) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	digest := sha256.New()
+	for _, h := range heads {
+		signature := s.sign(h.userID, h.seq, h.hash, now, h.prevSignature)
+		if _, err := s.db.ExecContext(ctx,
+			`INSERT INTO audit_checkpoints (user_id, seq, hash, prev_signature, signature, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			h.userID, h.seq, h.hash, h.prevSignature, signature, now); err != nil {
+			return fmt.Errorf("failed to write audit checkpoint: %w", err)
+		}
+		digest.Write([]byte(signature))
+	}
+
+	log.Printf("Audit checkpoint at %s: %d chains, digest %s",
+		now.Format(chainTimeFormat), len(heads), hex.EncodeToString(digest.Sum(nil)))
+	return nil
+}
+
+// VerifyAll verifies the chain of every user that has one.
+func (s *AuditChainService) VerifyAll(ctx context.Context) ([]*models.AuditChainReport, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id FROM audit_chain_heads ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chains: %w", err)
+	}
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan audit chain: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit chains: %w", err)
+	}
+
+	reports := make([]*models.AuditChainReport, 0, len(userIDs))
+	for _, userID := range userIDs {
+		report, err := s.Verify(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Verify walks the user's chain from the start and reports the first broken
+// link, then checks it against the chain head and the checkpoints.
+func (s *AuditChainService) Verify(ctx context.Context, userID string) (*models.AuditChainReport, error) {
+	report := &models.AuditChainReport{
+		UserID:             userID,
+		SignaturesVerified: len(s.key) > 0,
+	}
+
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM user_actions WHERE user_id = $1 AND chain_seq IS NULL`,
+		userID).Scan(&report.Unchained); err != nil {
+		return nil, fmt.Errorf("failed to count unchained audit entries: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, chain_seq, COALESCE(prev_hash, ''), COALESCE(hash, ''), action, entity,
+			COALESCE(entity_id::text, ''), COALESCE(details::text, ''), COALESCE(ip, ''),
+			COALESCE(user_agent, ''), COALESCE(request_id, ''), COALESCE(session_id, ''), created_at
+		FROM user_actions
+		WHERE user_id = $1 AND chain_seq IS NOT NULL
+		ORDER BY chain_seq`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+
+	// Hashes by sequence number, to check the checkpoints against
+	hashes := map[int64]string{}
+	lastHash := ""
+	for rows.Next() {
+		var (
+			id, prevHash, storedHash, details string
+			content                           chainContent
+			createdAt                         time.Time
+		)
+		if err := rows.Scan(&id, &content.Seq, &prevHash, &storedHash, &content.Action, &content.Entity,
+			&content.EntityID, &details, &content.IP, &content.UserAgent, &content.RequestID,
+			&content.SessionID, &createdAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+
+		if report.Break != nil {
+			continue
+		}
+
+		switch {
+		case content.Seq != report.Entries+1:
+			report.Break = &models.AuditChainBreak{Seq: report.Entries + 1, EntryID: id,
+				Reason: fmt.Sprintf("entry %d is missing, next entry is %d", report.Entries+1, content.Seq)}
+		case prevHash != lastHash:
+			report.Break = &models.AuditChainBreak{Seq: content.Seq, EntryID: id,
+				Reason: "previous hash does not match the previous entry"}
+		}
+		if report.Break != nil {
+			continue
+		}
+
+		content.UserID = userID
+		content.CreatedAt = createdAt.UTC().Format(chainTimeFormat)
+		content.PrevHash = prevHash
+		content.Details, err = canonicalDetails(details)
+		if err != nil {
+			report.Break = &models.AuditChainBreak{Seq: content.Seq, EntryID: id, Reason: err.Error()}
+			continue
+		}
+
+		hash, err := content.hash()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if hash != storedHash {
+			report.Break = &models.AuditChainBreak{Seq: content.Seq, EntryID: id,
+				Reason: "content does not match its hash"}
+			continue
+		}
+
+		report.Entries++
+		hashes[content.Seq] = hash
+		lastHash = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+
+	if err := s.verifyCheckpoints(ctx, report, hashes); err != nil {
+		return nil, err
+	}
+
+	// The head moves with every write, so it must be the last entry
+	if report.Break == nil {
+		var (
+			headSeq  int64
+			headHash string
+		)
+		err := s.db.QueryRowContext(ctx,
+			`SELECT seq, hash FROM audit_chain_heads WHERE user_id = $1`, userID).Scan(&headSeq, &headHash)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to read audit chain head: %w", err)
+		}
+		if err == nil && (headSeq != report.Entries || headHash != lastHash) {
+			report.Break = &models.AuditChainBreak{Seq: report.Entries + 1,
+				Reason: fmt.Sprintf("chain ends at entry %d but its head is entry %d", report.Entries, headSeq)}
+		}
+	}
+
+	report.Valid = report.Break == nil
+	return report, nil
+}
+
+func (s *AuditChainService) verifyCheckpoints(ctx context.Context, report *models.AuditChainReport, hashes map[int64]string) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, seq, hash, prev_signature, signature, created_at
+		FROM audit_checkpoints
+		WHERE user_id = $1
+		ORDER BY id`,
+		report.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to read audit checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	prevSignature := ""
+	for rows.Next() {
+		var (
+			id                          int64
+			seq                         int64
+			hash, storedPrev, signature string
+			createdAt                   time.Time
+		)
+		if err := rows.Scan(&id, &seq, &hash, &storedPrev, &signature, &createdAt); err != nil {
+			return fmt.Errorf("failed to scan audit checkpoint: %w", err)
+		}
+		report.Checkpoints++
+		report.LastCheckpointAt = &createdAt
+
+		var reason string
+		switch {
+		case storedPrev != prevSignature:
+			reason = fmt.Sprintf("checkpoint %d does not follow the previous checkpoint", id)
+		case report.SignaturesVerified &&
+			!hmac.Equal([]byte(signature), []byte(s.sign(report.UserID, seq, hash, createdAt, storedPrev))):
+			reason = fmt.Sprintf("checkpoint %d has an invalid signature", id)
+		case seq > report.Entries:
+			reason = fmt.Sprintf("checkpoint %d covers entry %d but the chain ends at entry %d", id, seq, report.Entries)
+		case hashes[seq] != hash:
+			reason = fmt.Sprintf("entry %d does not match checkpoint %d", seq, id)
+		}
+		prevSignature = signature
+
+		// Keep the earliest break in the chain
+		breakSeq := min(seq, report.Entries+1)
+		if reason != "" && (report.Break == nil || breakSeq < report.Break.Seq) {
+			report.Break = &models.AuditChainBreak{Seq: breakSeq, Reason: reason}
+		}
+	}
+	return rows.Err()
+}
+
+// chainHead is the last entry of a user's chain as writeActions tracks it.
+type chainHead struct {
+	seq  int64
+	hash string
+}
+
+// lockChainHeads locks the chain heads of the users for the rest of tx,
+// creating missing ones, so concurrent writers append to a chain one at a
+// time. Heads are locked in user order to avoid deadlocks.
+func lockChainHeads(ctx context.Context, tx *sql.Tx, userIDs []string) (map[string]*chainHead, error) {
+	sort.Strings(userIDs)
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_chain_heads (user_id, seq, hash)
+		SELECT id, 0, '' FROM unnest($1::uuid[]) AS id
+		ON CONFLICT (user_id) DO NOTHING`,
+		pq.Array(userIDs)); err != nil {
+		return nil, fmt.Errorf("failed to create audit chain heads: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT user_id, seq, hash FROM audit_chain_heads
+		WHERE user_id = ANY($1::uuid[])
+		ORDER BY user_id
+		FOR UPDATE`,
+		pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock audit chain heads: %w", err)
+	}
+	defer rows.Close()
+
+	heads := map[string]*chainHead{}
+	for rows.Next() {
+		var (
+			userID string
+			head   chainHead
+		)
+		if err := rows.Scan(&userID, &head.seq, &head.hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit chain head: %w", err)
+		}
+		heads[userID] = &head
+	}
+	return heads, rows.Err()
+}
@@ -0,0 +1,926 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// billOverdueWindowDays is how far back unpaid occurrences are still shown
+// as overdue on the calendar.
+const billOverdueWindowDays = 31
+
+// maxBillOccurrences bounds schedule expansion for very frequent bills.
+const maxBillOccurrences = 5000
+
+type BillService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewBillService(db *sql.DB, logService *LogService) *BillService {
+	return &BillService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+func (s *BillService) CreateBill(ctx context.Context, scope Scope, req *models.CreateBillRequest) (*models.Bill, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	firstDueDate, err := time.Parse("2006-01-02", req.FirstDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+	}
+
+	bill := &models.Bill{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		WorkspaceID:    scope.workspace(),
+		Name:           req.Name,
+		ExpectedAmount: req.ExpectedAmount,
+		Frequency:      req.Frequency,
+		Interval:       req.Interval,
+		FirstDueDate:   firstDueDate,
+		ReminderDays:   req.ReminderDays,
+		Autopay:        req.Autopay,
+		IsActive:       true,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if bill.Interval == 0 {
+		bill.Interval = 1
+	}
+
+	if req.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		if endDate.Before(firstDueDate) {
+			return nil, fmt.Errorf("end date is before the first due date")
+		}
+		bill.EndDate = &endDate
+	}
+
+	if req.PayeeID != "" {
+		if err := s.verifyPayee(ctx, scope, req.PayeeID); err != nil {
+			return nil, err
+		}
+		bill.PayeeID = &req.PayeeID
+	}
+
+	if req.CategoryID != "" {
+		if err := s.verifyCategory(ctx, scope, req.CategoryID); err != nil {
+			return nil, err
+		}
+		bill.CategoryID = &req.CategoryID
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO bills (id, user_id, workspace_id, name, payee_id, category_id, expected_amount, frequency, interval_count,
+            first_due_date, end_date, reminder_days, autopay, is_active, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		bill.ID, bill.UserID, bill.WorkspaceID, bill.Name, bill.PayeeID, bill.CategoryID, bill.ExpectedAmount,
+		bill.Frequency, bill.Interval, bill.FirstDueDate, bill.EndDate, bill.ReminderDays,
+		bill.Autopay, bill.IsActive, bill.CreatedAt, bill.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bill: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":              bill.ID,
+			"name":            bill.Name,
+			"expected_amount": bill.ExpectedAmount,
+			"frequency":       bill.Frequency,
+			"interval":        bill.Interval,
+			"first_due_date":  bill.FirstDueDate.Format("2006-01-02"),
+			"autopay":         bill.Autopay,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "bill",
+		EntityID: bill.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetBill(ctx, scope, bill.ID)
+}
+
+func (s *BillService) GetBills(ctx context.Context, scope Scope) ([]*models.Bill, error) {
+	rows, err := s.db.QueryContext(ctx, billSelect+`
+        WHERE `+scope.owns("b", "$1")+`
+        ORDER BY b.is_active DESC, b.name`,
+		scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bills: %w", err)
+	}
+	defer rows.Close()
+
+	bills := []*models.Bill{}
+	for rows.Next() {
+		bill, err := scanBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, bill)
+	}
+	rows.Close()
+
+	paid, err := s.getPaidOccurrences(ctx, scope, today().AddDate(0, 0, -billOverdueWindowDays))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bill := range bills {
+		bill.NextDueDate = nextUnpaidOccurrence(bill, paid)
+	}
+
+	return bills, nil
+}
+
+func (s *BillService) GetBill(ctx context.Context, scope Scope, billID string) (*models.Bill, error) {
+	row := s.db.QueryRowContext(ctx, billSelect+` WHERE b.id = $1 AND `+scope.owns("b", "$2"), billID, scope.arg())
+	bill, err := scanBill(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("bill not found")
+		}
+		return nil, err
+	}
+
+	paid, err := s.getPaidOccurrences(ctx, scope, today().AddDate(0, 0, -billOverdueWindowDays))
+	if err != nil {
+		return nil, err
+	}
+	bill.NextDueDate = nextUnpaidOccurrence(bill, paid)
+
+	return bill, nil
+}
+
+func (s *BillService) UpdateBill(ctx context.Context, scope Scope, billID string, req *models.UpdateBillRequest) (*models.Bill, error) {
+	userID := scope.UserID
+
+	oldBill, err := s.GetBill(ctx, scope, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	updateFields := make(map[string]interface{})
+	changes := make(map[string]map[string]interface{})
+
+	if req.Name != "" && req.Name != oldBill.Name {
+		updateFields["name"] = req.Name
+		changes["name"] = map[string]interface{}{"old": oldBill.Name, "new": req.Name}
+	}
+
+	if req.PayeeID != nil && *req.PayeeID != stringValue(oldBill.PayeeID) {
+		var payeeID *string
+		if *req.PayeeID != "" {
+			if err := s.verifyPayee(ctx, scope, *req.PayeeID); err != nil {
+				return nil, err
+			}
+			payeeID = req.PayeeID
+		}
+		updateFields["payee_id"] = payeeID
+		changes["payee_id"] = map[string]interface{}{"old": oldBill.PayeeID, "new": payeeID}
+	}
+
+	if req.CategoryID != nil && *req.CategoryID != stringValue(oldBill.CategoryID) {
+		var categoryID *string
+		if *req.CategoryID != "" {
+			if err := s.verifyCategory(ctx, scope, *req.CategoryID); err != nil {
+				return nil, err
+			}
+			categoryID = req.CategoryID
+		}
+		updateFields["category_id"] = categoryID
+		changes["category_id"] = map[string]interface{}{"old": oldBill.CategoryID, "new": categoryID}
+	}
+
+	if req.ExpectedAmount > 0 && req.ExpectedAmount != oldBill.ExpectedAmount {
+		updateFields["expected_amount"] = req.ExpectedAmount
+		changes["expected_amount"] = map[string]interface{}{"old": oldBill.ExpectedAmount, "new": req.ExpectedAmount}
+	}
+
+	if req.EndDate != nil {
+		var endDate *time.Time
+		if *req.EndDate != "" {
+			parsed, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+			}
+			if parsed.Before(oldBill.FirstDueDate) {
+				return nil, fmt.Errorf("end date is before the first due date")
+			}
+			endDate = &parsed
+		}
+		if formatOptionalDate(endDate) != formatOptionalDate(oldBill.EndDate) {
+			updateFields["end_date"] = endDate
+			changes["end_date"] = map[string]interface{}{
+				"old": formatOptionalDate(oldBill.EndDate),
+				"new": formatOptionalDate(endDate),
+			}
+		}
+	}
+
+	if req.ReminderDays != nil && *req.ReminderDays != oldBill.ReminderDays {
+		updateFields["reminder_days"] = *req.ReminderDays
+		changes["reminder_days"] = map[string]interface{}{"old": oldBill.ReminderDays, "new": *req.ReminderDays}
+	}
+
+	if req.Autopay != nil && *req.Autopay != oldBill.Autopay {
+		updateFields["autopay"] = *req.Autopay
+		changes["autopay"] = map[string]interface{}{"old": oldBill.Autopay, "new": *req.Autopay}
+	}
+
+	if req.IsActive != nil && *req.IsActive != oldBill.IsActive {
+		updateFields["is_active"] = *req.IsActive
+		changes["is_active"] = map[string]interface{}{"old": oldBill.IsActive, "new": *req.IsActive}
+	}
+
+	if len(updateFields) == 0 {
+		return oldBill, nil
+	}
+
+	updateFields["updated_at"] = time.Now()
+
+	query := "UPDATE bills SET "
+	args := []interface{}{}
+	argCount := 1
+
+	for field, value := range updateFields {
+		if argCount > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argCount)
+		args = append(args, value)
+		argCount++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d AND ", argCount) + scope.owns("", fmt.Sprintf("$%d", argCount+1))
+	args = append(args, billID, scope.arg())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update bill: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "bill",
+		EntityID: billID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetBill(ctx, scope, billID)
+}
+
+func (s *BillService) DeleteBill(ctx context.Context, scope Scope, billID string) error {
+	userID := scope.UserID
+
+	bill, err := s.GetBill(ctx, scope, billID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM bills WHERE id = $1 AND `+scope.owns("", "$2"), billID, scope.arg()); err != nil {
+		return fmt.Errorf("failed to delete bill: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name":            bill.Name,
+			"expected_amount": bill.ExpectedAmount,
+			"frequency":       bill.Frequency,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "bill",
+		EntityID: billID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetUpcoming returns the calendar of active bills from the overdue window
+// up to days ahead, sorted by due date. Unpaid past occurrences of autopay
+// bills are assumed to have been paid and are left out.
+func (s *BillService) GetUpcoming(ctx context.Context, scope Scope, days int) ([]*models.BillOccurrence, error) {
+	now := today()
+	from := now.AddDate(0, 0, -billOverdueWindowDays)
+	to := now.AddDate(0, 0, days)
+
+	rows, err := s.db.QueryContext(ctx, billSelect+` WHERE `+scope.owns("b", "$1")+` AND b.is_active = true`, scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bills: %w", err)
+	}
+	defer rows.Close()
+
+	bills := []*models.Bill{}
+	for rows.Next() {
+		bill, err := scanBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, bill)
+	}
+	rows.Close()
+
+	paid, err := s.getPaidOccurrences(ctx, scope, from)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := []*models.BillOccurrence{}
+	for _, bill := range bills {
+		for _, dueDate := range billOccurrences(bill, from, to) {
+			occurrence := &models.BillOccurrence{
+				BillID:    bill.ID,
+				Name:      bill.Name,
+				PayeeName: bill.PayeeName,
+				Amount:    bill.ExpectedAmount,
+				DueDate:   dueDate,
+				RemindOn:  dueDate.AddDate(0, 0, -bill.ReminderDays),
+				Autopay:   bill.Autopay,
+			}
+
+			payment, isPaid := paid[occurrenceKey(bill.ID, dueDate)]
+			switch {
+			case isPaid:
+				occurrence.Status = "paid"
+				occurrence.Amount = payment.Amount
+				occurrence.TransactionID = &payment.TransactionID
+			case dueDate.Before(now):
+				if bill.Autopay {
+					continue
+				}
+				occurrence.Status = "overdue"
+			case !occurrence.RemindOn.After(now):
+				occurrence.Status = "due_soon"
+			default:
+				occurrence.Status = "upcoming"
+			}
+
+			calendar = append(calendar, occurrence)
+		}
+	}
+
+	sort.SliceStable(calendar, func(i, j int) bool {
+		return calendar[i].DueDate.Before(calendar[j].DueDate)
+	})
+
+	return calendar, nil
+}
+
+// PayBill marks an occurrence of the bill as paid by linking it to an
+// existing transaction. Without a due date the earliest unpaid occurrence
+// is used.
+func (s *BillService) PayBill(ctx context.Context, scope Scope, billID string, req *models.PayBillRequest) (*models.BillPayment, error) {
+	userID := scope.UserID
+
+	bill, err := s.GetBill(ctx, scope, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	var amount float64
+	err = s.db.QueryRowContext(ctx,
+		`SELECT amount FROM transactions WHERE id = $1 AND account_id IN `+scope.accounts("$2"),
+		req.TransactionID, scope.arg()).Scan(&amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("transaction not found")
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	var dueDate time.Time
+	if req.DueDate != "" {
+		dueDate, err = time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		if occurrences := billOccurrences(bill, dueDate, dueDate); len(occurrences) == 0 {
+			return nil, fmt.Errorf("due date does not match the bill schedule")
+		}
+	} else {
+		if bill.NextDueDate == nil {
+			return nil, fmt.Errorf("bill has no unpaid occurrences")
+		}
+		dueDate = *bill.NextDueDate
+	}
+
+	payment := &models.BillPayment{
+		ID:            uuid.New().String(),
+		BillID:        billID,
+		DueDate:       dueDate,
+		TransactionID: req.TransactionID,
+		Amount:        amount,
+		CreatedAt:     time.Now(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO bill_payments (id, bill_id, user_id, due_date, transaction_id, created_at)
+         VALUES ($1, $2, $3, $4, $5, $6)`,
+		payment.ID, payment.BillID, userID, payment.DueDate, payment.TransactionID, payment.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			if pqErr.Constraint == "bill_payments_transaction_id_key" {
+				return nil, fmt.Errorf("transaction is already linked to a bill")
+			}
+			return nil, fmt.Errorf("bill is already paid for this date")
+		}
+		return nil, fmt.Errorf("failed to create bill payment: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "paid",
+		"data": map[string]interface{}{
+			"payment_id":     payment.ID,
+			"due_date":       payment.DueDate.Format("2006-01-02"),
+			"transaction_id": payment.TransactionID,
+			"amount":         payment.Amount,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "bill",
+		EntityID: billID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return payment, nil
+}
+
+func (s *BillService) DeletePayment(ctx context.Context, scope Scope, billID, paymentID string) error {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM bill_payments WHERE id = $1 AND bill_id = $2
+		AND bill_id IN (SELECT id FROM bills WHERE `+scope.owns("", "$3")+`)`,
+		paymentID, billID, scope.arg())
+	if err != nil {
+		return fmt.Errorf("failed to delete bill payment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("payment not found")
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "payment_deleted",
+		"data": map[string]interface{}{
+			"payment_id": paymentID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "bill",
+		EntityID: billID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCalendarToken returns the user's secret iCalendar feed token, creating
+// one on first use.
+func (s *BillService) GetCalendarToken(ctx context.Context, userID string) (string, error) {
+	var token string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT token FROM calendar_feeds WHERE user_id = $1`, userID).Scan(&token)
+	if err == nil {
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get calendar token: %w", err)
+	}
+
+	return s.RotateCalendarToken(ctx, userID)
+}
+
+// RotateCalendarToken replaces the feed token; subscriptions using the old
+// URL stop working.
+func (s *BillService) RotateCalendarToken(ctx context.Context, userID string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO calendar_feeds (user_id, token, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = EXCLUDED.created_at`,
+		userID, token, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to save calendar token: %w", err)
+	}
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:  userID,
+		Action:  "update",
+		Entity:  "calendar_feed",
+		Details: `{"action":"token_rotated"}`,
+	}); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetCalendarFeed renders unpaid upcoming bills in the personal space of the
+// token's owner as an iCalendar document.
+func (s *BillService) GetCalendarFeed(ctx context.Context, token string) (string, error) {
+	var userID string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT user_id FROM calendar_feeds WHERE token = $1`, token).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("calendar not found")
+		}
+		return "", fmt.Errorf("failed to get calendar: %w", err)
+	}
+
+	occurrences, err := s.GetUpcoming(ctx, Scope{UserID: userID}, 365)
+	if err != nil {
+		return "", err
+	}
+
+	return renderICalendar(occurrences, time.Now().UTC()), nil
+}
+
+func (s *BillService) verifyPayee(ctx context.Context, scope Scope, payeeID string) error {
+	if _, err := uuid.Parse(payeeID); err != nil {
+		return fmt.Errorf("payee not found")
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM payees WHERE id = $1 AND `+scope.owns("", "$2")+`)`,
+		payeeID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify payee: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("payee not found")
+	}
+	return nil
+}
+
+func (s *BillService) verifyCategory(ctx context.Context, scope Scope, categoryID string) error {
+	if _, err := uuid.Parse(categoryID); err != nil {
+		return fmt.Errorf("category not found")
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1 AND (`+scope.owns("", "$2")+` OR is_system = true))`,
+		categoryID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify category: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("category not found")
+	}
+	return nil
+}
+
+// getPaidOccurrences returns payments of the scope's bills due on or after
+// from, keyed by occurrenceKey.
+func (s *BillService) getPaidOccurrences(ctx context.Context, scope Scope, from time.Time) (map[string]*models.BillPayment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT bp.id, bp.bill_id, bp.due_date, bp.transaction_id, t.amount, bp.created_at
+		FROM bill_payments bp
+		JOIN bills b ON bp.bill_id = b.id
+		JOIN transactions t ON bp.transaction_id = t.id
+		WHERE `+scope.owns("b", "$1")+` AND bp.due_date >= $2`,
+		scope.arg(), from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bill payments: %w", err)
+	}
+	defer rows.Close()
+
+	paid := make(map[string]*models.BillPayment)
+	for rows.Next() {
+		var p models.BillPayment
+		if err := rows.Scan(&p.ID, &p.BillID, &p.DueDate, &p.TransactionID, &p.Amount, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bill payment: %w", err)
+		}
+		paid[occurrenceKey(p.BillID, p.DueDate)] = &p
+	}
+
+	return paid, nil
+}
+
+const billSelect = `
+        SELECT
+            b.id, b.user_id, b.workspace_id, b.name, b.payee_id, b.category_id, b.expected_amount, b.frequency,
+            b.interval_count, b.first_due_date, b.end_date, b.reminder_days, b.autopay, b.is_active,
+            b.created_at, b.updated_at, COALESCE(p.name, '') as payee_name
+        FROM bills b
+        LEFT JOIN payees p ON b.payee_id = p.id`
+
+func scanBill(row rowScanner) (*models.Bill, error) {
+	var b models.Bill
+	var payeeID, categoryID sql.NullString
+	var endDate sql.NullTime
+	err := row.Scan(&b.ID, &b.UserID, &b.WorkspaceID, &b.Name, &payeeID, &categoryID, &b.ExpectedAmount, &b.Frequency,
+		&b.Interval, &b.FirstDueDate, &endDate, &b.ReminderDays, &b.Autopay, &b.IsActive,
+		&b.CreatedAt, &b.UpdatedAt, &b.PayeeName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan bill: %w", err)
+	}
+
+	if payeeID.Valid {
+		b.PayeeID = &payeeID.String
+	}
+	if categoryID.Valid {
+		b.CategoryID = &categoryID.String
+	}
+	if endDate.Valid {
+		b.EndDate = &endDate.Time
+	}
+
+	return &b, nil
+}
+
+func occurrenceKey(billID string, dueDate time.Time) string {
+	return billID + "|" + dueDate.Format("2006-01-02")
+}
+
+// billOccurrence returns the n-th due date of a bill. Every date is derived
+// from the first due date so month-end clamping doesn't drift.
+func billOccurrence(bill *models.Bill, n int) time.Time {
+	step := n * bill.Interval
+	switch bill.Frequency {
+	case "weekly":
+		return bill.FirstDueDate.AddDate(0, 0, 7*step)
+	case "quarterly":
+		return addMonths(bill.FirstDueDate, 3*step)
+	case "yearly":
+		return addMonths(bill.FirstDueDate, 12*step)
+	default:
+		return addMonths(bill.FirstDueDate, step)
+	}
+}
+
+// billOccurrences lists due dates of the bill between from and to inclusive.
+func billOccurrences(bill *models.Bill, from, to time.Time) []time.Time {
+	dates := []time.Time{}
+	for n := 0; n < maxBillOccurrences; n++ {
+		date := billOccurrence(bill, n)
+		if date.After(to) || (bill.EndDate != nil && date.After(*bill.EndDate)) {
+			break
+		}
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+		if bill.Frequency == "once" {
+			break
+		}
+	}
+	return dates
+}
+
+// nextUnpaidOccurrence finds the earliest unpaid due date inside the overdue
+// window or later. It returns nil for inactive or finished bills.
+func nextUnpaidOccurrence(bill *models.Bill, paid map[string]*models.BillPayment) *time.Time {
+	if !bill.IsActive {
+		return nil
+	}
+
+	from := today().AddDate(0, 0, -billOverdueWindowDays)
+	to := today().AddDate(1, 0, 0)
+	if bill.FirstDueDate.After(to) {
+		to = bill.FirstDueDate
+	}
+
+	for _, date := range billOccurrences(bill, from, to) {
+		if _, ok := paid[occurrenceKey(bill.ID, date)]; !ok {
+			return &date
+		}
+	}
+	return nil
+}
+
+// renderICalendar builds an RFC 5545 calendar with an all-day event per
+// unpaid occurrence and an alarm at the bill's reminder lead time.
+func renderICalendar(occurrences []*models.BillOccurrence, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//FinTrack//Bills//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icalEscape("FinTrack bills"),
+	}
+
+	for _, o := range occurrences {
+		if o.Status == "paid" {
+			continue
+		}
+
+		summary := fmt.Sprintf("%s: %.2f", o.Name, o.Amount)
+		description := "Expected amount: " + fmt.Sprintf("%.2f", o.Amount)
+		if o.PayeeName != "" {
+			description += "\nPayee: " + o.PayeeName
+		}
+		if o.Autopay {
+			description += "\nPaid automatically"
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+o.BillID+"-"+o.DueDate.Format("20060102")+"@fintrack",
+			"DTSTAMP:"+now.Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+o.DueDate.Format("20060102"),
+			"DTEND;VALUE=DATE:"+o.DueDate.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icalEscape(summary),
+			"DESCRIPTION:"+icalEscape(description),
+			"TRANSP:TRANSPARENT",
+		)
+
+		if days := int(o.DueDate.Sub(o.RemindOn).Hours() / 24); days > 0 && !o.Autopay {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"DESCRIPTION:"+icalEscape(summary),
+				fmt.Sprintf("TRIGGER:-P%dD", days),
+				"END:VALARM",
+			)
+		}
+
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icalFold(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+func icalEscape(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// icalFold splits content lines longer than 75 octets, never inside a
+// multi-byte character.
+func icalFold(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BudgetService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewBudgetService(db *sql.DB, logService *LogService) *BudgetService {
+	return &BudgetService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+func (s *BudgetService) CreateBudget(ctx context.Context, scope Scope, req *models.CreateBudgetRequest) (*models.Budget, error) {
+	userID := scope.UserID
+
+	period := req.Period
+	if period == "" {
+		period = "monthly"
+	}
+
+	startDate := periodStart(period, today())
+	if req.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		startDate = periodStart(period, parsed)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireWorkspaceRole(ctx, tx, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCategories(ctx, tx, scope, req.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	budget := &models.Budget{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: scope.workspace(),
+		Name:        req.Name,
+		Amount:      req.Amount,
+		Period:      period,
+		Rollover:    req.Rollover,
+		StartDate:   startDate,
+		CategoryIDs: uniqueStrings(req.CategoryIDs),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO budgets (id, user_id, workspace_id, name, amount, period, rollover, start_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		budget.ID, budget.UserID, budget.WorkspaceID, budget.Name, budget.Amount, budget.Period,
+		budget.Rollover, budget.StartDate, budget.CreatedAt, budget.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create budget: %w", err)
+	}
+
+	if err := s.setCategories(ctx, tx, budget.ID, budget.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":           budget.ID,
+			"name":         budget.Name,
+			"amount":       budget.Amount,
+			"period":       budget.Period,
+			"rollover":     budget.Rollover,
+			"start_date":   budget.StartDate.Format("2006-01-02"),
+			"category_ids": budget.CategoryIDs,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "budget",
+		EntityID: budget.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return budget, nil
+}
+
+func (s *BudgetService) GetBudgets(ctx context.Context, scope Scope) ([]*models.Budget, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT b.id, b.user_id, b.workspace_id, b.name, b.amount, b.period, b.rollover, b.start_date,
+			b.created_at, b.updated_at,
+			COALESCE(ARRAY_AGG(bc.category_id::text) FILTER (WHERE bc.category_id IS NOT NULL), '{}')
+		FROM budgets b
+		LEFT JOIN budget_categories bc ON bc.budget_id = b.id
+		WHERE `+scope.owns("b", "$1")+`
+		GROUP BY b.id
+		ORDER BY b.created_at ASC`,
+		scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []*models.Budget{}
+	for rows.Next() {
+		var b models.Budget
+		var categoryIDs pq.StringArray
+		err := rows.Scan(&b.ID, &b.UserID, &b.WorkspaceID, &b.Name, &b.Amount, &b.Period, &b.Rollover,
+			&b.StartDate, &b.CreatedAt, &b.UpdatedAt, &categoryIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		b.CategoryIDs = categoryIDs
+		budgets = append(budgets, &b)
+	}
+
+	return budgets, nil
+}
+
+func (s *BudgetService) GetBudget(ctx context.Context, scope Scope, budgetID string) (*models.Budget, error) {
+	var b models.Budget
+	var categoryIDs pq.StringArray
+	err := s.db.QueryRowContext(ctx,
+		`SELECT b.id, b.user_id, b.workspace_id, b.name, b.amount, b.period, b.rollover, b.start_date,
+			b.created_at, b.updated_at,
+			COALESCE(ARRAY_AGG(bc.category_id::text) FILTER (WHERE bc.category_id IS NOT NULL), '{}')
+		FROM budgets b
+		LEFT JOIN budget_categories bc ON bc.budget_id = b.id
+		WHERE b.id = $1 AND `+scope.owns("b", "$2")+`
+		GROUP BY b.id`,
+		budgetID, scope.arg()).Scan(&b.ID, &b.UserID, &b.WorkspaceID, &b.Name, &b.Amount, &b.Period, &b.Rollover,
+		&b.StartDate, &b.CreatedAt, &b.UpdatedAt, &categoryIDs)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("budget not found")
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	b.CategoryIDs = categoryIDs
+
+	return &b, nil
+}
+
+func (s *BudgetService) UpdateBudget(ctx context.Context, scope Scope, budgetID string, req *models.UpdateBudgetRequest) (*models.Budget, error) {
+	userID := scope.UserID
+
+	oldBudget, err := s.GetBudget(ctx, scope, budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updateFields := make(map[string]interface{})
+	changes := make(map[string]map[string]interface{})
+
+	if req.Name != "" && req.Name != oldBudget.Name {
+		updateFields["name"] = req.Name
+		changes["name"] = map[string]interface{}{
+			"old": oldBudget.Name,
+			"new": req.Name,
+		}
+	}
+
+	if req.Amount > 0 && req.Amount != oldBudget.Amount {
+		updateFields["amount"] = req.Amount
+		changes["amount"] = map[string]interface{}{
+			"old": oldBudget.Amount,
+			"new": req.Amount,
+		}
+	}
+
+	if req.Rollover != nil && *req.Rollover != oldBudget.Rollover {
+		updateFields["rollover"] = *req.Rollover
+		changes["rollover"] = map[string]interface{}{
+			"old": oldBudget.Rollover,
+			"new": *req.Rollover,
+		}
+	}
+
+	if len(req.CategoryIDs) > 0 {
+		newIDs := uniqueStrings(req.CategoryIDs)
+		if !sameStrings(newIDs, oldBudget.CategoryIDs) {
+			if err := s.verifyCategories(ctx, tx, scope, newIDs); err != nil {
+				return nil, err
+			}
+			if err := s.setCategories(ctx, tx, budgetID, newIDs); err != nil {
+				return nil, err
+			}
+			changes["category_ids"] = map[string]interface{}{
+				"old": oldBudget.CategoryIDs,
+				"new": newIDs,
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return oldBudget, nil
+	}
+
+	updateFields["updated_at"] = time.Now()
+
+	query := `UPDATE budgets SET `
+	args := []interface{}{}
+	i := 1
+
+	for field, value := range updateFields {
+		if i > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, i)
+		args = append(args, value)
+		i++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d", i)
+	args = append(args, budgetID)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "budget",
+		EntityID: budgetID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetBudget(ctx, scope, budgetID)
+}
+
+func (s *BudgetService) DeleteBudget(ctx context.Context, scope Scope, budgetID string) error {
+	userID := scope.UserID
+
+	budget, err := s.GetBudget(ctx, scope, budgetID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM budgets WHERE id = $1`,
+		budgetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("budget not found")
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name":         budget.Name,
+			"amount":       budget.Amount,
+			"period":       budget.Period,
+			"category_ids": budget.CategoryIDs,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "budget",
+		EntityID: budgetID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCurrentProgress computes spending against every budget for the period
+// containing today.
+func (s *BudgetService) GetCurrentProgress(ctx context.Context, scope Scope) ([]*models.BudgetProgress, error) {
+	budgets, err := s.GetBudgets(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	now := today()
+	progress := []*models.BudgetProgress{}
+
+	for _, budget := range budgets {
+		p, err := s.getProgress(ctx, scope, budget, now)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+
+	return progress, nil
+}
+
+func (s *BudgetService) GetBudgetProgress(ctx context.Context, scope Scope, budgetID string) (*models.BudgetProgress, error) {
+	budget, err := s.GetBudget(ctx, scope, budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getProgress(ctx, scope, budget, today())
+}
+
+func (s *BudgetService) getProgress(ctx context.Context, scope Scope, budget *models.Budget, day time.Time) (*models.BudgetProgress, error) {
+	start := periodStart(budget.Period, day)
+	end := nextPeriodStart(budget.Period, start).AddDate(0, 0, -1)
+
+	var spent float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		JOIN budget_categories bc ON bc.category_id = t.category_id AND bc.budget_id = $2
+		WHERE t.account_id IN `+scope.accounts("$1")+` AND t.type = 'expense' AND t.date >= $3 AND t.date <= $4`,
+		scope.arg(), budget.ID, start, end).Scan(&spent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget spending: %w", err)
+	}
+
+	var carryover float64
+	if budget.Rollover {
+		carryover, err = s.getCarryover(ctx, scope, budget, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &models.BudgetProgress{
+		Budget:      budget,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Carryover:   roundMoney(carryover),
+		Available:   roundMoney(budget.Amount + carryover),
+		Spent:       roundMoney(spent),
+		DaysLeft:    int(end.Sub(day).Hours()/24) + 1,
+	}
+
+	p.Remaining = roundMoney(p.Available - p.Spent)
+	p.Overspent = p.Remaining < 0
+	if p.Available > 0 {
+		p.PercentUsed = math.Round(p.Spent/p.Available*10000) / 100
+	}
+	if p.DaysLeft > 0 && p.Remaining > 0 {
+		p.DailyAllowance = roundMoney(p.Remaining / float64(p.DaysLeft))
+	}
+
+	return p, nil
+}
+
+// getCarryover sums unspent (or overspent) money of all completed periods
+// between the budget start and the current period.
+func (s *BudgetService) getCarryover(ctx context.Context, scope Scope, budget *models.Budget, currentStart time.Time) (float64, error) {
+	first := periodStart(budget.Period, budget.StartDate)
+	if !first.Before(currentStart) {
+		return 0, nil
+	}
+
+	var periods int
+	for d := first; d.Before(currentStart); d = nextPeriodStart(budget.Period, d) {
+		periods++
+	}
+
+	var spent float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		JOIN budget_categories bc ON bc.category_id = t.category_id AND bc.budget_id = $2
+		WHERE t.account_id IN `+scope.accounts("$1")+` AND t.type = 'expense' AND t.date >= $3 AND t.date < $4`,
+		scope.arg(), budget.ID, first, currentStart).Scan(&spent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get budget carryover: %w", err)
+	}
+
+	return budget.Amount*float64(periods) - spent, nil
+}
+
+func (s *BudgetService) verifyCategories(ctx context.Context, tx *sql.Tx, scope Scope, categoryIDs []string) error {
+	var count int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM categories
+		WHERE id = ANY($1) AND (`+scope.owns("", "$2")+` OR is_system = true) AND type = 'expense'`,
+		pq.Array(uniqueStrings(categoryIDs)), scope.arg()).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to verify categories: %w", err)
+	}
+
+	if count != len(uniqueStrings(categoryIDs)) {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+func (s *BudgetService) setCategories(ctx context.Context, tx *sql.Tx, budgetID string, categoryIDs []string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM budget_categories WHERE budget_id = $1`, budgetID); err != nil {
+		return fmt.Errorf("failed to clear budget categories: %w", err)
+	}
+
+	for _, categoryID := range categoryIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO budget_categories (budget_id, category_id) VALUES ($1, $2)`,
+			budgetID, categoryID); err != nil {
+			return fmt.Errorf("failed to add budget category: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart returns the first day of the budget period containing day.
+// Weeks start on Monday, matching DATE_TRUNC('week', ...) in PostgreSQL.
+func periodStart(period string, day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case "weekly":
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case "yearly":
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextPeriodStart(period string, start time.Time) time.Time {
+	switch period {
+	case "weekly":
+		return start.AddDate(0, 0, 7)
+	case "yearly":
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		if !set[v] {
+			return false
+		}
+	}
+	return true
+}
@@ -15,21 +15,29 @@ import (
 type CategoryService struct {
 	db         *sql.DB
 	logService *LogService // ← ДОБАВЛЕНО
+	statsCache *StatsCache
 }
 
-func NewCategoryService(db *sql.DB, logService *LogService) *CategoryService {
+func NewCategoryService(db *sql.DB, logService *LogService, statsCache *StatsCache) *CategoryService {
 	return &CategoryService{
 		db:         db,
 		logService: logService, // ← ДОБАВЛЕНО
+		statsCache: statsCache,
 	}
 }
 
-func (s *CategoryService) CreateCategory(ctx context.Context, userID string, req *models.CreateCategoryRequest) (*models.Category, error) {
-	// Check if category with same name already exists for user
+func (s *CategoryService) CreateCategory(ctx context.Context, scope Scope, req *models.CreateCategoryRequest) (*models.Category, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	// Check if category with same name already exists in the space
 	var exists bool
 	err := s.db.QueryRowContext(ctx,
-		`SELECT EXISTS(SELECT 1 FROM categories WHERE name = $1 AND user_id = $2 AND type = $3)`,
-		req.Name, userID, req.Type).Scan(&exists)
+		`SELECT EXISTS(SELECT 1 FROM categories WHERE name = $1 AND `+scope.owns("", "$2")+` AND type = $3)`,
+		req.Name, scope.arg(), req.Type).Scan(&exists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check category existence: %w", err)
 	}
@@ -39,20 +47,27 @@ func (s *CategoryService) CreateCategory(ctx context.Context, userID string, req
 	}
 
 	category := &models.Category{
-		ID:        uuid.New().String(),
-		UserID:    &userID,
-		Name:      req.Name,
-		Type:      req.Type,
-		Icon:      req.Icon,
-		Color:     req.Color,
-		IsSystem:  false,
-		CreatedAt: time.Now(),
-	}
-
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO categories (id, user_id, name, type, icon, color, is_system, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		category.ID, category.UserID, category.Name, category.Type,
+		ID:          uuid.New().String(),
+		UserID:      &userID,
+		WorkspaceID: scope.workspace(),
+		Name:        req.Name,
+		Type:        req.Type,
+		Icon:        req.Icon,
+		Color:       req.Color,
+		IsSystem:    false,
+		CreatedAt:   time.Now(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO categories (id, user_id, workspace_id, name, type, icon, color, is_system, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		category.ID, category.UserID, category.WorkspaceID, category.Name, category.Type,
 		category.Icon, category.Color, category.IsSystem, category.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create category: %w", err)
@@ -71,24 +86,30 @@ func (s *CategoryService) CreateCategory(ctx context.Context, userID string, req
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "create",
 		Entity:   "category",
 		EntityID: category.ID,
 		Details:  string(detailsJSON),
-	})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
 	return category, nil
 }
 
-func (s *CategoryService) GetCategories(ctx context.Context, userID string) ([]*models.Category, error) {
+func (s *CategoryService) GetCategories(ctx context.Context, scope Scope) ([]*models.Category, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, name, type, icon, color, is_system, created_at
+		`SELECT id, user_id, workspace_id, name, type, icon, color, is_system, created_at
 		FROM categories
-		WHERE user_id = $1 OR is_system = true
+		WHERE `+scope.owns("", "$1")+` OR is_system = true
 		ORDER BY is_system DESC, type, name`,
-		userID)
+		scope.arg())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get categories: %w", err)
 	}
@@ -97,7 +118,7 @@ func (s *CategoryService) GetCategories(ctx context.Context, userID string) ([]*
 	var categories []*models.Category
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Type,
+		err := rows.Scan(&c.ID, &c.UserID, &c.WorkspaceID, &c.Name, &c.Type,
 			&c.Icon, &c.Color, &c.IsSystem, &c.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
@@ -109,13 +130,13 @@ func (s *CategoryService) GetCategories(ctx context.Context, userID string) ([]*
 	return categories, nil
 }
 
-func (s *CategoryService) GetCategoriesByType(ctx context.Context, userID, categoryType string) ([]*models.Category, error) {
+func (s *CategoryService) GetCategoriesByType(ctx context.Context, scope Scope, categoryType string) ([]*models.Category, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, name, type, icon, color, is_system, created_at
+		`SELECT id, user_id, workspace_id, name, type, icon, color, is_system, created_at
 		FROM categories
-		WHERE (user_id = $1 OR is_system = true) AND type = $2
+		WHERE (`+scope.owns("", "$1")+` OR is_system = true) AND type = $2
 		ORDER BY is_system DESC, name`,
-		userID, categoryType)
+		scope.arg(), categoryType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get categories: %w", err)
 	}
@@ -124,7 +145,7 @@ func (s *CategoryService) GetCategoriesByType(ctx context.Context, userID, categ
 	var categories []*models.Category
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Type,
+		err := rows.Scan(&c.ID, &c.UserID, &c.WorkspaceID, &c.Name, &c.Type,
 			&c.Icon, &c.Color, &c.IsSystem, &c.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
@@ -136,17 +157,17 @@ func (s *CategoryService) GetCategoriesByType(ctx context.Context, userID, categ
 	return categories, nil
 }
 
-func (s *CategoryService) GetCategory(ctx context.Context, userID, categoryID string) (*models.Category, error) {
+func (s *CategoryService) GetCategory(ctx context.Context, scope Scope, categoryID string) (*models.Category, error) {
 	var c models.Category
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, name, type, icon, color, is_system, created_at
+		`SELECT id, user_id, workspace_id, name, type, icon, color, is_system, created_at
 		FROM categories
-		WHERE id = $1 AND (user_id = $2 OR is_system = true)`,
-		categoryID, userID).Scan(&c.ID, &c.UserID, &c.Name, &c.Type,
+		WHERE id = $1 AND (`+scope.owns("", "$2")+` OR is_system = true)`,
+		categoryID, scope.arg()).Scan(&c.ID, &c.UserID, &c.WorkspaceID, &c.Name, &c.Type,
 		&c.Icon, &c.Color, &c.IsSystem, &c.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("category not found")
+			return nil, fmt.Errorf("category %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get category: %w", err)
 	}
@@ -154,16 +175,17 @@ func (s *CategoryService) GetCategory(ctx context.Context, userID, categoryID st
 	return &c, nil
 }
 
-func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID string, req *models.UpdateCategoryRequest) (*models.Category, error) {
-	// Check if category exists and belongs to user (not system)
-	var isSystem bool
-	var ownerID *string
+func (s *CategoryService) UpdateCategory(ctx context.Context, scope Scope, categoryID string, req *models.UpdateCategoryRequest) (*models.Category, error) {
+	userID := scope.UserID
+
+	// Check if category exists and belongs to the space (not system)
+	var isSystem, inScope bool
 	err := s.db.QueryRowContext(ctx,
-		`SELECT is_system, user_id FROM categories WHERE id = $1`,
-		categoryID).Scan(&isSystem, &ownerID)
+		`SELECT is_system, `+scope.owns("", "$2")+` FROM categories WHERE id = $1`,
+		categoryID, scope.arg()).Scan(&isSystem, &inScope)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("category not found")
+			return nil, fmt.Errorf("category %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to check category: %w", err)
 	}
@@ -172,12 +194,16 @@ func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID
 		return nil, fmt.Errorf("cannot modify system category")
 	}
 
-	if ownerID == nil || *ownerID != userID {
-		return nil, fmt.Errorf("category not found")
+	if !inScope {
+		return nil, fmt.Errorf("category %w", ErrNotFound)
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
 	}
 
 	// ✅ Получаем старую категорию для сравнения
-	oldCategory, err := s.GetCategory(ctx, userID, categoryID)
+	oldCategory, err := s.GetCategory(ctx, scope, categoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -228,10 +254,16 @@ func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID
 		i++
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d AND user_id = $%d", i, i+1)
-	args = append(args, categoryID, userID)
+	query += fmt.Sprintf(" WHERE id = $%d", i)
+	args = append(args, categoryID)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err = s.db.ExecContext(ctx, query, args...)
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update category: %w", err)
 	}
@@ -244,28 +276,44 @@ func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID
 		}
 		detailsJSON, _ := json.Marshal(logDetails)
 
-		go s.logService.Log(context.Background(), &UserAction{
+		if err := s.logService.Record(ctx, tx, &UserAction{
 			UserID:   userID,
 			Action:   "update",
 			Entity:   "category",
 			EntityID: categoryID,
 			Details:  string(detailsJSON),
-		})
+		}); err != nil {
+			return nil, err
+		}
+
+		// The category breakdown shows names, icons and colors; a category
+		// can only be deleted unused, so updates are the only change it sees
+		scopes, err := categoryStatsScopes(ctx, tx, categoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		s.statsCache.Invalidate(ctx, scopes...)
 	}
 
-	return s.GetCategory(ctx, userID, categoryID)
+	return s.GetCategory(ctx, scope, categoryID)
 }
 
-func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID string) error {
-	// Check if category exists and belongs to user (not system)
-	var isSystem bool
-	var ownerID *string
+func (s *CategoryService) DeleteCategory(ctx context.Context, scope Scope, categoryID string) error {
+	userID := scope.UserID
+
+	// Check if category exists and belongs to the space (not system)
+	var isSystem, inScope bool
 	err := s.db.QueryRowContext(ctx,
-		`SELECT is_system, user_id FROM categories WHERE id = $1`,
-		categoryID).Scan(&isSystem, &ownerID)
+		`SELECT is_system, `+scope.owns("", "$2")+` FROM categories WHERE id = $1`,
+		categoryID, scope.arg()).Scan(&isSystem, &inScope)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("category not found")
+			return fmt.Errorf("category %w", ErrNotFound)
 		}
 		return fmt.Errorf("failed to check category: %w", err)
 	}
@@ -274,8 +322,12 @@ func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID
 		return fmt.Errorf("cannot delete system category")
 	}
 
-	if ownerID == nil || *ownerID != userID {
-		return fmt.Errorf("category not found")
+	if !inScope {
+		return fmt.Errorf("category %w", ErrNotFound)
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
 	}
 
 	// Check if category has transactions
@@ -294,19 +346,25 @@ func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID
 	// ✅ Сохраняем данные категории ДО удаления
 	var categoryName, categoryType, icon, color string
 	err = s.db.QueryRowContext(ctx,
-		`SELECT name, type, icon, color FROM categories WHERE id = $1 AND user_id = $2`,
-		categoryID, userID).Scan(&categoryName, &categoryType, &icon, &color)
+		`SELECT name, type, icon, color FROM categories WHERE id = $1`,
+		categoryID).Scan(&categoryName, &categoryType, &icon, &color)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("category not found")
+			return fmt.Errorf("category %w", ErrNotFound)
 		}
 		return fmt.Errorf("failed to get category info: %w", err)
 	}
 
 	// Delete category
-	result, err := s.db.ExecContext(ctx,
-		`DELETE FROM categories WHERE id = $1 AND user_id = $2`,
-		categoryID, userID)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM categories WHERE id = $1`,
+		categoryID)
 	if err != nil {
 		return fmt.Errorf("failed to delete category: %w", err)
 	}
@@ -317,7 +375,7 @@ func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("category not found")
+		return fmt.Errorf("category %w", ErrNotFound)
 	}
 
 	// ✅ Логирование с деталями удалённой категории
@@ -332,18 +390,24 @@ func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "delete",
 		Entity:   "category",
 		EntityID: categoryID,
 		Details:  string(detailsJSON),
-	})
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
 	return nil
 }
 
-func (s *CategoryService) GetCategoryStats(ctx context.Context, userID string, startDate, endDate time.Time) ([]*models.CategoryStats, error) {
+func (s *CategoryService) GetCategoryStats(ctx context.Context, scope Scope, startDate, endDate time.Time) ([]*models.CategoryStats, error) {
 	query := `
 		SELECT
 			c.id as category_id,
@@ -352,16 +416,15 @@ func (s *CategoryService) GetCategoryStats(ctx context.Context, userID string, s
 			COALESCE(SUM(t.amount), 0) as total,
 			COUNT(t.id) as count
 		FROM categories c
-		LEFT JOIN transactions t ON c.id = t.category_id
-			AND t.user_id = $1
+		JOIN transactions t ON c.id = t.category_id
+			AND t.account_id IN ` + scope.accounts("$1") + `
 			AND t.date >= $2
 			AND t.date <= $3
-		WHERE c.user_id = $1 OR c.is_system = true
 		GROUP BY c.id, c.name, c.type
 		HAVING COUNT(t.id) > 0
 		ORDER BY total DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, startDate, endDate)
+	rows, err := s.db.QueryContext(ctx, query, scope.arg(), startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category stats: %w", err)
 	}
@@ -0,0 +1,894 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Debts move money between accounts and people without being income or
+// expense, so they never create rows in transactions and stay out of the
+// income/expense statistics. Account balances are adjusted directly.
+
+const debtSelect = `
+        SELECT
+            d.id, d.user_id, d.counterparty_id, d.account_id, d.direction, d.amount,
+            COALESCE(d.description, ''), d.date, d.due_date, d.created_at, d.updated_at,
+            cp.name as counterparty_name, a.name as account_name,
+            COALESCE((SELECT SUM(r.amount) FROM debt_repayments r WHERE r.debt_id = d.id), 0) as repaid
+        FROM debts d
+        JOIN counterparties cp ON d.counterparty_id = cp.id
+        JOIN accounts a ON d.account_id = a.id`
+
+type DebtService struct {
+	db         *sql.DB
+	logService *LogService
+	statsCache *StatsCache
+}
+
+func NewDebtService(db *sql.DB, logService *LogService, statsCache *StatsCache) *DebtService {
+	return &DebtService{
+		db:         db,
+		logService: logService,
+		statsCache: statsCache,
+	}
+}
+
+// debtSign is the direction in which a debt moves its account balance:
+// lending takes money out, borrowing brings it in. Repayments move it back.
+func debtSign(direction string) float64 {
+	if direction == "lent" {
+		return -1
+	}
+	return 1
+}
+
+func (s *DebtService) CreateCounterparty(ctx context.Context, userID string, req *models.CreateCounterpartyRequest) (*models.Counterparty, error) {
+	counterparty := &models.Counterparty{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		Note:      req.Note,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO counterparties (id, user_id, name, note, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6)`,
+		counterparty.ID, counterparty.UserID, counterparty.Name, counterparty.Note,
+		counterparty.CreatedAt, counterparty.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create counterparty: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":   counterparty.ID,
+			"name": counterparty.Name,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "counterparty",
+		EntityID: counterparty.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return counterparty, nil
+}
+
+func (s *DebtService) GetCounterparties(ctx context.Context, userID string) ([]*models.Counterparty, error) {
+	rows, err := s.db.QueryContext(ctx, counterpartySelect+`
+        WHERE cp.user_id = $1
+        GROUP BY cp.id
+        ORDER BY cp.name`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counterparties: %w", err)
+	}
+	defer rows.Close()
+
+	counterparties := []*models.Counterparty{}
+	for rows.Next() {
+		counterparty, err := scanCounterparty(rows)
+		if err != nil {
+			return nil, err
+		}
+		counterparties = append(counterparties, counterparty)
+	}
+
+	return counterparties, nil
+}
+
+func (s *DebtService) GetCounterparty(ctx context.Context, userID, counterpartyID string) (*models.Counterparty, error) {
+	row := s.db.QueryRowContext(ctx, counterpartySelect+`
+        WHERE cp.id = $1 AND cp.user_id = $2
+        GROUP BY cp.id`,
+		counterpartyID, userID)
+
+	counterparty, err := scanCounterparty(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("counterparty not found")
+	}
+	return counterparty, err
+}
+
+func (s *DebtService) UpdateCounterparty(ctx context.Context, userID, counterpartyID string, req *models.UpdateCounterpartyRequest) (*models.Counterparty, error) {
+	oldCounterparty, err := s.GetCounterparty(ctx, userID, counterpartyID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]map[string]interface{})
+	name := oldCounterparty.Name
+	note := oldCounterparty.Note
+
+	if req.Name != "" && req.Name != name {
+		changes["name"] = map[string]interface{}{"old": name, "new": req.Name}
+		name = req.Name
+	}
+	if req.Note != nil && *req.Note != note {
+		changes["note"] = map[string]interface{}{"old": note, "new": *req.Note}
+		note = *req.Note
+	}
+
+	if len(changes) == 0 {
+		return oldCounterparty, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE counterparties SET name = $1, note = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		name, note, time.Now(), counterpartyID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update counterparty: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "counterparty",
+		EntityID: counterpartyID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetCounterparty(ctx, userID, counterpartyID)
+}
+
+func (s *DebtService) DeleteCounterparty(ctx context.Context, userID, counterpartyID string) error {
+	counterparty, err := s.GetCounterparty(ctx, userID, counterpartyID)
+	if err != nil {
+		return err
+	}
+
+	var debtCount int
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM debts WHERE counterparty_id = $1`,
+		counterpartyID).Scan(&debtCount)
+	if err != nil {
+		return fmt.Errorf("failed to check debts: %w", err)
+	}
+
+	if debtCount > 0 {
+		return fmt.Errorf("cannot delete counterparty with existing debts")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM counterparties WHERE id = $1 AND user_id = $2`,
+		counterpartyID, userID); err != nil {
+		return fmt.Errorf("failed to delete counterparty: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name": counterparty.Name,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "counterparty",
+		EntityID: counterpartyID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateDebt records a debt of the user. The money moves through an account
+// of the active space.
+func (s *DebtService) CreateDebt(ctx context.Context, scope Scope, req *models.CreateDebtRequest) (*models.Debt, error) {
+	userID := scope.UserID
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+	}
+
+	var dueDate *time.Time
+	if req.DueDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		if parsed.Before(date) {
+			return nil, fmt.Errorf("due date is before the debt date")
+		}
+		dueDate = &parsed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var counterpartyExists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM counterparties WHERE id = $1 AND user_id = $2)`,
+		req.CounterpartyID, userID).Scan(&counterpartyExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify counterparty: %w", err)
+	}
+	if !counterpartyExists {
+		return nil, fmt.Errorf("counterparty not found")
+	}
+
+	if err := verifyAccountTx(ctx, tx, scope, req.AccountID); err != nil {
+		return nil, err
+	}
+
+	debt := &models.Debt{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		CounterpartyID: req.CounterpartyID,
+		AccountID:      req.AccountID,
+		Direction:      req.Direction,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		Date:           date,
+		DueDate:        dueDate,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO debts (id, user_id, counterparty_id, account_id, direction, amount, description, date, due_date, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		debt.ID, debt.UserID, debt.CounterpartyID, debt.AccountID, debt.Direction,
+		debt.Amount, debt.Description, debt.Date, debt.DueDate, debt.CreatedAt, debt.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debt: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE accounts SET balance = balance + $1 WHERE id = $2`,
+		debtSign(debt.Direction)*debt.Amount, debt.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":              debt.ID,
+			"direction":       debt.Direction,
+			"amount":          debt.Amount,
+			"counterparty_id": debt.CounterpartyID,
+			"account_id":      debt.AccountID,
+			"date":            debt.Date.Format("2006-01-02"),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "debt",
+		EntityID: debt.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	scopes, err := statsScopes(ctx, tx, debt.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	// Debts count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return s.GetDebt(ctx, userID, debt.ID)
+}
+
+func (s *DebtService) GetDebts(ctx context.Context, filter *models.DebtFilter) ([]*models.Debt, error) {
+	query := debtSelect + ` WHERE d.user_id = $1`
+	args := []interface{}{filter.UserID}
+	argCount := 1
+
+	if filter.CounterpartyID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND d.counterparty_id = $%d", argCount)
+		args = append(args, filter.CounterpartyID)
+	}
+
+	if filter.Direction != "" {
+		argCount++
+		query += fmt.Sprintf(" AND d.direction = $%d", argCount)
+		args = append(args, filter.Direction)
+	}
+
+	query += " ORDER BY d.date DESC, d.created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+	defer rows.Close()
+
+	debts := []*models.Debt{}
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Status != "" && debt.Status != filter.Status {
+			continue
+		}
+		debts = append(debts, debt)
+	}
+
+	return debts, nil
+}
+
+func (s *DebtService) GetDebt(ctx context.Context, userID, debtID string) (*models.Debt, error) {
+	row := s.db.QueryRowContext(ctx, debtSelect+` WHERE d.id = $1 AND d.user_id = $2`, debtID, userID)
+	debt, err := scanDebt(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("debt not found")
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, debt_id, account_id, amount, date, COALESCE(note, ''), created_at
+		FROM debt_repayments WHERE debt_id = $1 ORDER BY date, created_at`,
+		debtID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repayments: %w", err)
+	}
+	defer rows.Close()
+
+	debt.Repayments = []*models.DebtRepayment{}
+	for rows.Next() {
+		var r models.DebtRepayment
+		if err := rows.Scan(&r.ID, &r.DebtID, &r.AccountID, &r.Amount, &r.Date, &r.Note, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan repayment: %w", err)
+		}
+		debt.Repayments = append(debt.Repayments, &r)
+	}
+
+	return debt, nil
+}
+
+// UpdateDebt changes only the description and due date. Amount, direction
+// and account are fixed once money has moved; delete and recreate instead.
+func (s *DebtService) UpdateDebt(ctx context.Context, userID, debtID string, req *models.UpdateDebtRequest) (*models.Debt, error) {
+	oldDebt, err := s.GetDebt(ctx, userID, debtID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]map[string]interface{})
+	description := oldDebt.Description
+	dueDate := oldDebt.DueDate
+
+	if req.Description != nil && *req.Description != description {
+		changes["description"] = map[string]interface{}{"old": description, "new": *req.Description}
+		description = *req.Description
+	}
+
+	if req.DueDate != nil {
+		var newDueDate *time.Time
+		if *req.DueDate != "" {
+			parsed, err := time.Parse("2006-01-02", *req.DueDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+			}
+			if parsed.Before(oldDebt.Date) {
+				return nil, fmt.Errorf("due date is before the debt date")
+			}
+			newDueDate = &parsed
+		}
+
+		if formatOptionalDate(newDueDate) != formatOptionalDate(dueDate) {
+			changes["due_date"] = map[string]interface{}{
+				"old": formatOptionalDate(dueDate),
+				"new": formatOptionalDate(newDueDate),
+			}
+			dueDate = newDueDate
+		}
+	}
+
+	if len(changes) == 0 {
+		return oldDebt, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE debts SET description = $1, due_date = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		description, dueDate, time.Now(), debtID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update debt: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "debt",
+		EntityID: debtID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetDebt(ctx, userID, debtID)
+}
+
+// DeleteDebt removes a debt with its repayments and reverts every balance
+// change they made.
+func (s *DebtService) DeleteDebt(ctx context.Context, userID, debtID string) error {
+	debt, err := s.GetDebt(ctx, userID, debtID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sign := debtSign(debt.Direction)
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE accounts SET balance = balance - $1 WHERE id = $2`,
+		sign*debt.Amount, debt.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to revert account balance: %w", err)
+	}
+
+	for _, r := range debt.Repayments {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE accounts SET balance = balance + $1 WHERE id = $2`,
+			sign*r.Amount, r.AccountID)
+		if err != nil {
+			return fmt.Errorf("failed to revert account balance: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM debts WHERE id = $1 AND user_id = $2`, debtID, userID); err != nil {
+		return fmt.Errorf("failed to delete debt: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"direction":       debt.Direction,
+			"amount":          debt.Amount,
+			"repaid_amount":   debt.RepaidAmount,
+			"counterparty_id": debt.CounterpartyID,
+			"account_id":      debt.AccountID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "debt",
+		EntityID: debtID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	accountIDs := []string{debt.AccountID}
+	for _, r := range debt.Repayments {
+		accountIDs = append(accountIDs, r.AccountID)
+	}
+	scopes, err := statsScopes(ctx, tx, accountIDs...)
+	if err != nil {
+		return err
+	}
+	// Debts count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return nil
+}
+
+// AddRepayment records a partial or full repayment and moves the money back:
+// into the account for money I lent, out of it for money I borrowed.
+func (s *DebtService) AddRepayment(ctx context.Context, scope Scope, debtID string, req *models.CreateDebtRepaymentRequest) (*models.DebtRepayment, error) {
+	userID := scope.UserID
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the debt so concurrent repayments can't overpay it
+	var direction, accountID string
+	var amount float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT direction, account_id, amount FROM debts WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		debtID, userID).Scan(&direction, &accountID, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("debt not found")
+		}
+		return nil, fmt.Errorf("failed to get debt: %w", err)
+	}
+
+	var repaid float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM debt_repayments WHERE debt_id = $1`,
+		debtID).Scan(&repaid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repaid amount: %w", err)
+	}
+
+	if roundMoney(repaid+req.Amount) > roundMoney(amount) {
+		return nil, fmt.Errorf("repayment exceeds outstanding amount")
+	}
+
+	if req.AccountID != "" && req.AccountID != accountID {
+		if err := verifyAccountTx(ctx, tx, scope, req.AccountID); err != nil {
+			return nil, err
+		}
+		accountID = req.AccountID
+	}
+
+	repayment := &models.DebtRepayment{
+		ID:        uuid.New().String(),
+		DebtID:    debtID,
+		AccountID: accountID,
+		Amount:    req.Amount,
+		Date:      date,
+		Note:      req.Note,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO debt_repayments (id, debt_id, user_id, account_id, amount, date, note, created_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		repayment.ID, repayment.DebtID, userID, repayment.AccountID, repayment.Amount,
+		repayment.Date, repayment.Note, repayment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repayment: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE accounts SET balance = balance - $1 WHERE id = $2`,
+		debtSign(direction)*repayment.Amount, repayment.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "repaid",
+		"data": map[string]interface{}{
+			"repayment_id": repayment.ID,
+			"amount":       repayment.Amount,
+			"account_id":   repayment.AccountID,
+			"date":         repayment.Date.Format("2006-01-02"),
+			"outstanding":  roundMoney(amount - repaid - repayment.Amount),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "debt",
+		EntityID: debtID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	scopes, err := statsScopes(ctx, tx, repayment.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	// Debts count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return repayment, nil
+}
+
+func (s *DebtService) DeleteRepayment(ctx context.Context, userID, debtID, repaymentID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var direction, accountID string
+	var amount float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT d.direction, r.account_id, r.amount
+		FROM debt_repayments r
+		JOIN debts d ON r.debt_id = d.id
+		WHERE r.id = $1 AND r.debt_id = $2 AND d.user_id = $3`,
+		repaymentID, debtID, userID).Scan(&direction, &accountID, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("repayment not found")
+		}
+		return fmt.Errorf("failed to get repayment: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM debt_repayments WHERE id = $1`, repaymentID); err != nil {
+		return fmt.Errorf("failed to delete repayment: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE accounts SET balance = balance + $1 WHERE id = $2`,
+		debtSign(direction)*amount, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to revert account balance: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "repayment_deleted",
+		"data": map[string]interface{}{
+			"repayment_id": repaymentID,
+			"amount":       amount,
+			"account_id":   accountID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "debt",
+		EntityID: debtID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	scopes, err := statsScopes(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+	// Debts count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return nil
+}
+
+func (s *DebtService) GetSummary(ctx context.Context, userID string) (*models.DebtSummary, error) {
+	debts, err := s.GetDebts(ctx, &models.DebtFilter{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.DebtSummary{}
+	for _, d := range debts {
+		if d.Status == "repaid" {
+			continue
+		}
+		summary.OpenDebts++
+		if d.Status == "overdue" {
+			summary.OverdueDebts++
+		}
+		if d.Direction == "lent" {
+			summary.OwedToMe += d.Outstanding
+		} else {
+			summary.OwedByMe += d.Outstanding
+		}
+	}
+
+	summary.OwedToMe = roundMoney(summary.OwedToMe)
+	summary.OwedByMe = roundMoney(summary.OwedByMe)
+	summary.Net = roundMoney(summary.OwedToMe - summary.OwedByMe)
+
+	return summary, nil
+}
+
+const counterpartySelect = `
+        SELECT
+            cp.id, cp.user_id, cp.name, COALESCE(cp.note, ''), cp.created_at, cp.updated_at,
+            COALESCE(SUM(d.amount - COALESCE(r.repaid, 0)) FILTER (WHERE d.direction = 'lent'), 0),
+            COALESCE(SUM(d.amount - COALESCE(r.repaid, 0)) FILTER (WHERE d.direction = 'borrowed'), 0),
+            COUNT(d.id) FILTER (WHERE d.amount > COALESCE(r.repaid, 0)),
+            COALESCE(BOOL_OR(d.amount > COALESCE(r.repaid, 0) AND d.due_date < CURRENT_DATE), false)
+        FROM counterparties cp
+        LEFT JOIN debts d ON d.counterparty_id = cp.id
+        LEFT JOIN (
+            SELECT debt_id, SUM(amount) as repaid FROM debt_repayments GROUP BY debt_id
+        ) r ON r.debt_id = d.id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCounterparty(row rowScanner) (*models.Counterparty, error) {
+	var cp models.Counterparty
+	err := row.Scan(&cp.ID, &cp.UserID, &cp.Name, &cp.Note, &cp.CreatedAt, &cp.UpdatedAt,
+		&cp.OwedToMe, &cp.OwedByMe, &cp.OpenDebts, &cp.HasOverdue)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan counterparty: %w", err)
+	}
+
+	cp.OwedToMe = roundMoney(cp.OwedToMe)
+	cp.OwedByMe = roundMoney(cp.OwedByMe)
+	cp.Balance = roundMoney(cp.OwedToMe - cp.OwedByMe)
+
+	return &cp, nil
+}
+
+func scanDebt(row rowScanner) (*models.Debt, error) {
+	var d models.Debt
+	var dueDate sql.NullTime
+	err := row.Scan(
+		&d.ID, &d.UserID, &d.CounterpartyID, &d.AccountID, &d.Direction, &d.Amount,
+		&d.Description, &d.Date, &dueDate, &d.CreatedAt, &d.UpdatedAt,
+		&d.CounterpartyName, &d.AccountName, &d.RepaidAmount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan debt: %w", err)
+	}
+
+	if dueDate.Valid {
+		d.DueDate = &dueDate.Time
+	}
+
+	d.Outstanding = roundMoney(d.Amount - d.RepaidAmount)
+	switch {
+	case d.Outstanding <= 0:
+		d.Status = "repaid"
+	case d.DueDate != nil && d.DueDate.Before(today()):
+		d.Status = "overdue"
+	default:
+		d.Status = "open"
+	}
+
+	return &d, nil
+}
+
+// verifyAccountTx checks the account is part of the active space and the
+// user may change its balance.
+func verifyAccountTx(ctx context.Context, tx *sql.Tx, scope Scope, accountID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND id IN `+scope.accounts("$2")+`)`,
+		accountID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify account: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+	_, err = requireAccountRole(ctx, tx, scope.UserID, accountID, "editor")
+	return err
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
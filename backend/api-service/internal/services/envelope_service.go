@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"api-service/internal/models"
+)
+
+// ageOfMoneyOutflows is the number of most recent expenses averaged into the
+// age-of-money metric.
+const ageOfMoneyOutflows = 10
+
+// EnvelopeService implements zero-based ("envelope") budgeting: income raises
+// a ready-to-assign pool, the user assigns it to expense category envelopes
+// month by month and expenses draw the envelopes down. Envelope balances
+// carry over between months, so an overspent envelope stays negative until
+// money is moved into it.
+type EnvelopeService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewEnvelopeService(db *sql.DB, logService *LogService) *EnvelopeService {
+	return &EnvelopeService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+func (s *EnvelopeService) GetSettings(ctx context.Context, scope Scope) (*models.EnvelopeSettings, error) {
+	settings := &models.EnvelopeSettings{UserID: scope.UserID, WorkspaceID: scope.workspace()}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT user_id, enabled, start_month, updated_at FROM envelope_settings WHERE `+scope.owns("", "$1"),
+		scope.arg()).Scan(&settings.UserID, &settings.Enabled, &settings.StartMonth, &settings.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			settings.StartMonth = periodStart("monthly", today())
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to get envelope settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (s *EnvelopeService) UpdateSettings(ctx context.Context, scope Scope, req *models.UpdateEnvelopeSettingsRequest) (*models.EnvelopeSettings, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	startMonth := periodStart("monthly", today())
+	if req.StartMonth != "" {
+		parsed, err := parseMonth(req.StartMonth)
+		if err != nil {
+			return nil, err
+		}
+		startMonth = parsed
+	}
+
+	settings := &models.EnvelopeSettings{
+		UserID:      userID,
+		WorkspaceID: scope.workspace(),
+		Enabled:     req.Enabled,
+		StartMonth:  startMonth,
+		UpdatedAt:   time.Now(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO envelope_settings (user_id, workspace_id, enabled, start_month, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ((COALESCE(workspace_id, user_id))) DO UPDATE
+		SET user_id = EXCLUDED.user_id, enabled = EXCLUDED.enabled, start_month = EXCLUDED.start_month, updated_at = EXCLUDED.updated_at`,
+		settings.UserID, settings.WorkspaceID, settings.Enabled, settings.StartMonth, settings.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update envelope settings: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "updated",
+		"data": map[string]interface{}{
+			"enabled":     settings.Enabled,
+			"start_month": settings.StartMonth.Format("2006-01"),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:  userID,
+		Action:  "update",
+		Entity:  "envelope_settings",
+		Details: string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settings, nil
+}
+
+// GetGrid returns every expense envelope for the month together with the
+// ready-to-assign pool and the age-of-money metric.
+func (s *EnvelopeService) GetGrid(ctx context.Context, scope Scope, month string) (*models.EnvelopeGrid, error) {
+	settings, err := s.enabledSettings(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	monthStart := periodStart("monthly", today())
+	if month != "" {
+		if monthStart, err = parseMonth(month); err != nil {
+			return nil, err
+		}
+	}
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT
+			c.id, c.name, COALESCE(c.icon, ''), COALESCE(c.color, ''),
+			COALESCE((SELECT SUM(ea.assigned) FROM envelope_assignments ea
+				WHERE `+scope.owns("ea", "$1")+` AND ea.category_id = c.id AND ea.month = $3), 0),
+			COALESCE((SELECT SUM(ea.assigned) FROM envelope_assignments ea
+				WHERE `+scope.owns("ea", "$1")+` AND ea.category_id = c.id AND ea.month >= $2 AND ea.month <= $3), 0),
+			COALESCE((SELECT SUM(t.amount) FROM transactions t
+				WHERE t.account_id IN `+scope.accounts("$1")+` AND t.category_id = c.id AND t.type = 'expense'
+				AND t.date >= $3 AND t.date < $4), 0),
+			COALESCE((SELECT SUM(t.amount) FROM transactions t
+				WHERE t.account_id IN `+scope.accounts("$1")+` AND t.category_id = c.id AND t.type = 'expense'
+				AND t.date >= $2 AND t.date < $4), 0)
+		FROM categories c
+		WHERE (`+scope.owns("c", "$1")+` OR c.is_system = true) AND c.type = 'expense'
+		ORDER BY c.is_system DESC, c.name`,
+		scope.arg(), settings.StartMonth, monthStart, monthEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get envelopes: %w", err)
+	}
+	defer rows.Close()
+
+	grid := &models.EnvelopeGrid{
+		Month:     monthStart.Format("2006-01"),
+		Envelopes: []*models.Envelope{},
+	}
+
+	for rows.Next() {
+		var e models.Envelope
+		var assignedTotal, activityTotal float64
+		err := rows.Scan(&e.CategoryID, &e.CategoryName, &e.CategoryIcon, &e.CategoryColor,
+			&e.Assigned, &assignedTotal, &e.Activity, &activityTotal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan envelope: %w", err)
+		}
+
+		e.Available = roundMoney(assignedTotal - activityTotal)
+		e.Overspent = e.Available < 0
+
+		grid.TotalAssigned += e.Assigned
+		grid.TotalActivity += e.Activity
+		grid.TotalAvailable += e.Available
+		if e.Overspent {
+			grid.Overspent += -e.Available
+		}
+
+		grid.Envelopes = append(grid.Envelopes, &e)
+	}
+
+	grid.TotalAssigned = roundMoney(grid.TotalAssigned)
+	grid.TotalActivity = roundMoney(grid.TotalActivity)
+	grid.TotalAvailable = roundMoney(grid.TotalAvailable)
+	grid.Overspent = roundMoney(grid.Overspent)
+
+	income, assigned, err := s.getPool(ctx, scope, settings.StartMonth, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+	grid.Income = roundMoney(income)
+	grid.ReadyToAssign = roundMoney(income - assigned)
+
+	grid.AgeOfMoney, err = s.GetAgeOfMoney(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return grid, nil
+}
+
+// Assign sets the amount assigned to an envelope for a month. Increases are
+// funded from the ready-to-assign pool.
+func (s *EnvelopeService) Assign(ctx context.Context, scope Scope, req *models.AssignEnvelopeRequest) error {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	settings, err := s.enabledSettings(ctx, scope)
+	if err != nil {
+		return err
+	}
+
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		return err
+	}
+	if month.Before(settings.StartMonth) {
+		return fmt.Errorf("month is before envelope budgeting start")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.verifyEnvelope(ctx, tx, scope, req.CategoryID); err != nil {
+		return err
+	}
+
+	// Serialise concurrent assignments in the same space
+	if _, err := tx.ExecContext(ctx,
+		`SELECT 1 FROM envelope_settings WHERE `+scope.owns("", "$1")+` FOR UPDATE`, scope.arg()); err != nil {
+		return fmt.Errorf("failed to lock envelope settings: %w", err)
+	}
+
+	var current float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(assigned), 0) FROM envelope_assignments
+		WHERE `+scope.owns("", "$1")+` AND category_id = $2 AND month = $3`,
+		scope.arg(), req.CategoryID, month).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment: %w", err)
+	}
+
+	delta := req.Amount - current
+	if delta > 0 {
+		ready, err := s.readyToAssign(ctx, tx, scope, settings.StartMonth)
+		if err != nil {
+			return err
+		}
+		if delta > ready+0.005 {
+			return fmt.Errorf("not enough money ready to assign")
+		}
+	}
+
+	if err := s.addAssignment(ctx, tx, scope, req.CategoryID, month, delta); err != nil {
+		return err
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "assigned",
+		"changes": map[string]interface{}{
+			"assigned": map[string]interface{}{
+				"old": current,
+				"new": req.Amount,
+			},
+		},
+		"month": month.Format("2006-01"),
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "envelope",
+		EntityID: req.CategoryID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Move transfers money between two envelopes, or between an envelope and the
+// ready-to-assign pool when one side is empty. This is how overspending is
+// covered.
+func (s *EnvelopeService) Move(ctx context.Context, scope Scope, req *models.MoveEnvelopeRequest) error {
+	userID := scope.UserID
+
+	if req.FromCategoryID == "" && req.ToCategoryID == "" {
+		return fmt.Errorf("source or target envelope is required")
+	}
+	if req.FromCategoryID == req.ToCategoryID {
+		return fmt.Errorf("source and target envelopes must differ")
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	settings, err := s.enabledSettings(ctx, scope)
+	if err != nil {
+		return err
+	}
+
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		return err
+	}
+	if month.Before(settings.StartMonth) {
+		return fmt.Errorf("month is before envelope budgeting start")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`SELECT 1 FROM envelope_settings WHERE `+scope.owns("", "$1")+` FOR UPDATE`, scope.arg()); err != nil {
+		return fmt.Errorf("failed to lock envelope settings: %w", err)
+	}
+
+	if req.FromCategoryID != "" {
+		if err := s.verifyEnvelope(ctx, tx, scope, req.FromCategoryID); err != nil {
+			return err
+		}
+
+		available, err := s.envelopeAvailable(ctx, tx, scope, req.FromCategoryID, settings.StartMonth, month)
+		if err != nil {
+			return err
+		}
+		if req.Amount > available+0.005 {
+			return fmt.Errorf("not enough money in source envelope")
+		}
+
+		if err := s.addAssignment(ctx, tx, scope, req.FromCategoryID, month, -req.Amount); err != nil {
+			return err
+		}
+	} else {
+		ready, err := s.readyToAssign(ctx, tx, scope, settings.StartMonth)
+		if err != nil {
+			return err
+		}
+		if req.Amount > ready+0.005 {
+			return fmt.Errorf("not enough money ready to assign")
+		}
+	}
+
+	if req.ToCategoryID != "" {
+		if err := s.verifyEnvelope(ctx, tx, scope, req.ToCategoryID); err != nil {
+			return err
+		}
+		if err := s.addAssignment(ctx, tx, scope, req.ToCategoryID, month, req.Amount); err != nil {
+			return err
+		}
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "moved",
+		"data": map[string]interface{}{
+			"month":            month.Format("2006-01"),
+			"from_category_id": req.FromCategoryID,
+			"to_category_id":   req.ToCategoryID,
+			"amount":           req.Amount,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:  userID,
+		Action:  "update",
+		Entity:  "envelope",
+		Details: string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAgeOfMoney returns the average number of days between money arriving
+// and being spent over the most recent expenses, matching income to
+// expenses first-in first-out. It returns nil when there is not enough
+// history.
+func (s *EnvelopeService) GetAgeOfMoney(ctx context.Context, scope Scope) (*int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT type, amount, date FROM transactions
+		WHERE account_id IN `+scope.accounts("$1")+`
+		ORDER BY date ASC, CASE WHEN type = 'income' THEN 0 ELSE 1 END, created_at ASC`,
+		scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	type lot struct {
+		amount float64
+		date   time.Time
+	}
+
+	var lots []lot
+	var ages []float64
+
+	for rows.Next() {
+		var txType string
+		var amount float64
+		var date time.Time
+		if err := rows.Scan(&txType, &amount, &date); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		if txType == "income" {
+			lots = append(lots, lot{amount: amount, date: date})
+			continue
+		}
+
+		// Consume the oldest income first
+		remaining := amount
+		var weighted, matched float64
+		for remaining > 0 && len(lots) > 0 {
+			take := math.Min(remaining, lots[0].amount)
+			weighted += take * date.Sub(lots[0].date).Hours() / 24
+			matched += take
+			remaining -= take
+			lots[0].amount -= take
+			if lots[0].amount <= 0.005 {
+				lots = lots[1:]
+			}
+		}
+
+		if matched > 0 {
+			ages = append(ages, weighted/matched)
+		}
+	}
+
+	if len(ages) == 0 {
+		return nil, nil
+	}
+
+	if len(ages) > ageOfMoneyOutflows {
+		ages = ages[len(ages)-ageOfMoneyOutflows:]
+	}
+
+	var sum float64
+	for _, a := range ages {
+		sum += a
+	}
+	days := int(math.Round(sum / float64(len(ages))))
+
+	return &days, nil
+}
+
+func (s *EnvelopeService) enabledSettings(ctx context.Context, scope Scope) (*models.EnvelopeSettings, error) {
+	settings, err := s.GetSettings(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, fmt.Errorf("envelope mode is not enabled")
+	}
+	return settings, nil
+}
+
+// getPool returns income received and money assigned from the start of
+// envelope budgeting up to (but excluding) until.
+func (s *EnvelopeService) getPool(ctx context.Context, scope Scope, start, until time.Time) (float64, float64, error) {
+	var income, assigned float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT
+			COALESCE((SELECT SUM(amount) FROM transactions
+				WHERE account_id IN `+scope.accounts("$1")+` AND type = 'income' AND date >= $2 AND date < $3), 0),
+			COALESCE((SELECT SUM(assigned) FROM envelope_assignments
+				WHERE `+scope.owns("", "$1")+` AND month >= $2 AND month < $3), 0)`,
+		scope.arg(), start, until).Scan(&income, &assigned)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get ready to assign: %w", err)
+	}
+	return income, assigned, nil
+}
+
+// readyToAssign returns all income received so far minus everything
+// assigned in any month, including future ones.
+func (s *EnvelopeService) readyToAssign(ctx context.Context, tx *sql.Tx, scope Scope, start time.Time) (float64, error) {
+	var ready float64
+	err := tx.QueryRowContext(ctx,
+		`SELECT
+			COALESCE((SELECT SUM(amount) FROM transactions
+				WHERE account_id IN `+scope.accounts("$1")+` AND type = 'income' AND date >= $2), 0) -
+			COALESCE((SELECT SUM(assigned) FROM envelope_assignments
+				WHERE `+scope.owns("", "$1")+` AND month >= $2), 0)`,
+		scope.arg(), start).Scan(&ready)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ready to assign: %w", err)
+	}
+	return ready, nil
+}
+
+func (s *EnvelopeService) envelopeAvailable(ctx context.Context, tx *sql.Tx, scope Scope, categoryID string, start, month time.Time) (float64, error) {
+	var available float64
+	err := tx.QueryRowContext(ctx,
+		`SELECT
+			COALESCE((SELECT SUM(assigned) FROM envelope_assignments
+				WHERE `+scope.owns("", "$1")+` AND category_id = $2 AND month >= $3 AND month <= $4), 0) -
+			COALESCE((SELECT SUM(amount) FROM transactions
+				WHERE account_id IN `+scope.accounts("$1")+` AND category_id = $2 AND type = 'expense'
+				AND date >= $3 AND date < $5), 0)`,
+		scope.arg(), categoryID, start, month, month.AddDate(0, 1, 0)).Scan(&available)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get envelope balance: %w", err)
+	}
+	return available, nil
+}
+
+func (s *EnvelopeService) addAssignment(ctx context.Context, tx *sql.Tx, scope Scope, categoryID string, month time.Time, delta float64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO envelope_assignments (user_id, workspace_id, category_id, month, assigned)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ((COALESCE(workspace_id, user_id)), category_id, month) DO UPDATE
+		SET assigned = envelope_assignments.assigned + EXCLUDED.assigned, updated_at = CURRENT_TIMESTAMP`,
+		scope.UserID, scope.workspace(), categoryID, month, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update assignment: %w", err)
+	}
+	return nil
+}
+
+func (s *EnvelopeService) verifyEnvelope(ctx context.Context, tx *sql.Tx, scope Scope, categoryID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM categories
+		WHERE id = $1 AND (`+scope.owns("", "$2")+` OR is_system = true) AND type = 'expense')`,
+		categoryID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify category: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("category not found")
+	}
+	return nil
+}
+
+func parseMonth(value string) (time.Time, error) {
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month format, expected YYYY-MM: %w", err)
+	}
+	return month, nil
+}
@@ -0,0 +1,603 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// goalRateWindowMonths is how far back contributions are averaged to project
+// a goal's completion date.
+const goalRateWindowMonths = 3
+
+// goalScheduleTolerance is the share of the linear schedule a goal may lag
+// before it is reported as behind.
+const goalScheduleTolerance = 0.9
+
+type GoalService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewGoalService(db *sql.DB, logService *LogService) *GoalService {
+	return &GoalService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+func (s *GoalService) CreateGoal(ctx context.Context, scope Scope, req *models.CreateGoalRequest) (*models.Goal, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	goal := &models.Goal{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		WorkspaceID:  scope.workspace(),
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if req.TargetDate != "" {
+		targetDate, err := time.Parse("2006-01-02", req.TargetDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		goal.TargetDate = &targetDate
+	}
+
+	if req.AccountID != "" {
+		if err := s.verifyAccount(ctx, scope, req.AccountID); err != nil {
+			return nil, err
+		}
+		goal.AccountID = &req.AccountID
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO goals (id, user_id, workspace_id, name, target_amount, target_date, account_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		goal.ID, goal.UserID, goal.WorkspaceID, goal.Name, goal.TargetAmount, goal.TargetDate,
+		goal.AccountID, goal.CreatedAt, goal.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	s.calculate(goal, 0, 0)
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":            goal.ID,
+			"name":          goal.Name,
+			"target_amount": goal.TargetAmount,
+			"target_date":   req.TargetDate,
+			"account_id":    req.AccountID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "goal",
+		EntityID: goal.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return goal, nil
+}
+
+func (s *GoalService) GetGoals(ctx context.Context, scope Scope) ([]*models.Goal, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT g.id, g.user_id, g.workspace_id, g.name, g.target_amount, g.target_date, g.account_id,
+			g.created_at, g.updated_at,
+			COALESCE(SUM(gc.amount), 0),
+			COALESCE(SUM(gc.amount) FILTER (WHERE gc.date > CURRENT_DATE - make_interval(months => $2::int)), 0)
+		FROM goals g
+		LEFT JOIN goal_contributions gc ON gc.goal_id = g.id
+		WHERE `+scope.owns("g", "$1")+`
+		GROUP BY g.id
+		ORDER BY g.target_date ASC NULLS LAST, g.created_at ASC`,
+		scope.arg(), goalRateWindowMonths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []*models.Goal{}
+	for rows.Next() {
+		var g models.Goal
+		var saved, recent float64
+		err := rows.Scan(&g.ID, &g.UserID, &g.WorkspaceID, &g.Name, &g.TargetAmount, &g.TargetDate, &g.AccountID,
+			&g.CreatedAt, &g.UpdatedAt, &saved, &recent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		s.calculate(&g, saved, recent)
+		goals = append(goals, &g)
+	}
+
+	return goals, nil
+}
+
+func (s *GoalService) GetGoal(ctx context.Context, scope Scope, goalID string) (*models.Goal, error) {
+	var g models.Goal
+	var saved, recent float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT g.id, g.user_id, g.workspace_id, g.name, g.target_amount, g.target_date, g.account_id,
+			g.created_at, g.updated_at,
+			COALESCE(SUM(gc.amount), 0),
+			COALESCE(SUM(gc.amount) FILTER (WHERE gc.date > CURRENT_DATE - make_interval(months => $3::int)), 0)
+		FROM goals g
+		LEFT JOIN goal_contributions gc ON gc.goal_id = g.id
+		WHERE g.id = $1 AND `+scope.owns("g", "$2")+`
+		GROUP BY g.id`,
+		goalID, scope.arg(), goalRateWindowMonths).Scan(&g.ID, &g.UserID, &g.WorkspaceID, &g.Name, &g.TargetAmount,
+		&g.TargetDate, &g.AccountID, &g.CreatedAt, &g.UpdatedAt, &saved, &recent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("goal not found")
+		}
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+
+	s.calculate(&g, saved, recent)
+	return &g, nil
+}
+
+func (s *GoalService) UpdateGoal(ctx context.Context, scope Scope, goalID string, req *models.UpdateGoalRequest) (*models.Goal, error) {
+	userID := scope.UserID
+
+	oldGoal, err := s.GetGoal(ctx, scope, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	updateFields := make(map[string]interface{})
+	changes := make(map[string]map[string]interface{})
+
+	if req.Name != "" && req.Name != oldGoal.Name {
+		updateFields["name"] = req.Name
+		changes["name"] = map[string]interface{}{
+			"old": oldGoal.Name,
+			"new": req.Name,
+		}
+	}
+
+	if req.TargetAmount > 0 && req.TargetAmount != oldGoal.TargetAmount {
+		updateFields["target_amount"] = req.TargetAmount
+		changes["target_amount"] = map[string]interface{}{
+			"old": oldGoal.TargetAmount,
+			"new": req.TargetAmount,
+		}
+	}
+
+	if req.TargetDate != "" {
+		targetDate, err := time.Parse("2006-01-02", req.TargetDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		if oldGoal.TargetDate == nil || !oldGoal.TargetDate.Equal(targetDate) {
+			var old interface{}
+			if oldGoal.TargetDate != nil {
+				old = oldGoal.TargetDate.Format("2006-01-02")
+			}
+			updateFields["target_date"] = targetDate
+			changes["target_date"] = map[string]interface{}{
+				"old": old,
+				"new": req.TargetDate,
+			}
+		}
+	}
+
+	if req.AccountID != "" && (oldGoal.AccountID == nil || *oldGoal.AccountID != req.AccountID) {
+		if err := s.verifyAccount(ctx, scope, req.AccountID); err != nil {
+			return nil, err
+		}
+		updateFields["account_id"] = req.AccountID
+		changes["account_id"] = map[string]interface{}{
+			"old": oldGoal.AccountID,
+			"new": req.AccountID,
+		}
+	}
+
+	if len(updateFields) == 0 {
+		return oldGoal, nil
+	}
+
+	updateFields["updated_at"] = time.Now()
+
+	query := `UPDATE goals SET `
+	args := []interface{}{}
+	i := 1
+
+	for field, value := range updateFields {
+		if i > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, i)
+		args = append(args, value)
+		i++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d", i)
+	args = append(args, goalID)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "goal",
+		EntityID: goalID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetGoal(ctx, scope, goalID)
+}
+
+func (s *GoalService) DeleteGoal(ctx context.Context, scope Scope, goalID string) error {
+	userID := scope.UserID
+
+	goal, err := s.GetGoal(ctx, scope, goalID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM goals WHERE id = $1`,
+		goalID)
+	if err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("goal not found")
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name":          goal.Name,
+			"target_amount": goal.TargetAmount,
+			"saved_amount":  goal.SavedAmount,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "goal",
+		EntityID: goalID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AddContribution records money put towards (or taken from) a goal. When a
+// transaction is linked, its amount and date are used unless overridden, and
+// expenses count as withdrawals when the goal has a linked account.
+func (s *GoalService) AddContribution(ctx context.Context, scope Scope, goalID string, req *models.CreateGoalContributionRequest) (*models.GoalContribution, error) {
+	userID := scope.UserID
+
+	goal, err := s.GetGoal(ctx, scope, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	contribution := &models.GoalContribution{
+		ID:        uuid.New().String(),
+		GoalID:    goalID,
+		UserID:    userID,
+		Amount:    req.Amount,
+		Date:      today(),
+		Note:      req.Note,
+		CreatedAt: time.Now(),
+	}
+
+	if req.TransactionID != "" {
+		var txAccountID, txType string
+		var txAmount float64
+		var txDate time.Time
+		err := s.db.QueryRowContext(ctx,
+			`SELECT account_id, type, amount, date FROM transactions WHERE id = $1 AND account_id IN `+scope.accounts("$2"),
+			req.TransactionID, scope.arg()).Scan(&txAccountID, &txType, &txAmount, &txDate)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("transaction not found")
+			}
+			return nil, fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		if goal.AccountID != nil && *goal.AccountID != txAccountID {
+			return nil, fmt.Errorf("transaction does not belong to the goal account")
+		}
+
+		if contribution.Amount == 0 {
+			contribution.Amount = txAmount
+			if goal.AccountID != nil && txType == "expense" {
+				contribution.Amount = -txAmount
+			}
+		}
+		contribution.Date = txDate
+		contribution.TransactionID = &req.TransactionID
+	}
+
+	if contribution.Amount == 0 {
+		return nil, fmt.Errorf("contribution amount is required")
+	}
+
+	if req.Date != "" {
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+		}
+		contribution.Date = date
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO goal_contributions (id, goal_id, user_id, amount, date, transaction_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		contribution.ID, contribution.GoalID, contribution.UserID, contribution.Amount,
+		contribution.Date, contribution.TransactionID, contribution.Note, contribution.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contribution: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "contributed",
+		"data": map[string]interface{}{
+			"id":             contribution.ID,
+			"amount":         contribution.Amount,
+			"date":           contribution.Date.Format("2006-01-02"),
+			"transaction_id": req.TransactionID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "goal",
+		EntityID: goalID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return contribution, nil
+}
+
+func (s *GoalService) GetContributions(ctx context.Context, scope Scope, goalID string) ([]*models.GoalContribution, error) {
+	if _, err := s.GetGoal(ctx, scope, goalID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, goal_id, user_id, amount, date, transaction_id, COALESCE(note, ''), created_at
+		FROM goal_contributions
+		WHERE goal_id = $1
+		ORDER BY date DESC, created_at DESC`,
+		goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contributions: %w", err)
+	}
+	defer rows.Close()
+
+	contributions := []*models.GoalContribution{}
+	for rows.Next() {
+		var gc models.GoalContribution
+		err := rows.Scan(&gc.ID, &gc.GoalID, &gc.UserID, &gc.Amount, &gc.Date,
+			&gc.TransactionID, &gc.Note, &gc.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contribution: %w", err)
+		}
+		contributions = append(contributions, &gc)
+	}
+
+	return contributions, nil
+}
+
+func (s *GoalService) DeleteContribution(ctx context.Context, scope Scope, goalID, contributionID string) error {
+	userID := scope.UserID
+
+	if _, err := s.GetGoal(ctx, scope, goalID); err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM goal_contributions WHERE id = $1 AND goal_id = $2`,
+		contributionID, goalID)
+	if err != nil {
+		return fmt.Errorf("failed to delete contribution: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("contribution not found")
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "contribution_deleted",
+		"data": map[string]interface{}{
+			"contribution_id": contributionID,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "goal",
+		EntityID: goalID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// calculate fills progress, projection and schedule fields from the saved
+// total and the contributions made within the rate window.
+func (s *GoalService) calculate(goal *models.Goal, saved, recent float64) {
+	now := today()
+
+	goal.SavedAmount = roundMoney(saved)
+	goal.RemainingAmount = roundMoney(math.Max(goal.TargetAmount-saved, 0))
+	goal.Progress = math.Min(math.Round(saved/goal.TargetAmount*10000)/100, 100)
+	goal.Completed = saved >= goal.TargetAmount
+	goal.MonthlyContributionRate = roundMoney(recent / goalRateWindowMonths)
+
+	if goal.Completed {
+		return
+	}
+
+	if goal.MonthlyContributionRate > 0 {
+		months := goal.RemainingAmount / goal.MonthlyContributionRate
+		projected := now.AddDate(0, 0, int(math.Ceil(months*30.44)))
+		goal.ProjectedCompletionDate = &projected
+	}
+
+	if goal.TargetDate == nil {
+		return
+	}
+
+	monthsLeft := goal.TargetDate.Sub(now).Hours() / 24 / 30.44
+	if monthsLeft < 1 {
+		monthsLeft = 1
+	}
+	goal.RequiredMonthlyContribution = roundMoney(goal.RemainingAmount / monthsLeft)
+
+	// Compare with a linear schedule from creation to the target date
+	created := time.Date(goal.CreatedAt.Year(), goal.CreatedAt.Month(), goal.CreatedAt.Day(), 0, 0, 0, 0, time.UTC)
+	total := goal.TargetDate.Sub(created).Hours()
+	elapsed := now.Sub(created).Hours()
+
+	var expected float64
+	if total <= 0 || elapsed >= total {
+		expected = goal.TargetAmount
+	} else if elapsed > 0 {
+		expected = goal.TargetAmount * elapsed / total
+	}
+
+	goal.BehindSchedule = saved < expected*goalScheduleTolerance ||
+		(goal.ProjectedCompletionDate != nil && goal.ProjectedCompletionDate.After(*goal.TargetDate))
+}
+
+func (s *GoalService) verifyAccount(ctx context.Context, scope Scope, accountID string) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND id IN `+scope.accounts("$2")+`)`,
+		accountID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify account: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"api-service/internal/models"
+)
+
+// ErrNotFound is wrapped by the "<entity> not found" errors of the getters
+// the history endpoints check access with.
+var ErrNotFound = errors.New("not found")
+
+// historyTables are the tables of the entities that have a history.
+var historyTables = map[string]string{
+	"transaction": "transactions",
+	"account":     "accounts",
+	"category":    "categories",
+}
+
+// HistoryService builds the change history of a single entity from the
+// audit log.
+type HistoryService struct {
+	db *sql.DB
+}
+
+func NewHistoryService(db *sql.DB) *HistoryService {
+	return &HistoryService{db: db}
+}
+
+// GetHistory returns the audit entries of the entity, oldest first, up to
+// until when it is set. When at is set, the entity's state at that moment is
+// reconstructed as well. The IP and user agent are only kept on entries
+// viewerID made: others who can see a shared entity don't get them.
+func (s *HistoryService) GetHistory(ctx context.Context, viewerID, entity, entityID string, at, until *time.Time) (*models.EntityHistory, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ua.id, ua.user_id, COALESCE(u.email, ''), ua.action, COALESCE(ua.details::text, ''),
+			COALESCE(ua.ip, ''), COALESCE(ua.user_agent, ''), COALESCE(ua.request_id, ''), ua.created_at
+		FROM user_actions ua
+		LEFT JOIN users u ON u.id = ua.user_id
+		WHERE ua.entity = $1 AND ua.entity_id = $2 AND ($3::timestamptz IS NULL OR ua.created_at <= $3)
+		ORDER BY ua.created_at, ua.outbox_id`,
+		entity, entityID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	defer rows.Close()
+
+	history := &models.EntityHistory{
+		Entity:   entity,
+		EntityID: entityID,
+		Entries:  []*models.HistoryEntry{},
+	}
+	snapshot := &models.EntitySnapshot{State: map[string]interface{}{}}
+
+	for rows.Next() {
+		var (
+			e       models.HistoryEntry
+			details string
+		)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Email, &e.Action, &details,
+			&e.IP, &e.UserAgent, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+
+		var d models.AuditDetails
+		if details != "" {
+			// Entries with unreadable details still show who did what and when
+			_ = json.Unmarshal([]byte(details), &d)
+		}
+
+		e.Event = d.Action
+		e.Changes = fieldChanges(d)
+		e.Source = "system"
+		if e.IP != "" {
+			e.Source = "request"
+		}
+		if !strings.EqualFold(e.UserID, viewerID) {
+			e.IP = ""
+			e.UserAgent = ""
+		}
+		history.Entries = append(history.Entries, &e)
+
+		if at != nil && !e.CreatedAt.After(*at) {
+			applyChange(snapshot, e.Event, e.Changes)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if at != nil {
+		snapshot.At = *at
+		history.Snapshot = snapshot
+	}
+
+	return history, nil
+}
+
+// Exists reports whether the entity's row is still there, whoever it
+// belongs to.
+func (s *HistoryService) Exists(ctx context.Context, entity, entityID string) (bool, error) {
+	table, ok := historyTables[entity]
+	if !ok {
+		return false, fmt.Errorf("unknown entity %q", entity)
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, entityID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", entity, err)
+	}
+	return exists, nil
+}
+
+// LastActed returns when the user last appears in the entity's history, or
+// nil when they never do. It lets the author of a deleted entity still see
+// its history up to then, when they could still see the entity.
+func (s *HistoryService) LastActed(ctx context.Context, userID, entity, entityID string) (*time.Time, error) {
+	var last sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT MAX(created_at) FROM user_actions WHERE entity = $1 AND entity_id = $2 AND user_id = $3`,
+		entity, entityID, userID).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check history: %w", err)
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
+
+// fieldChanges turns the details of an entry into a list of field changes
+// sorted by field: created entries have only new values, deleted entries
+// only old ones.
+func fieldChanges(d models.AuditDetails) []*models.FieldChange {
+	changes := []*models.FieldChange{}
+
+	for field, value := range d.Data {
+		change := &models.FieldChange{Field: field}
+		if d.Action == "deleted" {
+			change.Old = value
+		} else {
+			change.New = value
+		}
+		changes = append(changes, change)
+	}
+
+	for field, value := range d.Changes {
+		change := &models.FieldChange{Field: field}
+		if pair, ok := value.(map[string]interface{}); ok {
+			change.Old = pair["old"]
+			change.New = pair["new"]
+		} else {
+			change.New = value
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// applyChange replays one entry onto the snapshot. Only the entity's own
+// create, update and delete entries change its state; others, like member
+// changes on an account, are part of the timeline only.
+func applyChange(snapshot *models.EntitySnapshot, event string, changes []*models.FieldChange) {
+	switch event {
+	case "created":
+		snapshot.Exists = true
+		snapshot.Deleted = false
+		snapshot.State = map[string]interface{}{}
+		for _, c := range changes {
+			snapshot.State[c.Field] = c.New
+		}
+	case "updated":
+		for _, c := range changes {
+			snapshot.State[c.Field] = c.New
+		}
+		// History recorded before the entity's creation entry existed
+		snapshot.Exists = !snapshot.Deleted
+	case "deleted":
+		// Keep the last known state so it shows what was deleted
+		for _, c := range changes {
+			if _, ok := snapshot.State[c.Field]; !ok {
+				snapshot.State[c.Field] = c.Old
+			}
+		}
+		snapshot.Exists = false
+		snapshot.Deleted = true
+	}
+}
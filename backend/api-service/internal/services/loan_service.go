@@ -0,0 +1,767 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Loan schedules are never stored. They are rebuilt from the loan terms and
+// the recorded payments every time, so deleting or back-dating a payment
+// re-splits everything after it.
+
+type LoanService struct {
+	db         *sql.DB
+	logService *LogService
+	statsCache *StatsCache
+}
+
+func NewLoanService(db *sql.DB, logService *LogService, statsCache *StatsCache) *LoanService {
+	return &LoanService{
+		db:         db,
+		logService: logService,
+		statsCache: statsCache,
+	}
+}
+
+func (s *LoanService) CreateLoan(ctx context.Context, scope Scope, req *models.CreateLoanRequest) (*models.Loan, error) {
+	userID := scope.UserID
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+	}
+
+	loan := &models.Loan{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Name:        req.Name,
+		Principal:   req.Principal,
+		AnnualRate:  req.AnnualRate,
+		TermMonths:  req.TermMonths,
+		PaymentType: req.PaymentType,
+		StartDate:   startDate,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if req.AccountID != "" {
+		if err := s.verifyAccount(ctx, scope, req.AccountID); err != nil {
+			return nil, err
+		}
+		loan.AccountID = &req.AccountID
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO loans (id, user_id, name, principal, annual_rate, term_months, payment_type, start_date, account_id, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		loan.ID, loan.UserID, loan.Name, loan.Principal, loan.AnnualRate, loan.TermMonths,
+		loan.PaymentType, loan.StartDate, loan.AccountID, loan.CreatedAt, loan.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loan: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":           loan.ID,
+			"name":         loan.Name,
+			"principal":    loan.Principal,
+			"annual_rate":  loan.AnnualRate,
+			"term_months":  loan.TermMonths,
+			"payment_type": loan.PaymentType,
+			"start_date":   loan.StartDate.Format("2006-01-02"),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "loan",
+		EntityID: loan.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Loans count towards the personal summary
+	s.statsCache.Invalidate(ctx, Scope{UserID: userID})
+
+	amortize(loan, nil)
+	return loan, nil
+}
+
+func (s *LoanService) GetLoans(ctx context.Context, userID string) ([]*models.Loan, error) {
+	return getLoans(ctx, s.db, userID)
+}
+
+func (s *LoanService) GetLoan(ctx context.Context, userID, loanID string) (*models.Loan, error) {
+	loan, _, err := s.getLoanWithSchedule(ctx, userID, loanID)
+	return loan, err
+}
+
+func (s *LoanService) GetSchedule(ctx context.Context, userID, loanID string) (*models.Loan, []*models.LoanScheduleEntry, error) {
+	return s.getLoanWithSchedule(ctx, userID, loanID)
+}
+
+func (s *LoanService) UpdateLoan(ctx context.Context, scope Scope, loanID string, req *models.UpdateLoanRequest) (*models.Loan, error) {
+	userID := scope.UserID
+
+	oldLoan, err := s.GetLoan(ctx, userID, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]map[string]interface{})
+	name := oldLoan.Name
+	accountID := oldLoan.AccountID
+
+	if req.Name != "" && req.Name != name {
+		changes["name"] = map[string]interface{}{"old": name, "new": req.Name}
+		name = req.Name
+	}
+
+	if req.AccountID != nil && *req.AccountID != stringValue(accountID) {
+		var newAccountID *string
+		if *req.AccountID != "" {
+			if _, err := uuid.Parse(*req.AccountID); err != nil {
+				return nil, fmt.Errorf("account not found")
+			}
+			if err := s.verifyAccount(ctx, scope, *req.AccountID); err != nil {
+				return nil, err
+			}
+			newAccountID = req.AccountID
+		}
+		changes["account_id"] = map[string]interface{}{"old": accountID, "new": newAccountID}
+		accountID = newAccountID
+	}
+
+	if len(changes) == 0 {
+		return oldLoan, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE loans SET name = $1, account_id = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		name, accountID, time.Now(), loanID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update loan: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "loan",
+		EntityID: loanID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetLoan(ctx, userID, loanID)
+}
+
+// DeleteLoan removes a loan with its payments and gives back to the accounts
+// whatever the payments took from them.
+func (s *LoanService) DeleteLoan(ctx context.Context, userID, loanID string) error {
+	loan, err := s.GetLoan(ctx, userID, loanID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range loan.Payments {
+		if p.AccountID == nil {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE accounts SET balance = balance + $1 WHERE id = $2`,
+			p.Amount, *p.AccountID)
+		if err != nil {
+			return fmt.Errorf("failed to revert account balance: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM loans WHERE id = $1 AND user_id = $2`, loanID, userID); err != nil {
+		return fmt.Errorf("failed to delete loan: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name":                loan.Name,
+			"principal":           loan.Principal,
+			"remaining_principal": loan.RemainingPrincipal,
+			"payments":            len(loan.Payments),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "loan",
+		EntityID: loanID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	accountIDs := []string{}
+	for _, p := range loan.Payments {
+		accountIDs = append(accountIDs, stringValue(p.AccountID))
+	}
+	scopes, err := statsScopes(ctx, tx, accountIDs...)
+	if err != nil {
+		return err
+	}
+	// Loans count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return nil
+}
+
+// AddPayment records a regular or early payment. Money is taken from the
+// given account, or the loan's default account, when there is one.
+func (s *LoanService) AddPayment(ctx context.Context, scope Scope, loanID string, req *models.CreateLoanPaymentRequest) (*models.LoanPayment, error) {
+	userID := scope.UserID
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
+	}
+
+	loan, err := s.GetLoan(ctx, userID, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if date.Before(loan.StartDate) {
+		return nil, fmt.Errorf("payment date is before the loan start date")
+	}
+
+	if loan.RemainingPrincipal <= 0 {
+		return nil, fmt.Errorf("loan is already paid off")
+	}
+
+	payment := &models.LoanPayment{
+		ID:        uuid.New().String(),
+		LoanID:    loanID,
+		Kind:      req.Kind,
+		Strategy:  req.Strategy,
+		Amount:    req.Amount,
+		Date:      date,
+		AccountID: loan.AccountID,
+		CreatedAt: time.Now(),
+	}
+
+	if payment.Kind == "" {
+		payment.Kind = "regular"
+	}
+
+	maxAmount := loan.RemainingPrincipal
+	if payment.Kind == "early" {
+		if payment.Strategy == "" {
+			payment.Strategy = "shorten_term"
+		}
+	} else {
+		payment.Strategy = ""
+		maxAmount += roundMoney(loan.RemainingPrincipal * monthlyRate(loan.AnnualRate))
+	}
+
+	if roundMoney(payment.Amount) > roundMoney(maxAmount) {
+		return nil, fmt.Errorf("payment exceeds remaining principal")
+	}
+
+	if req.AccountID != "" {
+		if err := s.verifyAccount(ctx, scope, req.AccountID); err != nil {
+			return nil, err
+		}
+		payment.AccountID = &req.AccountID
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO loan_payments (id, loan_id, user_id, kind, strategy, amount, date, account_id, created_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		payment.ID, payment.LoanID, userID, payment.Kind, payment.Strategy,
+		payment.Amount, payment.Date, payment.AccountID, payment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loan payment: %w", err)
+	}
+
+	if payment.AccountID != nil {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE accounts SET balance = balance - $1 WHERE id = $2`,
+			payment.Amount, *payment.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update account balance: %w", err)
+		}
+	}
+
+	// Replay the schedule to report how this payment was split
+	payments := append(loan.Payments, payment)
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].Date.Before(payments[j].Date)
+	})
+	amortize(loan, payments)
+
+	logDetails := map[string]interface{}{
+		"action": "payment_added",
+		"data": map[string]interface{}{
+			"payment_id": payment.ID,
+			"kind":       payment.Kind,
+			"strategy":   payment.Strategy,
+			"amount":     payment.Amount,
+			"interest":   payment.Interest,
+			"principal":  payment.PrincipalPart,
+			"date":       payment.Date.Format("2006-01-02"),
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "loan",
+		EntityID: loanID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	scopes, err := statsScopes(ctx, tx, stringValue(payment.AccountID))
+	if err != nil {
+		return nil, err
+	}
+	// Loans count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return payment, nil
+}
+
+func (s *LoanService) DeletePayment(ctx context.Context, userID, loanID, paymentID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var amount float64
+	var accountID sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM loan_payments WHERE id = $1 AND loan_id = $2 AND user_id = $3
+		RETURNING amount, account_id`,
+		paymentID, loanID, userID).Scan(&amount, &accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("payment not found")
+		}
+		return fmt.Errorf("failed to delete loan payment: %w", err)
+	}
+
+	if accountID.Valid {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE accounts SET balance = balance + $1 WHERE id = $2`,
+			amount, accountID.String)
+		if err != nil {
+			return fmt.Errorf("failed to revert account balance: %w", err)
+		}
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "payment_deleted",
+		"data": map[string]interface{}{
+			"payment_id": paymentID,
+			"amount":     amount,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "loan",
+		EntityID: loanID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	scopes, err := statsScopes(ctx, tx, accountID.String)
+	if err != nil {
+		return err
+	}
+	// Loans count towards the personal summary
+	scopes = append(scopes, Scope{UserID: userID})
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	return nil
+}
+
+func (s *LoanService) getLoanWithSchedule(ctx context.Context, userID, loanID string) (*models.Loan, []*models.LoanScheduleEntry, error) {
+	row := s.db.QueryRowContext(ctx, loanSelect+` WHERE id = $1 AND user_id = $2`, loanID, userID)
+	loan, err := scanLoan(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("loan not found")
+		}
+		return nil, nil, err
+	}
+
+	payments, err := getLoanPayments(ctx, s.db, userID, loanID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schedule := amortize(loan, payments[loanID])
+	loan.Payments = payments[loanID]
+	if loan.Payments == nil {
+		loan.Payments = []*models.LoanPayment{}
+	}
+
+	return loan, schedule, nil
+}
+
+// verifyAccount checks the account is part of the active space and the user
+// may pay from it.
+func (s *LoanService) verifyAccount(ctx context.Context, scope Scope, accountID string) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND id IN `+scope.accounts("$2")+`)`,
+		accountID, scope.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify account: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+	_, err = requireAccountRole(ctx, s.db, scope.UserID, accountID, "editor")
+	return err
+}
+
+const loanSelect = `
+        SELECT id, user_id, name, principal, annual_rate, term_months, payment_type,
+            start_date, account_id, created_at, updated_at
+        FROM loans`
+
+func scanLoan(row rowScanner) (*models.Loan, error) {
+	var l models.Loan
+	var accountID sql.NullString
+	err := row.Scan(&l.ID, &l.UserID, &l.Name, &l.Principal, &l.AnnualRate, &l.TermMonths,
+		&l.PaymentType, &l.StartDate, &accountID, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan loan: %w", err)
+	}
+
+	if accountID.Valid {
+		l.AccountID = &accountID.String
+	}
+
+	return &l, nil
+}
+
+// getLoans loads all loans of a user with their calculated fields.
+func getLoans(ctx context.Context, db *sql.DB, userID string) ([]*models.Loan, error) {
+	rows, err := db.QueryContext(ctx, loanSelect+` WHERE user_id = $1 ORDER BY start_date, created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loans: %w", err)
+	}
+	defer rows.Close()
+
+	loans := []*models.Loan{}
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, loan)
+	}
+	rows.Close()
+
+	payments, err := getLoanPayments(ctx, db, userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, loan := range loans {
+		amortize(loan, payments[loan.ID])
+	}
+
+	return loans, nil
+}
+
+// getLoanPayments returns recorded payments grouped by loan, in the order
+// they are applied. An empty loanID loads payments of all the user's loans.
+func getLoanPayments(ctx context.Context, db *sql.DB, userID, loanID string) (map[string][]*models.LoanPayment, error) {
+	query := `SELECT id, loan_id, kind, COALESCE(strategy, ''), amount, date, account_id, created_at
+		FROM loan_payments WHERE user_id = $1`
+	args := []interface{}{userID}
+	if loanID != "" {
+		query += ` AND loan_id = $2`
+		args = append(args, loanID)
+	}
+	query += ` ORDER BY date, created_at`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loan payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := make(map[string][]*models.LoanPayment)
+	for rows.Next() {
+		var p models.LoanPayment
+		var accountID sql.NullString
+		if err := rows.Scan(&p.ID, &p.LoanID, &p.Kind, &p.Strategy, &p.Amount, &p.Date, &accountID, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan loan payment: %w", err)
+		}
+		if accountID.Valid {
+			p.AccountID = &accountID.String
+		}
+		payments[p.LoanID] = append(payments[p.LoanID], &p)
+	}
+
+	return payments, nil
+}
+
+func monthlyRate(annualRate float64) float64 {
+	return annualRate / 12 / 100
+}
+
+// annuityPayment is the fixed monthly payment that repays balance over
+// months at monthly rate r.
+func annuityPayment(balance, r float64, months int) float64 {
+	if months <= 0 {
+		return balance
+	}
+	if r == 0 {
+		return roundMoney(balance / float64(months))
+	}
+	return roundMoney(balance * r / (1 - math.Pow(1+r, -float64(months))))
+}
+
+// annuityTerm is how many months a fixed payment needs to repay balance.
+func annuityTerm(balance, r, payment float64) int {
+	if payment <= 0 {
+		return 0
+	}
+	if r == 0 {
+		return int(math.Ceil(balance/payment - 1e-9))
+	}
+	x := 1 - balance*r/payment
+	if x <= 0 {
+		// The payment no longer covers interest; the term can't shrink
+		return -1
+	}
+	return int(math.Ceil(-math.Log(x)/math.Log(1+r) - 1e-9))
+}
+
+// addMonths moves date forward by months, clamping to the end of shorter
+// months so a loan started on the 31st is due on the 30th or 28th.
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// amortize replays the recorded payments against the loan terms, fills the
+// loan's calculated fields and the split of every payment, and returns the
+// full schedule: recorded payments followed by the projected ones.
+//
+// A regular payment pays the month's interest on the remaining principal
+// first and the rest goes to principal. An early payment goes entirely to
+// principal and then either keeps the payment and shortens the term or keeps
+// the term and lowers the payment.
+func amortize(loan *models.Loan, payments []*models.LoanPayment) []*models.LoanScheduleEntry {
+	r := monthlyRate(loan.AnnualRate)
+	balance := loan.Principal
+	remaining := loan.TermMonths
+	payment := annuityPayment(balance, r, remaining)
+	principalPart := balance / float64(remaining)
+	number := 0
+
+	schedule := []*models.LoanScheduleEntry{}
+	loan.InterestPaid = 0
+	loan.PrincipalPaid = 0
+	loan.PaymentsMade = 0
+
+	for _, p := range payments {
+		interest := 0.0
+		principal := 0.0
+		status := "early"
+
+		if p.Kind == "early" {
+			principal = math.Min(p.Amount, balance)
+		} else {
+			status = "paid"
+			number++
+			if remaining > 0 {
+				remaining--
+			}
+			interest = math.Min(roundMoney(balance*r), p.Amount)
+			principal = math.Min(roundMoney(p.Amount-interest), balance)
+			loan.PaymentsMade++
+		}
+
+		balance = roundMoney(balance - principal)
+		loan.InterestPaid += interest
+		loan.PrincipalPaid += principal
+
+		if p.Kind == "early" && balance > 0 && remaining > 0 {
+			if p.Strategy == "reduce_payment" {
+				payment = annuityPayment(balance, r, remaining)
+				principalPart = balance / float64(remaining)
+			} else if loan.PaymentType == "annuity" {
+				if term := annuityTerm(balance, r, payment); term >= 0 {
+					remaining = term
+				}
+			} else {
+				remaining = int(math.Ceil(balance/principalPart - 1e-9))
+			}
+		}
+
+		p.Interest = interest
+		p.PrincipalPart = principal
+		p.RemainingPrincipal = balance
+
+		schedule = append(schedule, &models.LoanScheduleEntry{
+			Number:             number,
+			Date:               p.Date,
+			Payment:            p.Amount,
+			Principal:          principal,
+			Interest:           interest,
+			RemainingPrincipal: balance,
+			Status:             status,
+		})
+	}
+
+	loan.RemainingPrincipal = balance
+	loan.InterestPaid = roundMoney(loan.InterestPaid)
+	loan.PrincipalPaid = roundMoney(loan.PrincipalPaid)
+
+	// Regular payments made beyond the original term still leave a balance;
+	// spread it over one more month rather than dropping it.
+	if balance > 0 && remaining == 0 {
+		remaining = 1
+	}
+
+	projectedInterest := 0.0
+	scheduled := 0
+	loan.NextPayment = 0
+	loan.NextPaymentDate = nil
+	loan.ProjectedPayoffDate = nil
+
+	for i := 1; i <= remaining && balance > 0; i++ {
+		number++
+		interest := roundMoney(balance * r)
+
+		var principal float64
+		if loan.PaymentType == "annuity" {
+			principal = roundMoney(payment - interest)
+		} else {
+			principal = roundMoney(principalPart)
+		}
+		if i == remaining || principal >= balance {
+			principal = balance
+		}
+		if principal < 0 {
+			principal = 0
+		}
+
+		balance = roundMoney(balance - principal)
+		projectedInterest += interest
+		scheduled++
+
+		entry := &models.LoanScheduleEntry{
+			Number:             number,
+			Date:               addMonths(loan.StartDate, number),
+			Payment:            roundMoney(principal + interest),
+			Principal:          principal,
+			Interest:           interest,
+			RemainingPrincipal: balance,
+			Status:             "scheduled",
+		}
+		schedule = append(schedule, entry)
+
+		if scheduled == 1 {
+			loan.NextPayment = entry.Payment
+			loan.NextPaymentDate = &entry.Date
+		}
+		loan.ProjectedPayoffDate = &entry.Date
+	}
+
+	loan.PaymentsRemaining = scheduled
+	loan.ProjectedInterest = roundMoney(loan.InterestPaid + projectedInterest)
+
+	return schedule
+}
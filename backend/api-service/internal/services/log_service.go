@@ -3,114 +3,475 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api-service/internal/config"
+	"api-service/internal/models"
+	"api-service/pkg/auditctx"
+
+	"github.com/lib/pq"
 )
 
+const (
+	auditWriteAttempts = 3
+	auditWriteTimeout  = 10 * time.Second
+)
+
+// LogService writes the audit log. Entries that belong to a data change go
+// through the outbox (Record); entries other services deliver are written
+// before they are acknowledged (Write); the rest are queued in memory and
+// written in batches by a single flusher (Log), so bursts don't take a
+// connection per entry.
 type LogService struct {
 	db *sql.DB
+
+	queue         chan *UserAction
+	flushSize     int
+	flushInterval time.Duration
+	dropOnFull    bool
+
+	mu     sync.RWMutex // guards closed against sends on a closed queue
+	closed bool
+	done   chan struct{}
+
+	enqueued atomic.Int64
+	written  atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
 }
 
-func NewLogService(db *sql.DB) *LogService {
-	return &LogService{db: db}
+func NewLogService(db *sql.DB, cfg *config.Config) *LogService {
+	s := &LogService{
+		db:            db,
+		queue:         make(chan *UserAction, max(cfg.AuditQueueSize, 1)),
+		flushSize:     max(cfg.AuditFlushSize, 1),
+		flushInterval: cfg.AuditFlushInterval,
+		dropOnFull:    cfg.AuditOverflow == "drop",
+		done:          make(chan struct{}),
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = 500 * time.Millisecond
+	}
+
+	go s.flusher()
+	return s
 }
 
 type UserAction struct {
-	UserID    string
-	Action    string // create, update, delete, view
-	Entity    string // transaction, account, category
-	EntityID  string
-	Details   string
-	IP        string
-	UserAgent string
+	UserID    string    `json:"user_id"`
+	Action    string    `json:"action"` // create, update, delete, view
+	Entity    string    `json:"entity"` // transaction, account, category
+	EntityID  string    `json:"entity_id,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"` // client-supplied device or session ID
+	CreatedAt time.Time `json:"created_at"`
+
+	outboxID       int64  // set for entries delivered from the outbox
+	idempotencyKey string // set for entries delivered by another service
 }
 
+// Record stores the action in the outbox using q. Pass the transaction that
+// makes the change so the audit entry is committed or rolled back with it;
+// OutboxRelay copies it to user_actions afterwards.
+func (s *LogService) Record(ctx context.Context, q execer, action *UserAction) error {
+	withRequest(ctx, action)
+
+	aggregateID := action.EntityID
+	if aggregateID == "" {
+		aggregateID = action.UserID
+	}
+
+	return enqueue(ctx, q, outboxAudit, action.Entity, aggregateID, action)
+}
+
+// Log queues an action that is not part of a data change, such as a read of
+// someone else's data or an entry sent by another service. When the queue is
+// full it waits for room until ctx is done, or drops the entry if the writer
+// is configured to.
 func (s *LogService) Log(ctx context.Context, action *UserAction) error {
-	query := `
-		INSERT INTO user_actions (user_id, action, entity, entity_id, details, ip, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
+	withRequest(ctx, action)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return fmt.Errorf("audit log is closed")
+	}
+
+	select {
+	case s.queue <- action:
+		s.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	if s.dropOnFull {
+		s.dropped.Add(1)
+		return fmt.Errorf("audit log queue is full")
+	}
+
+	select {
+	case s.queue <- action:
+		s.enqueued.Add(1)
+		return nil
+	case <-ctx.Done():
+		s.dropped.Add(1)
+		return fmt.Errorf("audit log queue is full: %w", ctx.Err())
+	}
+}
+
+// Write stores an action delivered by another service before returning, so
+// the sender can take a success as delivered. key identifies the entry at
+// the sender, which may deliver it more than once; an entry already written
+// under the same key is not stored again.
+func (s *LogService) Write(ctx context.Context, key string, action *UserAction) error {
+	withRequest(ctx, action)
+	action.idempotencyKey = key
+
+	return s.writeActions(ctx, []*UserAction{action})
+}
+
+// withRequest fills the fields the caller left empty from the request
+// details AuditContextMiddleware put into ctx.
+func withRequest(ctx context.Context, action *UserAction) {
+	info := auditctx.FromContext(ctx)
+	if action.IP == "" {
+		action.IP = info.IP
+	}
+	if action.UserAgent == "" {
+		action.UserAgent = info.UserAgent
+	}
+	if action.RequestID == "" {
+		action.RequestID = info.RequestID
+	}
+	if action.SessionID == "" {
+		action.SessionID = info.SessionID
+	}
+	if action.CreatedAt.IsZero() {
+		action.CreatedAt = time.Now()
+	}
+}
+
+// Close stops accepting entries and waits until the queued ones are
+// written or ctx is done.
+func (s *LogService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit log not drained, %d entries left: %w", len(s.queue), ctx.Err())
+	}
+}
+
+// GetWriterStats reports the queue and its counters since start.
+func (s *LogService) GetWriterStats() *models.AuditWriterStats {
+	return &models.AuditWriterStats{
+		Queued:   len(s.queue),
+		Capacity: cap(s.queue),
+		Enqueued: s.enqueued.Load(),
+		Written:  s.written.Load(),
+		Dropped:  s.dropped.Load(),
+		Failed:   s.failed.Load(),
+	}
+}
+
+func (s *LogService) flusher() {
+	defer close(s.done)
 
-	_, err := s.db.ExecContext(ctx, query,
-		action.UserID,
-		action.Action,
-		action.Entity,
-		action.EntityID,
-		action.Details,
-		action.IP,
-		action.UserAgent,
-	)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*UserAction, 0, s.flushSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.writeWithRetry(batch); err != nil {
+			log.Printf("Failed to write %d audit entries, writing them one by one: %v", len(batch), err)
+			s.writeEach(batch)
+		} else {
+			s.written.Add(int64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case action, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, action)
+			if len(batch) >= s.flushSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *LogService) writeWithRetry(batch []*UserAction) error {
+	var err error
+	backoff := 200 * time.Millisecond
+	for attempt := 1; attempt <= auditWriteAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+		err = s.writeActions(ctx, batch)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt < auditWriteAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// writeEach writes the actions one at a time after their batch failed, so
+// an entry that can't be stored, such as one of a user that doesn't exist,
+// is the only one dropped.
+func (s *LogService) writeEach(batch []*UserAction) {
+	for _, action := range batch {
+		ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+		err := s.writeActions(ctx, []*UserAction{action})
+		cancel()
+		if err != nil {
+			s.failed.Add(1)
+			log.Printf("Dropped audit entry (user %s, %s %s %s, request %s): %v",
+				action.UserID, action.Action, action.Entity, action.EntityID, action.RequestID, err)
+			continue
+		}
+		s.written.Add(1)
+	}
+}
+
+// writeActions inserts the actions with one statement, appending each to
+// its user's hash chain. Entries delivered from an outbox twice are stored
+// once.
+func (s *LogService) writeActions(ctx context.Context, actions []*UserAction) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	actions, err = skipWritten(ctx, tx, actions)
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		return nil
+	}
 
+	userIDs := []string{}
+	seen := map[string]bool{}
+	for _, action := range actions {
+		userID := canonicalUUID(action.UserID)
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	heads, err := lockChainHeads(ctx, tx, userIDs)
 	if err != nil {
-		// Не возвращаем ошибку — логирование не должно ломать основной флоу
-		fmt.Printf("Failed to log action: %v\n", err)
+		return err
+	}
+
+	const columns = 15
+	values := make([]string, 0, len(actions))
+	args := make([]interface{}, 0, len(actions)*columns)
+	for i, action := range actions {
+		n := i * columns
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, NULLIF($%d, '')::uuid, $%d::jsonb, NULLIF($%d, ''), NULLIF($%d, ''), NULLIF($%d, ''), NULLIF($%d, ''), $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13, n+14, n+15))
+
+		createdAt := action.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		// Postgres keeps microseconds; the hash must see the stored time
+		createdAt = createdAt.UTC().Truncate(time.Microsecond)
+
+		var outboxID interface{}
+		if action.outboxID != 0 {
+			outboxID = action.outboxID
+		}
+
+		details, err := canonicalDetails(action.Details)
+		if err != nil {
+			return err
+		}
+
+		content := &chainContent{
+			UserID:    canonicalUUID(action.UserID),
+			Action:    action.Action,
+			Entity:    action.Entity,
+			EntityID:  canonicalUUID(action.EntityID),
+			Details:   details,
+			IP:        action.IP,
+			UserAgent: action.UserAgent,
+			RequestID: action.RequestID,
+			SessionID: action.SessionID,
+			CreatedAt: createdAt.Format(chainTimeFormat),
+		}
+		head, ok := heads[content.UserID]
+		if !ok {
+			return fmt.Errorf("no audit chain head for user %s", content.UserID)
+		}
+		content.Seq = head.seq + 1
+		content.PrevHash = head.hash
+		hash, err := content.hash()
+		if err != nil {
+			return err
+		}
+		head.seq = content.Seq
+		head.hash = hash
+
+		args = append(args,
+			content.UserID,
+			content.Action,
+			content.Entity,
+			content.EntityID,
+			string(content.Details),
+			content.IP,
+			content.UserAgent,
+			content.RequestID,
+			content.SessionID,
+			createdAt,
+			outboxID,
+			action.idempotencyKey,
+			content.Seq,
+			content.PrevHash,
+			hash,
+		)
+	}
+
+	query := `INSERT INTO user_actions (user_id, action, entity, entity_id, details, ip, user_agent, request_id, session_id, created_at, outbox_id, idempotency_key, chain_seq, prev_hash, hash)
+		VALUES ` + strings.Join(values, ", ")
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to write audit entries: %w", err)
+	}
+
+	for userID, head := range heads {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE audit_chain_heads SET seq = $1, hash = $2, updated_at = NOW() WHERE user_id = $3`,
+			head.seq, head.hash, userID); err != nil {
+			return fmt.Errorf("failed to update audit chain head: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit entries: %w", err)
 	}
 
 	return nil
 }
 
-func (s *LogService) GetUserLogs(ctx context.Context, userID string, limit, offset int) ([]map[string]interface{}, error) {
-	query := `
-		SELECT 
-			id,
-			user_id,
-			action,
-			entity,
-			entity_id,
-			details,
-			ip,
-			user_agent,
-			created_at
-		FROM user_actions
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+// skipWritten drops actions delivered from an outbox, ours or another
+// service's, that are already in user_actions. A duplicate has to be
+// skipped rather than ignored on insert, since it would take a place in the
+// hash chain.
+func skipWritten(ctx context.Context, tx *sql.Tx, actions []*UserAction) ([]*UserAction, error) {
+	outboxIDs := []int64{}
+	keys := []string{}
+	for _, action := range actions {
+		if action.outboxID != 0 {
+			outboxIDs = append(outboxIDs, action.outboxID)
+		}
+		if action.idempotencyKey != "" {
+			keys = append(keys, action.idempotencyKey)
+		}
+	}
+	if len(outboxIDs) == 0 && len(keys) == 0 {
+		return actions, nil
+	}
 
-	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT outbox_id, idempotency_key FROM user_actions
+		WHERE outbox_id = ANY($1) OR idempotency_key = ANY($2)`,
+		pq.Array(outboxIDs), pq.Array(keys))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query logs: %w", err)
+		return nil, fmt.Errorf("failed to check written audit entries: %w", err)
 	}
 	defer rows.Close()
 
-	logs := []map[string]interface{}{}
+	writtenIDs := map[int64]bool{}
+	writtenKeys := map[string]bool{}
 	for rows.Next() {
-		var (
-			id, userID, action, entity, entityID, details, ip, userAgent string
-			createdAt                                                    interface{}
-		)
-
-		err := rows.Scan(&id, &userID, &action, &entity, &entityID, &details, &ip, &userAgent, &createdAt)
-		if err != nil {
-			return nil, err
+		var id sql.NullInt64
+		var key sql.NullString
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, fmt.Errorf("failed to scan written audit entry: %w", err)
+		}
+		if id.Valid {
+			writtenIDs[id.Int64] = true
+		}
+		if key.Valid {
+			writtenKeys[key.String] = true
 		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check written audit entries: %w", err)
+	}
 
-		logs = append(logs, map[string]interface{}{
-			"id":         id,
-			"user_id":    userID,
-			"action":     action,
-			"entity":     entity,
-			"entity_id":  entityID,
-			"details":    details,
-			"ip":         ip,
-			"user_agent": userAgent,
-			"created_at": createdAt,
-		})
+	pending := make([]*UserAction, 0, len(actions))
+	for _, action := range actions {
+		if (action.outboxID != 0 && writtenIDs[action.outboxID]) ||
+			(action.idempotencyKey != "" && writtenKeys[action.idempotencyKey]) {
+			continue
+		}
+		pending = append(pending, action)
 	}
+	return pending, nil
+}
 
-	return logs, nil
+// GetUserLogs returns the user's own entries matching filter, newest first.
+// filter.UserID is ignored.
+func (s *LogService) GetUserLogs(ctx context.Context, userID string, filter *models.AuditLogFilter, limit, offset int) ([]*models.AuditLogEntry, error) {
+	own := *filter
+	own.UserID = userID
+	return s.queryLogs(ctx, &own, limit, offset)
 }
 
-func (s *LogService) GetAllLogs(ctx context.Context, limit, offset int, filters map[string]string) ([]map[string]interface{}, error) {
+// GetAllLogs returns entries of all users matching filter, newest first.
+func (s *LogService) GetAllLogs(ctx context.Context, filter *models.AuditLogFilter, limit, offset int) ([]*models.AuditLogEntry, error) {
+	return s.queryLogs(ctx, filter, limit, offset)
+}
+
+func (s *LogService) queryLogs(ctx context.Context, filter *models.AuditLogFilter, limit, offset int) ([]*models.AuditLogEntry, error) {
 	query := `
 		SELECT 
 			ua.id,
 			ua.user_id,
-			u.email,
+			COALESCE(u.email, ''),
 			ua.action,
 			ua.entity,
-			ua.entity_id,
-			ua.details,
-			ua.ip,
+			COALESCE(ua.entity_id::text, ''),
+			COALESCE(ua.details::text, 'null'),
+			COALESCE(ua.ip, ''),
+			COALESCE(ua.user_agent, ''),
+			COALESCE(ua.request_id, ''),
 			ua.created_at
 		FROM user_actions ua
 		LEFT JOIN users u ON ua.user_id = u.id
@@ -120,22 +481,49 @@ func (s *LogService) GetAllLogs(ctx context.Context, limit, offset int, filters
 	args := []interface{}{}
 	argCount := 1
 
-	if action := filters["action"]; action != "" {
-		query += fmt.Sprintf(" AND ua.action = $%d", argCount)
-		args = append(args, action)
+	where := func(condition string, arg interface{}) {
+		query += " AND " + strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", argCount))
+		args = append(args, arg)
 		argCount++
 	}
 
-	if entity := filters["entity"]; entity != "" {
-		query += fmt.Sprintf(" AND ua.entity = $%d", argCount)
-		args = append(args, entity)
-		argCount++
+	if filter.UserID != "" {
+		where("ua.user_id = $?", filter.UserID)
 	}
-
-	if userID := filters["user_id"]; userID != "" {
-		query += fmt.Sprintf(" AND ua.user_id = $%d", argCount)
-		args = append(args, userID)
-		argCount++
+	if filter.Action != "" {
+		where("ua.action = $?", filter.Action)
+	}
+	if filter.Entity != "" {
+		where("ua.entity = $?", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		where("ua.entity_id = $?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		where("ua.request_id = $?", filter.RequestID)
+	}
+	if filter.From != nil {
+		where("ua.created_at >= $?", *filter.From)
+	}
+	if filter.To != nil {
+		where("ua.created_at <= $?", *filter.To)
+	}
+	if filter.Path != "" {
+		where("ua.details @@ $?::jsonpath", filter.Path)
+	}
+	if filter.Field != "" {
+		if filter.MinChange == nil {
+			where("ua.details->'changes' ? $?::text", filter.Field)
+		} else {
+			// CASE keeps the numeric casts away from non-numeric values
+			query += fmt.Sprintf(` AND CASE
+				WHEN jsonb_typeof(ua.details->'changes'->$%[1]d::text->'old') = 'number'
+					AND jsonb_typeof(ua.details->'changes'->$%[1]d::text->'new') = 'number'
+				THEN ABS((ua.details->'changes'->$%[1]d::text->>'new')::numeric - (ua.details->'changes'->$%[1]d::text->>'old')::numeric)
+				END >= $%[2]d`, argCount, argCount+1)
+			args = append(args, filter.Field, *filter.MinChange)
+			argCount += 2
+		}
 	}
 
 	query += fmt.Sprintf(" ORDER BY ua.created_at DESC LIMIT $%d OFFSET $%d", argCount, argCount+1)
@@ -143,33 +531,32 @@ func (s *LogService) GetAllLogs(ctx context.Context, limit, offset int, filters
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query all logs: %w", err)
+		// 42601 and 22P02: the path or the entity ID doesn't parse
+		if pqErr, ok := err.(*pq.Error); ok && (pqErr.Code == "42601" || pqErr.Code == "22P02") {
+			return nil, fmt.Errorf("invalid filter: %s", pqErr.Message)
+		}
+		return nil, fmt.Errorf("failed to query logs: %w", err)
 	}
 	defer rows.Close()
 
-	logs := []map[string]interface{}{}
+	logs := []*models.AuditLogEntry{}
 	for rows.Next() {
 		var (
-			id, userID, email, action, entity, entityID, details, ip string
-			createdAt                                                interface{}
+			e       models.AuditLogEntry
+			details string
 		)
 
-		err := rows.Scan(&id, &userID, &email, &action, &entity, &entityID, &details, &ip, &createdAt)
+		err := rows.Scan(&e.ID, &e.UserID, &e.Email, &e.Action, &e.Entity, &e.EntityID, &details,
+			&e.IP, &e.UserAgent, &e.RequestID, &e.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		e.Details = json.RawMessage(details)
 
-		logs = append(logs, map[string]interface{}{
-			"id":         id,
-			"user_id":    userID,
-			"email":      email,
-			"action":     action,
-			"entity":     entity,
-			"entity_id":  entityID,
-			"details":    details,
-			"ip":         ip,
-			"created_at": createdAt,
-		})
+		logs = append(logs, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
 	}
 
 	return logs, nil
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"api-service/internal/models"
+	"api-service/pkg/events"
+
+	"github.com/lib/pq"
+)
+
+// Outbox entry kinds
+const (
+	outboxAudit = "audit" // delivered to user_actions
+	outboxEvent = "event" // delivered to Redis Streams
+)
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func enqueue(ctx context.Context, q execer, kind, aggregateType, aggregateID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO outbox (kind, aggregate_type, aggregate_id, payload) VALUES ($1, $2, $3, $4)`,
+		kind, aggregateType, aggregateID, string(body))
+	if err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// enqueueEvent stores a domain event in the outbox using q, normally the
+// transaction making the change the event describes.
+func enqueueEvent(ctx context.Context, q execer, publisher *events.Publisher, eventType, userID, aggregateID string, data interface{}) error {
+	event, err := publisher.NewEvent(eventType, userID, data)
+	if err != nil {
+		return err
+	}
+
+	return enqueue(ctx, q, outboxEvent, events.Stream(eventType), aggregateID, event)
+}
+
+const (
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 15
+	outboxMaxBackoff  = 5 * time.Minute
+	outboxRetention   = 7 * 24 * time.Hour
+
+	// Only one relay delivers at a time so entries keep their order
+	outboxLockKey = 7291001
+)
+
+// OutboxRelay delivers outbox entries at least once, in order per
+// aggregate: an entry waiting for a retry holds back later entries of the
+// same aggregate.
+type OutboxRelay struct {
+	db         *sql.DB
+	logService *LogService
+	publisher  *events.Publisher
+	interval   time.Duration
+}
+
+func NewOutboxRelay(db *sql.DB, logService *LogService, publisher *events.Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		db:         db,
+		logService: logService,
+		publisher:  publisher,
+		interval:   time.Second,
+	}
+}
+
+// Run delivers entries until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		for {
+			delivered, err := r.relayBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Outbox relay failed: %v", err)
+			}
+			// Keep going while there is a backlog
+			if err != nil || delivered < outboxBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type outboxEntry struct {
+	id            int64
+	kind          string
+	aggregateType string
+	aggregateID   string
+	payload       []byte
+	attempts      int
+	due           bool
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, outboxLockKey)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, kind, aggregate_type, aggregate_id, payload, attempts, next_attempt_at <= NOW()
+		FROM outbox
+		WHERE delivered_at IS NULL AND failed_at IS NULL
+		ORDER BY id
+		LIMIT $1`,
+		outboxBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	entries := []*outboxEntry{}
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.id, &e.kind, &e.aggregateType, &e.aggregateID, &e.payload, &e.attempts, &e.due); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	rows.Close()
+
+	delivered := 0
+	blocked := map[string]bool{}
+	audits := []*outboxEntry{}
+	actions := []*UserAction{}
+	for _, e := range entries {
+		aggregate := e.aggregateType + ":" + e.aggregateID
+		if blocked[aggregate] {
+			continue
+		}
+		if !e.due {
+			blocked[aggregate] = true
+			continue
+		}
+
+		// Audit entries are written with one insert after the loop
+		if e.kind == outboxAudit {
+			var action UserAction
+			if err := json.Unmarshal(e.payload, &action); err != nil {
+				blocked[aggregate] = true
+				r.markFailed(ctx, e, fmt.Errorf("failed to decode audit entry: %w", err))
+				continue
+			}
+			action.outboxID = e.id
+			audits = append(audits, e)
+			actions = append(actions, &action)
+			continue
+		}
+
+		if err := r.deliver(ctx, e); err != nil {
+			blocked[aggregate] = true
+			r.markFailed(ctx, e, err)
+			continue
+		}
+
+		if err := r.markDelivered(ctx, e.id); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	if len(audits) > 0 {
+		if err := r.logService.writeActions(ctx, actions); err != nil {
+			log.Printf("Failed to write %d outbox audit entries, writing them one by one: %v", len(audits), err)
+			n, err := r.writeAuditsEach(ctx, audits, actions)
+			return delivered + n, err
+		}
+
+		ids := make([]int64, len(audits))
+		for i, e := range audits {
+			ids[i] = e.id
+		}
+		if err := r.markDelivered(ctx, ids...); err != nil {
+			return delivered, err
+		}
+		delivered += len(audits)
+	}
+
+	return delivered, nil
+}
+
+// writeAuditsEach writes audit entries one at a time after their batch
+// failed, so only the entries that fail on their own are retried, and hold
+// back the rest of their aggregate.
+func (r *OutboxRelay) writeAuditsEach(ctx context.Context, audits []*outboxEntry, actions []*UserAction) (int, error) {
+	delivered := 0
+	blocked := map[string]bool{}
+	for i, e := range audits {
+		aggregate := e.aggregateType + ":" + e.aggregateID
+		if blocked[aggregate] {
+			continue
+		}
+
+		if err := r.logService.writeActions(ctx, actions[i:i+1]); err != nil {
+			blocked[aggregate] = true
+			r.markFailed(ctx, e, err)
+			continue
+		}
+
+		if err := r.markDelivered(ctx, e.id); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func (r *OutboxRelay) markDelivered(ctx context.Context, ids ...int64) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE outbox SET delivered_at = NOW() WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to mark outbox entries delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRelay) deliver(ctx context.Context, e *outboxEntry) error {
+	switch e.kind {
+	case outboxEvent:
+		var event events.Event
+		if err := json.Unmarshal(e.payload, &event); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		return r.publisher.PublishEvent(ctx, &event)
+	default:
+		return fmt.Errorf("unknown outbox entry kind %q", e.kind)
+	}
+}
+
+// markFailed schedules a retry with exponential backoff, or gives up on the
+// entry after outboxMaxAttempts so it stops holding back its aggregate.
+func (r *OutboxRelay) markFailed(ctx context.Context, e *outboxEntry, cause error) {
+	attempts := e.attempts + 1
+	backoff := time.Duration(1<<uint(min(attempts, 16))) * time.Second
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+
+	var err error
+	if attempts >= outboxMaxAttempts {
+		log.Printf("Giving up on outbox entry %d (%s %s:%s) after %d attempts: %v",
+			e.id, e.kind, e.aggregateType, e.aggregateID, attempts, cause)
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE outbox SET attempts = $1, last_error = $2, failed_at = NOW() WHERE id = $3`,
+			attempts, cause.Error(), e.id)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE outbox SET attempts = $1, last_error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second' WHERE id = $4`,
+			attempts, cause.Error(), int(backoff.Seconds()), e.id)
+	}
+	if err != nil {
+		log.Printf("Failed to record outbox failure for entry %d: %v", e.id, err)
+	}
+}
+
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM outbox WHERE delivered_at < NOW() - $1 * INTERVAL '1 second'`,
+		int(outboxRetention.Seconds()))
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Failed to clean up outbox: %v", err)
+	}
+}
+
+// GetStats reports the relay's backlog and lag.
+func (r *OutboxRelay) GetStats(ctx context.Context) (*models.OutboxStats, error) {
+	var stats models.OutboxStats
+	err := r.db.QueryRowContext(ctx,
+		`SELECT
+			COUNT(*) FILTER (WHERE delivered_at IS NULL AND failed_at IS NULL),
+			COUNT(*) FILTER (WHERE delivered_at IS NULL AND failed_at IS NULL AND attempts > 0),
+			COUNT(*) FILTER (WHERE failed_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE delivered_at >= NOW() - INTERVAL '1 hour'),
+			COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at) FILTER (WHERE delivered_at IS NULL AND failed_at IS NULL)), 0),
+			COALESCE(EXTRACT(EPOCH FROM AVG(delivered_at - created_at) FILTER (WHERE delivered_at >= NOW() - INTERVAL '1 hour')), 0)
+		FROM outbox`).Scan(&stats.Pending, &stats.Retrying, &stats.Failed, &stats.DeliveredLastHour,
+		&stats.LagSeconds, &stats.AvgDeliverySeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outbox stats: %w", err)
+	}
+
+	return &stats, nil
+}
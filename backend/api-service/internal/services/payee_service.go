@@ -0,0 +1,559 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// payeeNoiseWords are dropped while normalising descriptions: cities, country
+// codes and legal forms that bank statements append to merchant names.
+var payeeNoiseWords = map[string]bool{
+	"moskva": true, "moscow": true, "msk": true, "spb": true, "sankt": true,
+	"peterburg": true, "rus": true, "ru": true, "russia": true, "rossiya": true,
+	"ooo": true, "oao": true, "zao": true, "pao": true, "ip": true,
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+type PayeeService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewPayeeService(db *sql.DB, logService *LogService) *PayeeService {
+	return &PayeeService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+// NormalizePayee reduces a free-text description to a comparable key:
+// lowercased, transliterated to Latin, without numbers and noise words.
+// "PYATEROCHKA 1234 MOSKVA" and "Пятерочка" both become "pyaterochka".
+func NormalizePayee(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+
+	tokens := []string{}
+	for _, w := range strings.Fields(b.String()) {
+		if len(w) < 2 || isNumeric(w) || payeeNoiseWords[w] {
+			continue
+		}
+		tokens = append(tokens, w)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// payeeDisplayName keeps the original words of a description that survive
+// normalisation, so "PYATEROCHKA 1234 MOSKVA" is shown as "PYATEROCHKA".
+func payeeDisplayName(text string) string {
+	words := []string{}
+	for _, w := range strings.Fields(text) {
+		if NormalizePayee(w) != "" {
+			words = append(words, w)
+		}
+	}
+
+	name := strings.Join(words, " ")
+	if len([]rune(name)) > 100 {
+		name = string([]rune(name)[:100])
+	}
+	return name
+}
+
+// Resolve finds the payee for a transaction description using the aliases
+// of the space and creates a new payee there when nothing matches. It runs
+// inside the caller's database transaction and returns nil for empty
+// descriptions.
+func (s *PayeeService) Resolve(ctx context.Context, tx *sql.Tx, space Scope, description string) (*string, error) {
+	key := NormalizePayee(description)
+	if key == "" {
+		return nil, nil
+	}
+
+	var payeeID string
+	err := tx.QueryRowContext(ctx,
+		`SELECT payee_id FROM payee_aliases
+		WHERE `+space.owns("", "$1")+` AND (
+			(match_type = 'exact' AND pattern = $2) OR
+			(match_type = 'prefix' AND $2 LIKE pattern || '%') OR
+			(match_type = 'contains' AND $2 LIKE '%' || pattern || '%'))
+		ORDER BY (match_type = 'exact') DESC, LENGTH(pattern) DESC
+		LIMIT 1`,
+		space.arg(), key).Scan(&payeeID)
+	if err == nil {
+		return &payeeID, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to resolve payee: %w", err)
+	}
+
+	payeeID = uuid.New().String()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO payees (id, user_id, workspace_id, name) VALUES ($1, $2, $3, $4)`,
+		payeeID, space.UserID, space.workspace(), payeeDisplayName(description))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payee: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO payee_aliases (payee_id, user_id, workspace_id, pattern, match_type)
+		VALUES ($1, $2, $3, $4, 'prefix')
+		ON CONFLICT ((COALESCE(workspace_id, user_id)), pattern) DO NOTHING`,
+		payeeID, space.UserID, space.workspace(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payee alias: %w", err)
+	}
+
+	return &payeeID, nil
+}
+
+// VerifyPayee checks that an explicitly chosen payee belongs to the space.
+func (s *PayeeService) VerifyPayee(ctx context.Context, tx *sql.Tx, space Scope, payeeID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM payees WHERE id = $1 AND `+space.owns("", "$2")+`)`,
+		payeeID, space.arg()).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify payee: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("payee not found")
+	}
+	return nil
+}
+
+func (s *PayeeService) CreatePayee(ctx context.Context, scope Scope, req *models.CreatePayeeRequest) (*models.Payee, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	payee := &models.Payee{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: scope.workspace(),
+		Name:        req.Name,
+		Aliases:     []*models.PayeeAlias{},
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO payees (id, user_id, workspace_id, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		payee.ID, payee.UserID, payee.WorkspaceID, payee.Name, payee.CreatedAt, payee.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payee: %w", err)
+	}
+
+	patterns := append([]string{req.Name}, req.Aliases...)
+	for _, p := range patterns {
+		alias, err := s.addAlias(ctx, tx, scope, payee.ID, p, "prefix")
+		if err != nil {
+			return nil, err
+		}
+		if alias != nil {
+			payee.Aliases = append(payee.Aliases, alias)
+		}
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":      payee.ID,
+			"name":    payee.Name,
+			"aliases": req.Aliases,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "payee",
+		EntityID: payee.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return payee, nil
+}
+
+func (s *PayeeService) GetPayees(ctx context.Context, scope Scope) ([]*models.Payee, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, workspace_id, name, created_at, updated_at
+		FROM payees WHERE `+scope.owns("", "$1")+` ORDER BY name`,
+		scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payees: %w", err)
+	}
+	defer rows.Close()
+
+	payees := []*models.Payee{}
+	for rows.Next() {
+		var p models.Payee
+		if err := rows.Scan(&p.ID, &p.UserID, &p.WorkspaceID, &p.Name, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payee: %w", err)
+		}
+		payees = append(payees, &p)
+	}
+
+	return payees, nil
+}
+
+func (s *PayeeService) GetPayee(ctx context.Context, scope Scope, payeeID string) (*models.Payee, error) {
+	var p models.Payee
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, workspace_id, name, created_at, updated_at
+		FROM payees WHERE id = $1 AND `+scope.owns("", "$2"),
+		payeeID, scope.arg()).Scan(&p.ID, &p.UserID, &p.WorkspaceID, &p.Name, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payee not found")
+		}
+		return nil, fmt.Errorf("failed to get payee: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, payee_id, pattern, match_type, created_at
+		FROM payee_aliases WHERE payee_id = $1 ORDER BY created_at`,
+		payeeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payee aliases: %w", err)
+	}
+	defer rows.Close()
+
+	p.Aliases = []*models.PayeeAlias{}
+	for rows.Next() {
+		var a models.PayeeAlias
+		if err := rows.Scan(&a.ID, &a.PayeeID, &a.Pattern, &a.MatchType, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payee alias: %w", err)
+		}
+		p.Aliases = append(p.Aliases, &a)
+	}
+
+	return &p, nil
+}
+
+func (s *PayeeService) RenamePayee(ctx context.Context, scope Scope, payeeID string, req *models.UpdatePayeeRequest) (*models.Payee, error) {
+	userID := scope.UserID
+
+	oldPayee, err := s.GetPayee(ctx, scope, payeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	if req.Name == oldPayee.Name {
+		return oldPayee, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE payees SET name = $1, updated_at = $2 WHERE id = $3 AND `+scope.owns("", "$4"),
+		req.Name, time.Now(), payeeID, scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payee: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "updated",
+		"changes": map[string]interface{}{
+			"name": map[string]interface{}{
+				"old": oldPayee.Name,
+				"new": req.Name,
+			},
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "payee",
+		EntityID: payeeID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetPayee(ctx, scope, payeeID)
+}
+
+// MergePayees moves transactions and aliases of the source payees to the
+// target and deletes the sources.
+func (s *PayeeService) MergePayees(ctx context.Context, scope Scope, targetID string, req *models.MergePayeesRequest) (*models.Payee, error) {
+	userID := scope.UserID
+
+	sourceIDs := []string{}
+	for _, id := range uniqueStrings(req.SourceIDs) {
+		if id != targetID {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		return nil, fmt.Errorf("no payees to merge")
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payees WHERE `+scope.owns("", "$1")+` AND (id = $2 OR id = ANY($3))`,
+		scope.arg(), targetID, pq.Array(sourceIDs)).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify payees: %w", err)
+	}
+	if count != len(sourceIDs)+1 {
+		return nil, fmt.Errorf("payee not found")
+	}
+
+	// The payees were checked to be in the scope, so are their transactions
+	// and aliases
+	result, err := tx.ExecContext(ctx,
+		`UPDATE transactions SET payee_id = $1 WHERE payee_id = ANY($2)`,
+		targetID, pq.Array(sourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to move transactions: %w", err)
+	}
+	movedTransactions, _ := result.RowsAffected()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE payee_aliases SET payee_id = $1 WHERE payee_id = ANY($2)`,
+		targetID, pq.Array(sourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to move payee aliases: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM payees WHERE `+scope.owns("", "$1")+` AND id = ANY($2)`,
+		scope.arg(), pq.Array(sourceIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete merged payees: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "merged",
+		"data": map[string]interface{}{
+			"source_ids":         sourceIDs,
+			"moved_transactions": movedTransactions,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "payee",
+		EntityID: targetID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetPayee(ctx, scope, targetID)
+}
+
+func (s *PayeeService) DeletePayee(ctx context.Context, scope Scope, payeeID string) error {
+	userID := scope.UserID
+
+	payee, err := s.GetPayee(ctx, scope, payeeID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM payees WHERE id = $1 AND `+scope.owns("", "$2"), payeeID, scope.arg()); err != nil {
+		return fmt.Errorf("failed to delete payee: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name": payee.Name,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "payee",
+		EntityID: payeeID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PayeeService) AddAlias(ctx context.Context, scope Scope, payeeID string, req *models.CreatePayeeAliasRequest) (*models.PayeeAlias, error) {
+	if _, err := s.GetPayee(ctx, scope, payeeID); err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return nil, err
+	}
+
+	matchType := req.MatchType
+	if matchType == "" {
+		matchType = "prefix"
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	alias, err := s.addAlias(ctx, tx, scope, payeeID, req.Pattern, matchType)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, fmt.Errorf("alias pattern is empty after normalisation")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return alias, nil
+}
+
+func (s *PayeeService) DeleteAlias(ctx context.Context, scope Scope, payeeID, aliasID string) error {
+	if err := requireWorkspaceRole(ctx, s.db, scope, "member"); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM payee_aliases WHERE id = $1 AND payee_id = $2 AND `+scope.owns("", "$3"),
+		aliasID, payeeID, scope.arg())
+	if err != nil {
+		return fmt.Errorf("failed to delete payee alias: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("alias not found")
+	}
+
+	return nil
+}
+
+// addAlias stores a normalised alias. An existing alias with the same pattern
+// is reassigned to this payee. It returns nil when the pattern normalises to
+// nothing.
+func (s *PayeeService) addAlias(ctx context.Context, tx *sql.Tx, scope Scope, payeeID, pattern, matchType string) (*models.PayeeAlias, error) {
+	key := NormalizePayee(pattern)
+	if key == "" {
+		return nil, nil
+	}
+
+	alias := &models.PayeeAlias{
+		PayeeID:   payeeID,
+		Pattern:   key,
+		MatchType: matchType,
+	}
+
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO payee_aliases (payee_id, user_id, workspace_id, pattern, match_type)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ((COALESCE(workspace_id, user_id)), pattern) DO UPDATE
+		SET payee_id = EXCLUDED.payee_id, match_type = EXCLUDED.match_type
+		RETURNING id, created_at`,
+		payeeID, scope.UserID, scope.workspace(), key, matchType).Scan(&alias.ID, &alias.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payee alias: %w", err)
+	}
+
+	return alias, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"api-service/internal/config"
+	"api-service/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cached stats kinds
+const (
+	statsSummary        = "summary"
+	statsMonthly        = "monthly"
+	statsCategory       = "category"
+	statsBalanceHistory = "balance_history"
+)
+
+// A version key outlives every entry cached under it, so an expired version
+// that restarts from zero can't match old entries.
+const statsVersionTTL = 7 * 24 * time.Hour
+
+type statsCacheBypassKey struct{}
+
+// BypassStatsCache makes stats requests with ctx skip cached results. The
+// fresh result is still cached for the next request.
+func BypassStatsCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statsCacheBypassKey{}, true)
+}
+
+type statsCounters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// StatsCache keeps stats results in Redis per scope and parameters.
+//
+// Every scope has a version number that is part of its cache keys. Writes
+// bump the version of each scope that sees the changed data, which orphans
+// the old entries at once; they expire with their TTL. A result is cached
+// under the version read before it was computed, so a write that lands
+// during the computation can't leave a stale entry under the new version.
+type StatsCache struct {
+	redis *redis.Client
+	ttl   time.Duration
+
+	kinds         map[string]*statsCounters
+	bypassed      atomic.Int64
+	invalidations atomic.Int64
+	errors        atomic.Int64
+}
+
+func NewStatsCache(redisClient *redis.Client, cfg *config.Config) *StatsCache {
+	ttl := cfg.StatsCacheTTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &StatsCache{
+		redis: redisClient,
+		ttl:   ttl,
+		kinds: map[string]*statsCounters{
+			statsSummary:        {},
+			statsMonthly:        {},
+			statsCategory:       {},
+			statsBalanceHistory: {},
+		},
+	}
+}
+
+func statsScopeKey(scope Scope) string {
+	if scope.WorkspaceID != "" {
+		return "workspace:" + scope.WorkspaceID
+	}
+	return "user:" + scope.UserID
+}
+
+func statsVersionKey(scope Scope) string {
+	return "stats:version:" + statsScopeKey(scope)
+}
+
+// get loads a cached result into dest. It returns the key to cache the
+// result under when it had to be computed; the key is empty when Redis is
+// unavailable.
+func (c *StatsCache) get(ctx context.Context, scope Scope, kind, params string, dest interface{}) (string, bool) {
+	counters := c.kinds[kind]
+
+	version, err := c.redis.Get(ctx, statsVersionKey(scope)).Int64()
+	if err != nil && err != redis.Nil {
+		c.errors.Add(1)
+		counters.misses.Add(1)
+		return "", false
+	}
+
+	key := fmt.Sprintf("stats:%s:%s:v%d:%s", kind, statsScopeKey(scope), version, params)
+
+	if bypass, _ := ctx.Value(statsCacheBypassKey{}).(bool); bypass {
+		c.bypassed.Add(1)
+		return key, false
+	}
+
+	body, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.errors.Add(1)
+		}
+		counters.misses.Add(1)
+		return key, false
+	}
+
+	if err := json.Unmarshal(body, dest); err != nil {
+		c.errors.Add(1)
+		counters.misses.Add(1)
+		return key, false
+	}
+
+	counters.hits.Add(1)
+	return key, true
+}
+
+func (c *StatsCache) set(ctx context.Context, key string, value interface{}) {
+	if key == "" {
+		return
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+	if err := c.redis.Set(ctx, key, body, c.ttl).Err(); err != nil {
+		c.errors.Add(1)
+	}
+}
+
+// Invalidate bumps the versions of the scopes. Call it after the change is
+// committed, so no request can cache the old data under the new version.
+func (c *StatsCache) Invalidate(ctx context.Context, scopes ...Scope) {
+	if len(scopes) == 0 {
+		return
+	}
+
+	seen := map[string]bool{}
+	pipe := c.redis.Pipeline()
+	for _, scope := range scopes {
+		key := statsVersionKey(scope)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, statsVersionTTL)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.errors.Add(1)
+		log.Printf("Failed to invalidate stats cache: %v", err)
+		return
+	}
+	c.invalidations.Add(int64(len(seen)))
+}
+
+// GetStats reports hits and misses per kind since start.
+func (c *StatsCache) GetStats() *models.StatsCacheStats {
+	stats := &models.StatsCacheStats{
+		Kinds:         map[string]*models.StatsCacheKindStats{},
+		Bypassed:      c.bypassed.Load(),
+		Invalidations: c.invalidations.Load(),
+		Errors:        c.errors.Load(),
+	}
+	for kind, counters := range c.kinds {
+		kindStats := &models.StatsCacheKindStats{
+			Hits:   counters.hits.Load(),
+			Misses: counters.misses.Load(),
+		}
+		if total := kindStats.Hits + kindStats.Misses; total > 0 {
+			kindStats.HitRate = float64(kindStats.Hits) / float64(total)
+		}
+		stats.Kinds[kind] = kindStats
+	}
+	return stats
+}
+
+// statsScopes returns the scopes whose stats include the accounts: the
+// workspace of a workspace account or the owner's personal space, and the
+// personal space of every member. Call it before the change, while members
+// of a deleted account are still there.
+func statsScopes(ctx context.Context, tx *sql.Tx, accountIDs ...string) ([]Scope, error) {
+	ids := []string{}
+	for _, id := range accountIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT user_id, COALESCE(workspace_id::text, '') FROM accounts WHERE id = ANY($1::uuid[])
+		UNION
+		SELECT user_id, '' FROM account_members WHERE account_id = ANY($1::uuid[])`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats scopes: %w", err)
+	}
+	defer rows.Close()
+
+	scopes := []Scope{}
+	for rows.Next() {
+		var scope Scope
+		if err := rows.Scan(&scope.UserID, &scope.WorkspaceID); err != nil {
+			return nil, fmt.Errorf("failed to scan stats scope: %w", err)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, rows.Err()
+}
+
+// categoryStatsScopes returns the scopes whose stats include transactions
+// in the category.
+func categoryStatsScopes(ctx context.Context, tx *sql.Tx, categoryID string) ([]Scope, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT DISTINCT account_id FROM transactions WHERE category_id = $1`, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accountIDs := []string{}
+	for rows.Next() {
+		var accountID string
+		if err := rows.Scan(&accountID); err != nil {
+			return nil, fmt.Errorf("failed to scan category account: %w", err)
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get category accounts: %w", err)
+	}
+
+	return statsScopes(ctx, tx, accountIDs...)
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"time"
 )
 
 type StatsService struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *StatsCache
 }
 
-func NewStatsService(db *sql.DB) *StatsService {
-	return &StatsService{db: db}
+func NewStatsService(db *sql.DB, cache *StatsCache) *StatsService {
+	return &StatsService{db: db, cache: cache}
 }
 
 type Summary struct {
@@ -21,6 +23,10 @@ type Summary struct {
 	Balance           float64 `json:"balance"`
 	AccountsCount     int     `json:"accounts_count"`
 	TransactionsCount int     `json:"transactions_count"`
+	Receivables       float64 `json:"receivables"` // money lent and not yet repaid
+	Payables          float64 `json:"payables"`    // money borrowed and not yet repaid
+	Loans             float64 `json:"loans"`       // remaining principal of loans
+	NetWorth          float64 `json:"net_worth"`
 }
 
 type MonthlyStats struct {
@@ -39,13 +45,30 @@ type DailyBalance struct {
 	Expense float64   `json:"expense"`
 }
 
-func (s *StatsService) GetSummary(ctx context.Context, userID string) (*Summary, error) {
+type PayeeStats struct {
+	PayeeID           string    `json:"payee_id"`
+	PayeeName         string    `json:"payee_name"`
+	TotalSpent        float64   `json:"total_spent"`
+	TotalReceived     float64   `json:"total_received"`
+	Visits            int       `json:"visits"`
+	AverageTicket     float64   `json:"average_ticket"`
+	VisitsPerMonth    float64   `json:"visits_per_month"`
+	DaysBetweenVisits float64   `json:"days_between_visits"`
+	FirstVisit        time.Time `json:"first_visit"`
+	LastVisit         time.Time `json:"last_visit"`
+}
+
+func (s *StatsService) GetSummary(ctx context.Context, scope Scope) (*Summary, error) {
 	summary := &Summary{}
+	key, cached := s.cache.get(ctx, scope, statsSummary, "", summary)
+	if cached {
+		return summary, nil
+	}
 
 	// Get total balance from all accounts
 	err := s.db.QueryRowContext(ctx,
-		`SELECT COALESCE(SUM(balance), 0), COUNT(*) FROM accounts WHERE user_id = $1`,
-		userID).Scan(&summary.Balance, &summary.AccountsCount)
+		`SELECT COALESCE(SUM(balance), 0), COUNT(*) FROM accounts WHERE id IN `+scope.accounts("$1"),
+		scope.arg()).Scan(&summary.Balance, &summary.AccountsCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accounts summary: %w", err)
 	}
@@ -53,8 +76,8 @@ func (s *StatsService) GetSummary(ctx context.Context, userID string) (*Summary,
 	// Get total income
 	err = s.db.QueryRowContext(ctx,
 		`SELECT COALESCE(SUM(amount), 0) FROM transactions 
-         WHERE user_id = $1 AND type = 'income'`,
-		userID).Scan(&summary.TotalIncome)
+         WHERE account_id IN `+scope.accounts("$1")+` AND type = 'income'`,
+		scope.arg()).Scan(&summary.TotalIncome)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total income: %w", err)
 	}
@@ -62,30 +85,68 @@ func (s *StatsService) GetSummary(ctx context.Context, userID string) (*Summary,
 	// Get total expense
 	err = s.db.QueryRowContext(ctx,
 		`SELECT COALESCE(SUM(amount), 0) FROM transactions 
-         WHERE user_id = $1 AND type = 'expense'`,
-		userID).Scan(&summary.TotalExpense)
+         WHERE account_id IN `+scope.accounts("$1")+` AND type = 'expense'`,
+		scope.arg()).Scan(&summary.TotalExpense)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total expense: %w", err)
 	}
 
 	// Get transactions count
 	err = s.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM transactions WHERE user_id = $1`,
-		userID).Scan(&summary.TransactionsCount)
+		`SELECT COUNT(*) FROM transactions WHERE account_id IN `+scope.accounts("$1"),
+		scope.arg()).Scan(&summary.TransactionsCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions count: %w", err)
 	}
 
+	// Debts and loans are personal; a workspace dashboard shows the shared
+	// accounts only
+	if scope.WorkspaceID == "" {
+		// Debts are not income or expense, but they count towards net worth
+		err = s.db.QueryRowContext(ctx,
+			`SELECT 
+             COALESCE(SUM(d.amount - COALESCE(r.repaid, 0)) FILTER (WHERE d.direction = 'lent'), 0),
+             COALESCE(SUM(d.amount - COALESCE(r.repaid, 0)) FILTER (WHERE d.direction = 'borrowed'), 0)
+          FROM debts d
+          LEFT JOIN (
+             SELECT debt_id, SUM(amount) as repaid FROM debt_repayments GROUP BY debt_id
+          ) r ON r.debt_id = d.id
+          WHERE d.user_id = $1`,
+			scope.UserID).Scan(&summary.Receivables, &summary.Payables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get debts summary: %w", err)
+		}
+
+		loans, err := getLoans(ctx, s.db, scope.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, loan := range loans {
+			summary.Loans += loan.RemainingPrincipal
+		}
+		summary.Loans = roundMoney(summary.Loans)
+	}
+
+	summary.NetWorth = summary.Balance + summary.Receivables - summary.Payables - summary.Loans
+
+	s.cache.set(ctx, key, summary)
 	return summary, nil
 }
 
-func (s *StatsService) GetMonthlyStats(ctx context.Context, userID string, months int) ([]*MonthlyStats, error) {
+func (s *StatsService) GetMonthlyStats(ctx context.Context, scope Scope, months int) ([]*MonthlyStats, error) {
 	if months <= 0 {
 		months = 12
 	}
 
 	startDate := time.Now().AddDate(0, -months+1, 0).Format("2006-01-01")
 
+	// The period ends today, so the date is part of the key
+	var stats []*MonthlyStats
+	key, cached := s.cache.get(ctx, scope, statsMonthly, fmt.Sprintf("%s:%d", today().Format("2006-01-02"), months), &stats)
+	if cached {
+		return stats, nil
+	}
+
 	query := `
         WITH months AS (
             SELECT 
@@ -94,7 +155,7 @@ func (s *StatsService) GetMonthlyStats(ctx context.Context, userID string, month
                 SUM(t.amount) as total,
                 COUNT(t.id) as count
             FROM transactions t
-            WHERE t.user_id = $1 AND t.date >= $2::date
+            WHERE t.account_id IN ` + scope.accounts("$1") + ` AND t.date >= $2::date
             GROUP BY DATE_TRUNC('month', t.date), t.type
         )
         SELECT 
@@ -107,13 +168,12 @@ func (s *StatsService) GetMonthlyStats(ctx context.Context, userID string, month
         GROUP BY month, month_name, year
         ORDER BY month DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, startDate)
+	rows, err := s.db.QueryContext(ctx, query, scope.arg(), startDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monthly stats: %w", err)
 	}
 	defer rows.Close()
 
-	var stats []*MonthlyStats
 	for rows.Next() {
 		var stat MonthlyStats
 		err := rows.Scan(&stat.Month, &stat.Year, &stat.Income, &stat.Expense, &stat.Transactions)
@@ -124,16 +184,23 @@ func (s *StatsService) GetMonthlyStats(ctx context.Context, userID string, month
 		stats = append(stats, &stat)
 	}
 
+	s.cache.set(ctx, key, stats)
 	return stats, nil
 }
 
-func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, days int) ([]*DailyBalance, error) {
+func (s *StatsService) GetBalanceHistory(ctx context.Context, scope Scope, days int) ([]*DailyBalance, error) {
 	if days <= 0 {
 		days = 30
 	}
 
 	startDate := time.Now().AddDate(0, 0, -days+1)
 
+	var history []*DailyBalance
+	key, cached := s.cache.get(ctx, scope, statsBalanceHistory, fmt.Sprintf("%s:%d", today().Format("2006-01-02"), days), &history)
+	if cached {
+		return history, nil
+	}
+
 	query := `
         WITH daily_transactions AS (
             SELECT 
@@ -141,7 +208,7 @@ func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, day
                 SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END) as income,
                 SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END) as expense
             FROM transactions
-            WHERE user_id = $1 AND date >= $2
+            WHERE account_id IN ` + scope.accounts("$1") + ` AND date >= $2
             GROUP BY DATE(date)
         ),
         date_series AS (
@@ -155,7 +222,7 @@ func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, day
         LEFT JOIN daily_transactions dt ON ds.day = dt.day
         ORDER BY ds.day`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, startDate)
+	rows, err := s.db.QueryContext(ctx, query, scope.arg(), startDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance history: %w", err)
 	}
@@ -164,8 +231,8 @@ func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, day
 	// Get initial balance
 	var initialBalance float64
 	err = s.db.QueryRowContext(ctx,
-		`SELECT COALESCE(SUM(balance), 0) FROM accounts WHERE user_id = $1`,
-		userID).Scan(&initialBalance)
+		`SELECT COALESCE(SUM(balance), 0) FROM accounts WHERE id IN `+scope.accounts("$1"),
+		scope.arg()).Scan(&initialBalance)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get initial balance: %w", err)
 	}
@@ -177,15 +244,14 @@ func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, day
             COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
             COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
          FROM transactions 
-         WHERE user_id = $1 AND date < $2`,
-		userID, startDate).Scan(&priorIncome, &priorExpense)
+         WHERE account_id IN `+scope.accounts("$1")+` AND date < $2`,
+		scope.arg(), startDate).Scan(&priorIncome, &priorExpense)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prior transactions: %w", err)
 	}
 
 	runningBalance := initialBalance - (priorIncome - priorExpense)
 
-	var history []*DailyBalance
 	for rows.Next() {
 		var daily DailyBalance
 		err := rows.Scan(&daily.Date, &daily.Income, &daily.Expense)
@@ -199,10 +265,17 @@ func (s *StatsService) GetBalanceHistory(ctx context.Context, userID string, day
 		history = append(history, &daily)
 	}
 
+	s.cache.set(ctx, key, history)
 	return history, nil
 }
 
-func (s *StatsService) GetCategoryBreakdown(ctx context.Context, userID string, transactionType string, period string) (map[string]interface{}, error) {
+func (s *StatsService) GetCategoryBreakdown(ctx context.Context, scope Scope, transactionType string, period string) (map[string]interface{}, error) {
+	var breakdown map[string]interface{}
+	key, cached := s.cache.get(ctx, scope, statsCategory, fmt.Sprintf("%s:%s:%s", today().Format("2006-01-02"), transactionType, period), &breakdown)
+	if cached {
+		return breakdown, nil
+	}
+
 	var startDate time.Time
 
 	switch period {
@@ -224,16 +297,16 @@ func (s *StatsService) GetCategoryBreakdown(ctx context.Context, userID string,
             COALESCE(SUM(t.amount), 0) as total,
             COUNT(t.id) as count
         FROM categories c
-        LEFT JOIN transactions t ON c.id = t.category_id 
-            AND t.user_id = $1 
+        JOIN transactions t ON c.id = t.category_id 
+            AND t.account_id IN ` + scope.accounts("$1") + ` 
             AND t.type = $2 
             AND t.date >= $3
-        WHERE (c.user_id = $1 OR c.is_system = true) AND c.type = $2
+        WHERE c.type = $2
         GROUP BY c.id, c.name, c.color, c.icon
         HAVING COUNT(t.id) > 0
         ORDER BY total DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, transactionType, startDate)
+	rows, err := s.db.QueryContext(ctx, query, scope.arg(), transactionType, startDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category breakdown: %w", err)
 	}
@@ -272,10 +345,87 @@ func (s *StatsService) GetCategoryBreakdown(ctx context.Context, userID string,
 		}
 	}
 
-	return map[string]interface{}{
+	breakdown = map[string]interface{}{
 		"categories": categories,
 		"total":      total,
 		"period":     period,
 		"type":       transactionType,
-	}, nil
+	}
+
+	s.cache.set(ctx, key, breakdown)
+	return breakdown, nil
+}
+
+// GetPayeeStats returns spending per payee for the period, ordered by total
+// spend. A visit is a day with at least one transaction at the payee.
+func (s *StatsService) GetPayeeStats(ctx context.Context, scope Scope, period string, limit int) ([]*PayeeStats, error) {
+	var startDate time.Time
+
+	switch period {
+	case "month":
+		startDate = time.Now().AddDate(0, -1, 0)
+	case "quarter":
+		startDate = time.Now().AddDate(0, -3, 0)
+	case "year":
+		startDate = time.Now().AddDate(-1, 0, 0)
+	case "all":
+		startDate = time.Time{}
+	default:
+		startDate = time.Now().AddDate(0, -3, 0) // default to quarter
+	}
+
+	query := `
+        SELECT 
+            p.id,
+            p.name,
+            COALESCE(SUM(t.amount) FILTER (WHERE t.type = 'expense'), 0) as spent,
+            COALESCE(SUM(t.amount) FILTER (WHERE t.type = 'income'), 0) as received,
+            COUNT(DISTINCT t.date::date) as visits,
+            COUNT(t.id) FILTER (WHERE t.type = 'expense') as expense_count,
+            MIN(t.date) as first_visit,
+            MAX(t.date) as last_visit
+        FROM payees p
+        JOIN transactions t ON t.payee_id = p.id AND t.account_id IN ` + scope.accounts("$1") + ` AND t.date >= $2
+        WHERE ` + scope.owns("p", "$1") + `
+        GROUP BY p.id, p.name
+        ORDER BY spent DESC, visits DESC
+        LIMIT $3`
+
+	rows, err := s.db.QueryContext(ctx, query, scope.arg(), startDate, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payee stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*PayeeStats{}
+	for rows.Next() {
+		var ps PayeeStats
+		var expenseCount int
+
+		err := rows.Scan(&ps.PayeeID, &ps.PayeeName, &ps.TotalSpent, &ps.TotalReceived,
+			&ps.Visits, &expenseCount, &ps.FirstVisit, &ps.LastVisit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payee stats: %w", err)
+		}
+
+		if expenseCount > 0 {
+			ps.AverageTicket = roundMoney(ps.TotalSpent / float64(expenseCount))
+		}
+
+		// Frequency is measured over the span the payee was actually used,
+		// with at least one month so a single visit isn't extrapolated.
+		days := ps.LastVisit.Sub(ps.FirstVisit).Hours() / 24
+		months := days / 30
+		if months < 1 {
+			months = 1
+		}
+		ps.VisitsPerMonth = math.Round(float64(ps.Visits)/months*100) / 100
+		if ps.Visits > 1 {
+			ps.DaysBetweenVisits = math.Round(days/float64(ps.Visits-1)*10) / 10
+		}
+
+		stats = append(stats, &ps)
+	}
+
+	return stats, nil
 }
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// At most this many models are kept in memory; the least recently used
+	// is dropped to make room
+	suggestionMaxModels = 1000
+	// A model unused for this long is dropped
+	suggestionModelIdle = time.Hour
+	// A model is rebuilt after this long, picking up the changes other
+	// replicas made
+	suggestionModelMaxAge = 10 * time.Minute
+)
+
+// SuggestionService guesses a category for a new transaction using a
+// multinomial naive Bayes model per space (a workspace or a user's personal
+// space) trained on the categorised transactions of the accounts in it.
+// Models are built lazily from the database on first use and then kept up
+// to date incrementally by TransactionService. Idle models are evicted
+// (see suggestionMaxModels and suggestionModelIdle) and rebuilt from the
+// database the next time they are needed.
+//
+// Models live in each replica's memory and only learn the changes made
+// through that replica; what other replicas change shows up when the model
+// is rebuilt, at most suggestionModelMaxAge later.
+type SuggestionService struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	models map[string]*list.Element // of *cachedModel
+	lru    *list.List               // most recently used first
+
+	// Per model key being loaded: loads in flight, and changes made
+	// meanwhile that the loads may have missed
+	loading map[string]int
+	missed  map[string]int
+}
+
+func NewSuggestionService(db *sql.DB) *SuggestionService {
+	return &SuggestionService{
+		db:      db,
+		models:  make(map[string]*list.Element),
+		lru:     list.New(),
+		loading: make(map[string]int),
+		missed:  make(map[string]int),
+	}
+}
+
+type cachedModel struct {
+	key      string
+	model    *categoryModel
+	loadedAt time.Time
+	usedAt   time.Time
+}
+
+type CategorySuggestion struct {
+	CategoryID    string  `json:"category_id"`
+	CategoryName  string  `json:"category_name"`
+	CategoryType  string  `json:"category_type"`
+	CategoryIcon  string  `json:"category_icon"`
+	CategoryColor string  `json:"category_color"`
+	Confidence    float64 `json:"confidence"`
+}
+
+type categoryModel struct {
+	mu sync.RWMutex
+
+	docs        int
+	docsByCat   map[string]int
+	tokensByCat map[string]map[string]int
+	totalByCat  map[string]int
+	vocabulary  map[string]int
+}
+
+func newCategoryModel() *categoryModel {
+	return &categoryModel{
+		docsByCat:   make(map[string]int),
+		tokensByCat: make(map[string]map[string]int),
+		totalByCat:  make(map[string]int),
+		vocabulary:  make(map[string]int),
+	}
+}
+
+func (m *categoryModel) add(categoryID string, features []string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs += delta
+	m.docsByCat[categoryID] += delta
+	if m.docsByCat[categoryID] <= 0 {
+		delete(m.docsByCat, categoryID)
+	}
+
+	counts := m.tokensByCat[categoryID]
+	if counts == nil {
+		counts = make(map[string]int)
+		m.tokensByCat[categoryID] = counts
+	}
+
+	for _, f := range features {
+		counts[f] += delta
+		m.totalByCat[categoryID] += delta
+		m.vocabulary[f] += delta
+
+		if counts[f] <= 0 {
+			delete(counts, f)
+		}
+		if m.vocabulary[f] <= 0 {
+			delete(m.vocabulary, f)
+		}
+	}
+
+	if len(counts) == 0 {
+		delete(m.tokensByCat, categoryID)
+		delete(m.totalByCat, categoryID)
+	}
+}
+
+// scores returns normalised posterior probabilities per category.
+func (m *categoryModel) scores(features []string) map[string]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.docs <= 0 || len(m.docsByCat) == 0 {
+		return nil
+	}
+
+	vocab := float64(len(m.vocabulary) + 1)
+	logScores := make(map[string]float64, len(m.docsByCat))
+	maxScore := math.Inf(-1)
+
+	for categoryID, docs := range m.docsByCat {
+		score := math.Log(float64(docs) / float64(m.docs))
+		counts := m.tokensByCat[categoryID]
+		total := float64(m.totalByCat[categoryID])
+
+		for _, f := range features {
+			score += math.Log((float64(counts[f]) + 1) / (total + vocab))
+		}
+
+		logScores[categoryID] = score
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+
+	// Softmax over log scores
+	var sum float64
+	probs := make(map[string]float64, len(logScores))
+	for categoryID, score := range logScores {
+		p := math.Exp(score - maxScore)
+		probs[categoryID] = p
+		sum += p
+	}
+	for categoryID := range probs {
+		probs[categoryID] /= sum
+	}
+
+	return probs
+}
+
+// Suggest returns up to limit categories ranked by confidence. When
+// categoryType is set, only categories of that type are considered.
+func (s *SuggestionService) Suggest(ctx context.Context, scope Scope, description string, amount float64, categoryType string, limit int) ([]*CategorySuggestion, error) {
+	if limit <= 0 {
+		limit = 3
+	}
+
+	model, err := s.getModel(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	probs := model.scores(extractFeatures(description, amount))
+	if len(probs) == 0 {
+		return []*CategorySuggestion{}, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, type, COALESCE(icon, ''), COALESCE(color, '')
+		FROM categories
+		WHERE `+scope.owns("", "$1")+` OR is_system = true`,
+		scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []*CategorySuggestion{}
+	var total float64
+	for rows.Next() {
+		var sg CategorySuggestion
+		if err := rows.Scan(&sg.CategoryID, &sg.CategoryName, &sg.CategoryType,
+			&sg.CategoryIcon, &sg.CategoryColor); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+
+		p, ok := probs[sg.CategoryID]
+		if !ok || (categoryType != "" && sg.CategoryType != categoryType) {
+			continue
+		}
+
+		sg.Confidence = p
+		total += p
+		suggestions = append(suggestions, &sg)
+	}
+
+	// Renormalise after filtering out deleted or other-type categories
+	for _, sg := range suggestions {
+		if total > 0 {
+			sg.Confidence = math.Round(sg.Confidence/total*10000) / 10000
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Confidence > suggestions[j].Confidence
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
+
+// Learn adds a categorised transaction to the model of the space its
+// account belongs to. It is a no-op until the model has been loaded; the
+// first load reads it from the database.
+func (s *SuggestionService) Learn(space Scope, categoryID, description string, amount float64) {
+	if model := s.changedModel(space); model != nil {
+		model.add(categoryID, extractFeatures(description, amount), 1)
+	}
+}
+
+// Forget removes a previously learned transaction from the space's model.
+func (s *SuggestionService) Forget(space Scope, categoryID, description string, amount float64) {
+	if model := s.changedModel(space); model != nil {
+		model.add(categoryID, extractFeatures(description, amount), -1)
+	}
+}
+
+// changedModel returns the model to apply a change of the space's data to.
+// When there is none but one is being loaded, the load may have read the
+// data before the change, so it is marked as missing it.
+func (s *SuggestionService) changedModel(space Scope) *categoryModel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := modelKey(space)
+	model := s.lookup(key)
+	if model == nil && s.loading[key] > 0 {
+		s.missed[key]++
+	}
+	return model
+}
+
+// modelKey identifies a space's model. Workspace and user IDs are both
+// UUIDs, so they can't collide.
+func modelKey(space Scope) string {
+	if space.WorkspaceID != "" {
+		return space.WorkspaceID
+	}
+	return space.UserID
+}
+
+// loadedModel returns the space's model if it is in memory and marks it as
+// used. A model that has been idle or loaded too long ago is evicted
+// instead.
+func (s *SuggestionService) loadedModel(space Scope) *categoryModel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(modelKey(space))
+}
+
+// lookup is loadedModel by key; s.mu must be held.
+func (s *SuggestionService) lookup(key string) *categoryModel {
+	elem, ok := s.models[key]
+	if !ok {
+		return nil
+	}
+	cached := elem.Value.(*cachedModel)
+	now := time.Now()
+	if now.Sub(cached.usedAt) > suggestionModelIdle || now.Sub(cached.loadedAt) > suggestionModelMaxAge {
+		s.evict(elem)
+		return nil
+	}
+	cached.usedAt = now
+	s.lru.MoveToFront(elem)
+	return cached.model
+}
+
+// evict drops a model; s.mu must be held.
+func (s *SuggestionService) evict(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.models, elem.Value.(*cachedModel).key)
+}
+
+func (s *SuggestionService) getModel(ctx context.Context, space Scope) (*categoryModel, error) {
+	if model := s.loadedModel(space); model != nil {
+		return model, nil
+	}
+
+	key := modelKey(space)
+	s.mu.Lock()
+	s.loading[key]++
+	missedBefore := s.missed[key]
+	s.mu.Unlock()
+
+	model, err := s.loadModel(ctx, space)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missed := s.missed[key] != missedBefore
+	if s.loading[key]--; s.loading[key] == 0 {
+		delete(s.loading, key)
+		delete(s.missed, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request may have loaded the model meanwhile
+	if elem, ok := s.models[key]; ok {
+		return elem.Value.(*cachedModel).model, nil
+	}
+	// A model that missed a change still answers this request, but isn't
+	// kept: the next request loads it again
+	if missed {
+		return model, nil
+	}
+
+	now := time.Now()
+	s.models[key] = s.lru.PushFront(&cachedModel{key: key, model: model, loadedAt: now, usedAt: now})
+
+	// Make room by dropping the least recently used models
+	for s.lru.Len() > suggestionMaxModels {
+		s.evict(s.lru.Back())
+	}
+
+	return model, nil
+}
+
+// loadModel trains the space's model from the database.
+func (s *SuggestionService) loadModel(ctx context.Context, space Scope) (*categoryModel, error) {
+	model := newCategoryModel()
+
+	// Only the space's own accounts: transactions on accounts shared with
+	// the user are learned by the owner's space
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT category_id, COALESCE(description, ''), amount
+		FROM transactions
+		WHERE account_id IN (SELECT id FROM accounts WHERE `+space.owns("", "$1")+`)`,
+		space.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load training data: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var categoryID, description string
+		var amount float64
+		if err := rows.Scan(&categoryID, &description, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan training row: %w", err)
+		}
+		model.add(categoryID, extractFeatures(description, amount), 1)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load training data: %w", err)
+	}
+
+	return model, nil
+}
+
+// extractFeatures turns a description and amount into model features:
+// normalised word tokens plus a logarithmic amount bucket.
+func extractFeatures(description string, amount float64) []string {
+	features := tokenize(description)
+	if amount > 0 {
+		features = append(features, fmt.Sprintf("amount:%d", int(math.Floor(math.Log10(amount)*2))))
+	}
+	return features
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		// Store numbers, card tails and single letters carry no signal
+		if len([]rune(w)) < 2 || isNumeric(w) {
+			continue
+		}
+		tokens = append(tokens, strings.ReplaceAll(w, "ё", "е"))
+	}
+
+	return tokens
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -8,23 +8,47 @@ import (
 	"time"
 
 	"api-service/internal/models"
+	"api-service/pkg/events"
 
 	"github.com/google/uuid"
 )
 
 type TransactionService struct {
-	db         *sql.DB
-	logService *LogService
+	db                *sql.DB
+	logService        *LogService
+	suggestionService *SuggestionService
+	payeeService      *PayeeService
+	publisher         *events.Publisher
+	statsCache        *StatsCache
 }
 
-func NewTransactionService(db *sql.DB, logService *LogService) *TransactionService {
+func NewTransactionService(db *sql.DB, logService *LogService, suggestionService *SuggestionService, payeeService *PayeeService, publisher *events.Publisher, statsCache *StatsCache) *TransactionService {
 	return &TransactionService{
-		db:         db,
-		logService: logService,
+		db:                db,
+		logService:        logService,
+		suggestionService: suggestionService,
+		payeeService:      payeeService,
+		publisher:         publisher,
+		statsCache:        statsCache,
 	}
 }
 
-func (s *TransactionService) CreateTransaction(ctx context.Context, userID string, req *models.CreateTransactionRequest) (*models.Transaction, error) {
+func transactionPayload(t *models.Transaction) *events.TransactionPayload {
+	return &events.TransactionPayload{
+		ID:          t.ID,
+		AccountID:   t.AccountID,
+		CategoryID:  t.CategoryID,
+		Type:        t.Type,
+		Amount:      t.Amount,
+		Description: t.Description,
+		PayeeID:     t.PayeeID,
+		Date:        t.Date,
+	}
+}
+
+func (s *TransactionService) CreateTransaction(ctx context.Context, scope Scope, req *models.CreateTransactionRequest) (*models.Transaction, error) {
+	userID := scope.UserID
+
 	// Begin transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -35,8 +59,8 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID strin
 	// Get category type to determine transaction type
 	var categoryType string
 	err = tx.QueryRowContext(ctx,
-		`SELECT type FROM categories WHERE id = $1 AND (user_id = $2 OR is_system = true)`,
-		req.CategoryID, userID).Scan(&categoryType)
+		`SELECT type FROM categories WHERE id = $1 AND (`+scope.owns("", "$2")+` OR is_system = true)`,
+		req.CategoryID, scope.arg()).Scan(&categoryType)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -45,18 +69,16 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID strin
 		return nil, fmt.Errorf("failed to get category type: %w", err)
 	}
 
-	// Verify account belongs to user
-	var accountExists bool
-	err = tx.QueryRowContext(ctx,
-		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND user_id = $2)`,
-		req.AccountID, userID).Scan(&accountExists)
-
+	// Verify the user may add transactions to the account; on shared
+	// accounts the transaction belongs to the account owner
+	ownerID, err := s.requireScopedAccount(ctx, tx, scope, req.AccountID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify account: %w", err)
+		return nil, err
 	}
 
-	if !accountExists {
-		return nil, fmt.Errorf("account not found")
+	space, err := accountSpace(ctx, tx, req.AccountID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse date properly - expecting "YYYY-MM-DD" format
@@ -70,25 +92,44 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID strin
 		transactionDate = time.Now()
 	}
 
+	// Link payee: explicit choice or resolved from description. Payees
+	// belong to the account's space, like the ones Resolve picks.
+	var payeeID *string
+	if req.PayeeID != "" {
+		if err := s.payeeService.VerifyPayee(ctx, tx, space, req.PayeeID); err != nil {
+			return nil, err
+		}
+		payeeID = &req.PayeeID
+	} else {
+		payeeID, err = s.payeeService.Resolve(ctx, tx, space, req.Description)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create transaction
 	transaction := &models.Transaction{
 		ID:          uuid.New().String(),
-		UserID:      userID,
+		UserID:      ownerID,
 		AccountID:   req.AccountID,
 		CategoryID:  req.CategoryID,
 		Type:        categoryType,
 		Amount:      req.Amount,
 		Description: req.Description,
+		PayeeID:     payeeID,
 		Date:        transactionDate,
+		CreatedBy:   &userID,
+		UpdatedBy:   &userID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
 	_, err = tx.ExecContext(ctx,
-		`INSERT INTO transactions (id, user_id, account_id, category_id, type, amount, description, date, created_at, updated_at) 
-         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		`INSERT INTO transactions (id, user_id, account_id, category_id, type, amount, description, payee_id, date, created_by, updated_by, created_at, updated_at) 
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
 		transaction.ID, transaction.UserID, transaction.AccountID, transaction.CategoryID,
-		transaction.Type, transaction.Amount, transaction.Description, transaction.Date,
+		transaction.Type, transaction.Amount, transaction.Description, transaction.PayeeID,
+		transaction.Date, transaction.CreatedBy, transaction.UpdatedBy,
 		transaction.CreatedAt, transaction.UpdatedAt)
 
 	if err != nil {
@@ -110,11 +151,6 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID strin
 		return nil, fmt.Errorf("failed to update account balance: %w", err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// ✅ Детальное логирование создания
 	logDetails := map[string]interface{}{
 		"action": "created",
@@ -126,35 +162,66 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID strin
 			"date":        transaction.Date.Format("2006-01-02"),
 			"account_id":  transaction.AccountID,
 			"category_id": transaction.CategoryID,
+			"payee_id":    transaction.PayeeID,
 		},
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "create",
 		Entity:   "transaction",
 		EntityID: transaction.ID,
 		Details:  string(detailsJSON),
-	})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := enqueueEvent(ctx, tx, s.publisher, events.TransactionCreated, userID, transaction.ID, transactionPayload(transaction)); err != nil {
+		return nil, err
+	}
+
+	scopes, err := statsScopes(ctx, tx, transaction.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	// Suggestions are learned in the account's space
+	s.suggestionService.Learn(space, transaction.CategoryID, transaction.Description, transaction.Amount)
 
 	return transaction, nil
 
 }
 
 func (s *TransactionService) GetTransactions(ctx context.Context, filter *models.TransactionFilter) ([]*models.Transaction, error) {
+	scope := Scope{UserID: filter.UserID, WorkspaceID: filter.WorkspaceID}
+
 	query := `
         SELECT 
             t.id, t.user_id, t.account_id, t.category_id, t.type, 
-            t.amount, t.description, t.date, t.created_at, t.updated_at,
+            t.amount, t.description, t.payee_id, t.date, t.created_by, t.updated_by,
+            t.created_at, t.updated_at,
             a.name as account_name,
-            c.name as category_name, c.icon as category_icon, c.color as category_color
+            c.name as category_name, c.icon as category_icon, c.color as category_color,
+            COALESCE(p.name, '') as payee_name,
+            COALESCE(cu.email, '') as created_by_email,
+            COALESCE(uu.email, '') as updated_by_email
         FROM transactions t
         JOIN accounts a ON t.account_id = a.id
         JOIN categories c ON t.category_id = c.id
-        WHERE t.user_id = $1`
+        LEFT JOIN payees p ON t.payee_id = p.id
+        LEFT JOIN users cu ON t.created_by = cu.id
+        LEFT JOIN users uu ON t.updated_by = uu.id
+        WHERE t.account_id IN ` + scope.accounts("$1")
 
-	args := []interface{}{filter.UserID}
+	args := []interface{}{scope.arg()}
 	argCount := 1
 
 	if filter.AccountID != "" {
@@ -212,8 +279,10 @@ func (s *TransactionService) GetTransactions(ctx context.Context, filter *models
 		var t models.Transaction
 		err := rows.Scan(
 			&t.ID, &t.UserID, &t.AccountID, &t.CategoryID, &t.Type,
-			&t.Amount, &t.Description, &t.Date, &t.CreatedAt, &t.UpdatedAt,
-			&t.AccountName, &t.CategoryName, &t.CategoryIcon, &t.CategoryColor,
+			&t.Amount, &t.Description, &t.PayeeID, &t.Date, &t.CreatedBy, &t.UpdatedBy,
+			&t.CreatedAt, &t.UpdatedAt,
+			&t.AccountName, &t.CategoryName, &t.CategoryIcon, &t.CategoryColor, &t.PayeeName,
+			&t.CreatedByEmail, &t.UpdatedByEmail,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -224,27 +293,36 @@ func (s *TransactionService) GetTransactions(ctx context.Context, filter *models
 	return transactions, nil
 }
 
-func (s *TransactionService) GetTransaction(ctx context.Context, userID, transactionID string) (*models.Transaction, error) {
+func (s *TransactionService) GetTransaction(ctx context.Context, scope Scope, transactionID string) (*models.Transaction, error) {
 	var t models.Transaction
 	err := s.db.QueryRowContext(ctx,
 		`SELECT 
             t.id, t.user_id, t.account_id, t.category_id, t.type, 
-            t.amount, t.description, t.date, t.created_at, t.updated_at,
+            t.amount, t.description, t.payee_id, t.date, t.created_by, t.updated_by,
+            t.created_at, t.updated_at,
             a.name as account_name,
-            c.name as category_name, c.icon as category_icon, c.color as category_color
+            c.name as category_name, c.icon as category_icon, c.color as category_color,
+            COALESCE(p.name, '') as payee_name,
+            COALESCE(cu.email, '') as created_by_email,
+            COALESCE(uu.email, '') as updated_by_email
         FROM transactions t
         JOIN accounts a ON t.account_id = a.id
         JOIN categories c ON t.category_id = c.id
-        WHERE t.id = $1 AND t.user_id = $2`,
-		transactionID, userID).Scan(
+        LEFT JOIN payees p ON t.payee_id = p.id
+        LEFT JOIN users cu ON t.created_by = cu.id
+        LEFT JOIN users uu ON t.updated_by = uu.id
+        WHERE t.id = $1 AND t.account_id IN `+scope.accounts("$2"),
+		transactionID, scope.arg()).Scan(
 		&t.ID, &t.UserID, &t.AccountID, &t.CategoryID, &t.Type,
-		&t.Amount, &t.Description, &t.Date, &t.CreatedAt, &t.UpdatedAt,
-		&t.AccountName, &t.CategoryName, &t.CategoryIcon, &t.CategoryColor,
+		&t.Amount, &t.Description, &t.PayeeID, &t.Date, &t.CreatedBy, &t.UpdatedBy,
+		&t.CreatedAt, &t.UpdatedAt,
+		&t.AccountName, &t.CategoryName, &t.CategoryIcon, &t.CategoryColor, &t.PayeeName,
+		&t.CreatedByEmail, &t.UpdatedByEmail,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
+			return nil, fmt.Errorf("transaction %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
@@ -252,7 +330,9 @@ func (s *TransactionService) GetTransaction(ctx context.Context, userID, transac
 	return &t, nil
 }
 
-func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, transactionID string, req *models.UpdateTransactionRequest) (*models.Transaction, error) {
+func (s *TransactionService) UpdateTransaction(ctx context.Context, scope Scope, transactionID string, req *models.UpdateTransactionRequest) (*models.Transaction, error) {
+	userID := scope.UserID
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -262,23 +342,35 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 	// Get current transaction
 	var oldTransaction models.Transaction
 	err = tx.QueryRowContext(ctx,
-		`SELECT id, user_id, account_id, category_id, type, amount, description, date 
-         FROM transactions WHERE id = $1 AND user_id = $2`,
-		transactionID, userID).Scan(
+		`SELECT id, user_id, account_id, category_id, type, amount, description, payee_id, date, created_by, created_at 
+         FROM transactions WHERE id = $1 AND account_id IN `+scope.accounts("$2"),
+		transactionID, scope.arg()).Scan(
 		&oldTransaction.ID, &oldTransaction.UserID, &oldTransaction.AccountID,
 		&oldTransaction.CategoryID, &oldTransaction.Type, &oldTransaction.Amount,
-		&oldTransaction.Description, &oldTransaction.Date,
+		&oldTransaction.Description, &oldTransaction.PayeeID, &oldTransaction.Date,
+		&oldTransaction.CreatedBy, &oldTransaction.CreatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
+			return nil, fmt.Errorf("transaction %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
+	if _, err := requireAccountRole(ctx, tx, userID, oldTransaction.AccountID, "editor"); err != nil {
+		return nil, err
+	}
+
+	previous := oldTransaction
 	changes := make(map[string]map[string]interface{})
 
+	previousSpace, err := accountSpace(ctx, tx, previous.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	space := previousSpace
+
 	// Revert old transaction from account balance
 	if oldTransaction.Type == "income" {
 		_, err = tx.ExecContext(ctx,
@@ -296,20 +388,34 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 
 	// Update transaction fields
 	if req.AccountID != "" && req.AccountID != oldTransaction.AccountID {
+		// Moving to another account re-homes the transaction with that account's owner
+		ownerID, err := s.requireScopedAccount(ctx, tx, scope, req.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		if space, err = accountSpace(ctx, tx, req.AccountID); err != nil {
+			return nil, err
+		}
+
 		changes["account_id"] = map[string]interface{}{
 			"old": oldTransaction.AccountID,
 			"new": req.AccountID,
 		}
 		oldTransaction.AccountID = req.AccountID
+		oldTransaction.UserID = ownerID
 	}
 
 	if req.CategoryID != "" && req.CategoryID != oldTransaction.CategoryID {
 		// Get new category type
 		var categoryType string
 		err = tx.QueryRowContext(ctx,
-			`SELECT type FROM categories WHERE id = $1 AND (user_id = $2 OR is_system = true)`,
-			req.CategoryID, userID).Scan(&categoryType)
+			`SELECT type FROM categories WHERE id = $1 AND (`+scope.owns("", "$2")+` OR is_system = true)`,
+			req.CategoryID, scope.arg()).Scan(&categoryType)
 		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("category not found")
+			}
 			return nil, fmt.Errorf("failed to get category type: %w", err)
 		}
 
@@ -342,6 +448,31 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		oldTransaction.Description = req.Description
 	}
 
+	// An explicit payee wins; otherwise re-resolve when the description or
+	// the space changed. Either way the payee is in the account's space.
+	var payeeID *string
+	if req.PayeeID != "" {
+		if err := s.payeeService.VerifyPayee(ctx, tx, space, req.PayeeID); err != nil {
+			return nil, err
+		}
+		payeeID = &req.PayeeID
+	} else if _, ok := changes["description"]; ok || space != previousSpace {
+		payeeID, err = s.payeeService.Resolve(ctx, tx, space, oldTransaction.Description)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		payeeID = oldTransaction.PayeeID
+	}
+
+	if stringValue(payeeID) != stringValue(oldTransaction.PayeeID) {
+		changes["payee_id"] = map[string]interface{}{
+			"old": oldTransaction.PayeeID,
+			"new": payeeID,
+		}
+		oldTransaction.PayeeID = payeeID
+	}
+
 	if req.Date != "" {
 		transactionDate, err := time.Parse("2006-01-02", req.Date)
 		if err != nil {
@@ -356,16 +487,17 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		}
 	}
 
+	oldTransaction.UpdatedBy = &userID
 	oldTransaction.UpdatedAt = time.Now()
 
 	// Update transaction in database
 	_, err = tx.ExecContext(ctx,
-		`UPDATE transactions SET account_id = $1, category_id = $2, type = $3, 
-         amount = $4, description = $5, date = $6, updated_at = $7 
-         WHERE id = $8`,
-		oldTransaction.AccountID, oldTransaction.CategoryID, oldTransaction.Type,
-		oldTransaction.Amount, oldTransaction.Description, oldTransaction.Date,
-		oldTransaction.UpdatedAt, transactionID)
+		`UPDATE transactions SET user_id = $1, account_id = $2, category_id = $3, type = $4, 
+         amount = $5, description = $6, payee_id = $7, date = $8, updated_by = $9, updated_at = $10 
+         WHERE id = $11`,
+		oldTransaction.UserID, oldTransaction.AccountID, oldTransaction.CategoryID, oldTransaction.Type,
+		oldTransaction.Amount, oldTransaction.Description, oldTransaction.PayeeID,
+		oldTransaction.Date, oldTransaction.UpdatedBy, oldTransaction.UpdatedAt, transactionID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update transaction: %w", err)
@@ -386,10 +518,6 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		return nil, fmt.Errorf("failed to update new balance: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// ✅ Логирование только если были изменения
 	if len(changes) > 0 {
 		logDetails := map[string]interface{}{
@@ -398,20 +526,44 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		}
 		detailsJSON, _ := json.Marshal(logDetails)
 
-		go s.logService.Log(context.Background(), &UserAction{
+		if err := s.logService.Record(ctx, tx, &UserAction{
 			UserID:   userID,
 			Action:   "update",
 			Entity:   "transaction",
 			EntityID: transactionID,
 			Details:  string(detailsJSON),
-		})
+		}); err != nil {
+			return nil, err
+		}
+
+		payload := transactionPayload(&oldTransaction)
+		payload.Previous = transactionPayload(&previous)
+		if err := enqueueEvent(ctx, tx, s.publisher, events.TransactionUpdated, userID, transactionID, payload); err != nil {
+			return nil, err
+		}
 	}
 
+	scopes, err := statsScopes(ctx, tx, previous.AccountID, oldTransaction.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	s.suggestionService.Forget(previousSpace, previous.CategoryID, previous.Description, previous.Amount)
+	s.suggestionService.Learn(space, oldTransaction.CategoryID, oldTransaction.Description, oldTransaction.Amount)
+
 	return &oldTransaction, nil
 
 }
 
-func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, transactionID string) error {
+func (s *TransactionService) DeleteTransaction(ctx context.Context, scope Scope, transactionID string) error {
+	userID := scope.UserID
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -419,25 +571,36 @@ func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, tran
 	defer tx.Rollback()
 
 	// Get transaction details
-	var accountID string
+	var accountID, categoryID string
 	var transactionType string
 	var amount float64
+	var description sql.NullString
+	var date time.Time
 
 	err = tx.QueryRowContext(ctx,
-		`SELECT account_id, type, amount FROM transactions WHERE id = $1 AND user_id = $2`,
-		transactionID, userID).Scan(&accountID, &transactionType, &amount)
+		`SELECT account_id, category_id, type, amount, description, date FROM transactions WHERE id = $1 AND account_id IN `+scope.accounts("$2"),
+		transactionID, scope.arg()).Scan(&accountID, &categoryID, &transactionType, &amount, &description, &date)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("transaction not found")
+			return fmt.Errorf("transaction %w", ErrNotFound)
 		}
 		return fmt.Errorf("failed to get transaction: %w", err)
 	}
 
+	if _, err := requireAccountRole(ctx, tx, userID, accountID, "editor"); err != nil {
+		return err
+	}
+
+	space, err := accountSpace(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+
 	// Delete transaction
 	result, err := tx.ExecContext(ctx,
-		`DELETE FROM transactions WHERE id = $1 AND user_id = $2`,
-		transactionID, userID)
+		`DELETE FROM transactions WHERE id = $1 AND account_id = $2`,
+		transactionID, accountID)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete transaction: %w", err)
@@ -449,7 +612,7 @@ func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, tran
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("transaction not found")
+		return fmt.Errorf("transaction %w", ErrNotFound)
 	}
 
 	// Update account balance
@@ -467,10 +630,6 @@ func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, tran
 		return fmt.Errorf("failed to update account balance: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// ✅ Детальное логирование удаления
 	logDetails := map[string]interface{}{
 		"action": "deleted",
@@ -482,13 +641,59 @@ func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, tran
 	}
 	detailsJSON, _ := json.Marshal(logDetails)
 
-	go s.logService.Log(context.Background(), &UserAction{
+	if err := s.logService.Record(ctx, tx, &UserAction{
 		UserID:   userID,
 		Action:   "delete",
 		Entity:   "transaction",
 		EntityID: transactionID,
 		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	err = enqueueEvent(ctx, tx, s.publisher, events.TransactionDeleted, userID, transactionID, &events.TransactionPayload{
+		ID:          transactionID,
+		AccountID:   accountID,
+		CategoryID:  categoryID,
+		Type:        transactionType,
+		Amount:      amount,
+		Description: description.String,
+		Date:        date,
 	})
+	if err != nil {
+		return err
+	}
+
+	scopes, err := statsScopes(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.statsCache.Invalidate(ctx, scopes...)
+
+	s.suggestionService.Forget(space, categoryID, description.String, amount)
 
 	return nil
 }
+
+// requireScopedAccount checks the account is part of the active space and
+// the user may edit it, and returns the account owner.
+func (s *TransactionService) requireScopedAccount(ctx context.Context, tx *sql.Tx, scope Scope, accountID string) (string, error) {
+	var inScope bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND id IN `+scope.accounts("$2")+`)`,
+		accountID, scope.arg()).Scan(&inScope)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify account: %w", err)
+	}
+
+	if !inScope {
+		return "", fmt.Errorf("account not found")
+	}
+
+	return requireAccountRole(ctx, tx, scope.UserID, accountID, "editor")
+}
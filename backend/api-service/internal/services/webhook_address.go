@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// errWebhookAddress is returned when an endpoint resolves to an address
+// webhooks must not be sent to.
+var errWebhookAddress = errors.New("webhook url must point to a public address")
+
+// nonPublicNetworks are not covered by the net.IP predicates but are not
+// reachable on the public internet either.
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"192.0.2.0/24",  // documentation
+	"198.18.0.0/15", // benchmarking
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"240.0.0.0/4",  // reserved, and the broadcast address
+	"64:ff9b::/96", // NAT64 can reach any IPv4 address
+	"64:ff9b:1::/48",
+	"2001:db8::/32",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// parseNetworks reads CIDRs or single IP addresses.
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q", entry)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookAddressGuard decides which addresses webhooks may be sent to:
+// public ones, and those in the allowed networks.
+type webhookAddressGuard struct {
+	allowed []*net.IPNet
+}
+
+func (g *webhookAddressGuard) permits(ip net.IP) bool {
+	if isPublicIP(ip) {
+		return true
+	}
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// control runs after the endpoint's host is resolved and before connecting,
+// so it sees the address actually dialed: a host that resolves to a public
+// address when the webhook is saved and to an internal one later is still
+// refused.
+func (g *webhookAddressGuard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !g.permits(ip) {
+		return fmt.Errorf("refusing to connect to %s: %w", host, errWebhookAddress)
+	}
+	return nil
+}
+
+// checkHost resolves the host of an endpoint being saved, so that internal
+// endpoints are refused up front instead of failing every delivery.
+func (g *webhookAddressGuard) checkHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.permits(ip) {
+			return errWebhookAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.New("webhook url must have a resolvable host")
+	}
+	for _, addr := range addrs {
+		if !g.permits(addr.IP) {
+			return errWebhookAddress
+		}
+	}
+	return nil
+}
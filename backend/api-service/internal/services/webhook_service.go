@@ -0,0 +1,804 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"api-service/internal/config"
+	"api-service/internal/models"
+	"api-service/pkg/events"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	webhookBatchSize    = 20
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 8                // per delivery, then it is marked failed
+	webhookBaseBackoff  = 30 * time.Second // doubled after every failed attempt
+	webhookDisableAfter = 25               // consecutive failed attempts before the endpoint is disabled
+	webhookMaxBodyLog   = 1024             // bytes of the response kept in the delivery log
+
+	// webhookTestEvent is sent by SendTest and not subscribable
+	webhookTestEvent = "webhook.test"
+)
+
+// WebhookService manages user webhook endpoints and delivers events to
+// them. Every request carries an X-Webhook-Signature header of the form
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>" keyed by the
+// endpoint secret>", so receivers can check both origin and freshness.
+//
+// Endpoints must be public: every connection is checked against the
+// address actually dialed, so webhooks can't be pointed at the services
+// and databases next to this one.
+type WebhookService struct {
+	db         *sql.DB
+	logService *LogService
+	client     *http.Client
+	guard      *webhookAddressGuard
+}
+
+func NewWebhookService(db *sql.DB, logService *LogService, cfg *config.Config) (*WebhookService, error) {
+	allowed, err := parseNetworks(cfg.WebhookAllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	guard := &webhookAddressGuard{allowed: allowed}
+
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: guard.control,
+	}
+
+	return &WebhookService{
+		db:         db,
+		logService: logService,
+		guard:      guard,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// No proxy: it would be the address dialed instead of the endpoint
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// Redirects are reported as failures instead of being followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+const webhookSelect = `SELECT id, user_id, workspace_id, url, event_types, is_active, failure_count,
+	disabled_at, COALESCE(disabled_reason, ''), created_at, updated_at
+	FROM webhooks`
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var w models.Webhook
+	var eventTypes pq.StringArray
+	err := row.Scan(&w.ID, &w.UserID, &w.WorkspaceID, &w.URL, &eventTypes, &w.IsActive, &w.FailureCount,
+		&w.DisabledAt, &w.DisabledReason, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.EventTypes = []string(eventTypes)
+	return &w, nil
+}
+
+// Webhooks send the space's data to outside endpoints, so in a workspace
+// only admins manage them.
+func (s *WebhookService) CreateWebhook(ctx context.Context, scope Scope, req *models.CreateWebhookRequest) (*models.Webhook, error) {
+	userID := scope.UserID
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "admin"); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	}
+
+	webhook := &models.Webhook{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: scope.workspace(),
+		URL:         req.URL,
+		EventTypes:  uniqueStrings(req.EventTypes),
+		IsActive:    true,
+		Secret:      secret,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO webhooks (id, user_id, workspace_id, url, secret, event_types, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		webhook.ID, webhook.UserID, webhook.WorkspaceID, webhook.URL, secret, pq.Array(webhook.EventTypes),
+		webhook.CreatedAt, webhook.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":          webhook.ID,
+			"url":         webhook.URL,
+			"event_types": webhook.EventTypes,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "create",
+		Entity:   "webhook",
+		EntityID: webhook.ID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, scope Scope) ([]*models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, webhookSelect+` WHERE `+scope.owns("", "$1")+` ORDER BY created_at`, scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, scope Scope, webhookID string) (*models.Webhook, error) {
+	row := s.db.QueryRowContext(ctx, webhookSelect+` WHERE id = $1 AND `+scope.owns("", "$2"), webhookID, scope.arg())
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, scope Scope, webhookID string, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	userID := scope.UserID
+
+	old, err := s.GetWebhook(ctx, scope, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "admin"); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+	updated := *old
+
+	if req.URL != "" && req.URL != old.URL {
+		if err := s.validateURL(ctx, req.URL); err != nil {
+			return nil, err
+		}
+		updated.URL = req.URL
+		changes["url"] = map[string]interface{}{"old": old.URL, "new": req.URL}
+	}
+
+	if len(req.EventTypes) > 0 {
+		eventTypes := uniqueStrings(req.EventTypes)
+		if !sameStrings(eventTypes, old.EventTypes) {
+			updated.EventTypes = eventTypes
+			changes["event_types"] = map[string]interface{}{"old": old.EventTypes, "new": eventTypes}
+		}
+	}
+
+	if req.IsActive != nil && *req.IsActive != old.IsActive {
+		updated.IsActive = *req.IsActive
+		changes["is_active"] = map[string]interface{}{"old": old.IsActive, "new": *req.IsActive}
+	}
+
+	if len(changes) == 0 {
+		return old, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Re-enabling gives the endpoint a clean slate
+	_, err = tx.ExecContext(ctx,
+		`UPDATE webhooks SET url = $1, event_types = $2, is_active = $3,
+			failure_count = CASE WHEN $3 AND NOT is_active THEN 0 ELSE failure_count END,
+			disabled_at = CASE WHEN $3 THEN NULL ELSE disabled_at END,
+			disabled_reason = CASE WHEN $3 THEN NULL ELSE disabled_reason END,
+			updated_at = $4
+		WHERE id = $5 AND `+scope.owns("", "$6"),
+		updated.URL, pq.Array(updated.EventTypes), updated.IsActive, time.Now(), webhookID, scope.arg())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action":  "updated",
+		"changes": changes,
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "update",
+		Entity:   "webhook",
+		EntityID: webhookID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetWebhook(ctx, scope, webhookID)
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, scope Scope, webhookID string) error {
+	userID := scope.UserID
+
+	webhook, err := s.GetWebhook(ctx, scope, webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err := requireWorkspaceRole(ctx, s.db, scope, "admin"); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE id = $1 AND `+scope.owns("", "$2"), webhookID, scope.arg()); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	logDetails := map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"url":         webhook.URL,
+			"event_types": webhook.EventTypes,
+		},
+	}
+	detailsJSON, _ := json.Marshal(logDetails)
+
+	if err := s.logService.Record(ctx, tx, &UserAction{
+		UserID:   userID,
+		Action:   "delete",
+		Entity:   "webhook",
+		EntityID: webhookID,
+		Details:  string(detailsJSON),
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const deliverySelect = `SELECT id, webhook_id, event_id, event_type, payload, status, attempts,
+	response_code, COALESCE(response_body, ''), COALESCE(error, ''), duration_ms,
+	next_attempt_at, redelivery_of, created_at, delivered_at
+	FROM webhook_deliveries`
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.ResponseCode, &d.ResponseBody, &d.Error, &d.DurationMs,
+		&d.NextAttemptAt, &d.RedeliveryOf, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	if d.Status != "pending" {
+		d.NextAttemptAt = nil
+	}
+	return &d, nil
+}
+
+// GetDeliveries returns the delivery log of a webhook, newest first.
+func (s *WebhookService) GetDeliveries(ctx context.Context, scope Scope, webhookID string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, scope, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		deliverySelect+` WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`,
+		webhookID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (s *WebhookService) getDelivery(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	row := s.db.QueryRowContext(ctx, deliverySelect+` WHERE id = $1 AND webhook_id = $2`, deliveryID, webhookID)
+	delivery, err := scanDelivery(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues a copy of an earlier delivery. The original entry stays
+// in the log unchanged.
+func (s *WebhookService) Redeliver(ctx context.Context, scope Scope, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, scope, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireWorkspaceRole(ctx, s.db, scope, "admin"); err != nil {
+		return nil, err
+	}
+	if !webhook.IsActive {
+		return nil, fmt.Errorf("webhook is disabled")
+	}
+
+	original, err := s.getDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, redelivery_of)
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING id`,
+		webhookID, original.EventID, original.EventType, string(original.Payload), original.ID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue redelivery: %w", err)
+	}
+
+	return s.getDelivery(ctx, webhookID, id)
+}
+
+// SendTest delivers a webhook.test event right away and returns the result,
+// so an endpoint can be checked without waiting for a real event.
+func (s *WebhookService) SendTest(ctx context.Context, scope Scope, webhookID string) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, scope, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireWorkspaceRole(ctx, s.db, scope, "admin"); err != nil {
+		return nil, err
+	}
+	if !webhook.IsActive {
+		return nil, fmt.Errorf("webhook is disabled")
+	}
+
+	eventID := uuid.New().String()
+	data, _ := json.Marshal(map[string]string{"webhook_id": webhookID})
+	payload, err := json.Marshal(webhookPayload{
+		ID:         eventID,
+		Type:       webhookTestEvent,
+		Version:    events.SchemaVersion,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal test event: %w", err)
+	}
+
+	var id string
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at)
+         VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+         RETURNING id`,
+		webhookID, eventID, webhookTestEvent, string(payload), int(webhookTimeout.Seconds())*2).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test delivery: %w", err)
+	}
+
+	s.attempt(ctx, &pendingDelivery{
+		id:        id,
+		webhookID: webhookID,
+		url:       webhook.URL,
+		eventID:   eventID,
+		eventType: webhookTestEvent,
+		payload:   payload,
+	})
+
+	return s.getDelivery(ctx, webhookID, id)
+}
+
+// webhookPayload is the JSON body sent to endpoints.
+type webhookPayload struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// HandleEvent queues a delivery for every active endpoint subscribed to the
+// event's type in the personal space of its user or in the workspace it
+// happened in. It is run by a consumer group per stream, and an event
+// delivered twice is queued once.
+func (s *WebhookService) HandleEvent(ctx context.Context, event *events.Event) error {
+	if event.UserID == "" {
+		return nil
+	}
+
+	space, err := s.eventSpace(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		ID:         event.ID,
+		Type:       event.Type,
+		Version:    event.Version,
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4 FROM webhooks
+		WHERE `+space.owns("", "$1")+` AND is_active AND $3 = ANY(event_types)
+		ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING`,
+		space.arg(), event.ID, event.Type, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// eventSpace returns the space whose webhooks receive an event. An event
+// about an account's data goes to the account's space, whoever made the
+// change: a member writing to a shared account notifies the owner, not
+// themselves. Other events, and events about deleted accounts, go to the
+// workspace in their payload, or to the acting user's personal space.
+func (s *WebhookService) eventSpace(ctx context.Context, event *events.Event) (Scope, error) {
+	actor := Scope{UserID: event.UserID}
+
+	var data struct {
+		WorkspaceID *string `json:"workspace_id"`
+		AccountID   string  `json:"account_id"`
+	}
+	if err := event.Decode(&data); err != nil {
+		return actor, nil
+	}
+	if data.WorkspaceID != nil {
+		actor.WorkspaceID = *data.WorkspaceID
+	}
+	if data.AccountID == "" {
+		return actor, nil
+	}
+
+	space, err := accountSpace(ctx, s.db, data.AccountID)
+	if err != nil {
+		// The account was deleted, which only its owner can do
+		if err.Error() == "account not found" {
+			return actor, nil
+		}
+		return Scope{}, err
+	}
+	return space, nil
+}
+
+type pendingDelivery struct {
+	id        string
+	webhookID string
+	url       string
+	secret    string
+	eventID   string
+	eventType string
+	payload   []byte
+	attempts  int
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := s.dispatchBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Webhook dispatch failed: %v", err)
+			}
+			if err != nil || sent < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch claims due deliveries by pushing their next attempt past
+// the request timeout, so other instances skip them, then sends them.
+func (s *WebhookService) dispatchBatch(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE d.id IN (
+			SELECT d2.id FROM webhook_deliveries d2
+			JOIN webhooks w2 ON w2.id = d2.webhook_id
+			WHERE d2.status = 'pending' AND d2.next_attempt_at <= NOW() AND w2.is_active
+			ORDER BY d2.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d2 SKIP LOCKED
+		) AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_id, d.event_type, d.payload, d.attempts`,
+		webhookBatchSize, int(webhookTimeout.Seconds())*2)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	batch := []*pendingDelivery{}
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.id, &d.webhookID, &d.url, &d.secret, &d.eventID, &d.eventType, &d.payload, &d.attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		batch = append(batch, &d)
+	}
+	rows.Close()
+
+	var wg sync.WaitGroup
+	for _, d := range batch {
+		wg.Add(1)
+		go func(d *pendingDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+
+	return len(batch), nil
+}
+
+// attempt sends one delivery and records the outcome.
+func (s *WebhookService) attempt(ctx context.Context, d *pendingDelivery) {
+	if d.secret == "" {
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT secret FROM webhooks WHERE id = $1`, d.webhookID).Scan(&d.secret); err != nil {
+			log.Printf("Failed to load secret for webhook %s: %v", d.webhookID, err)
+			return
+		}
+	}
+
+	start := time.Now()
+	code, body, sendErr := s.send(ctx, d, start)
+	duration := int(time.Since(start).Milliseconds())
+
+	if ctx.Err() != nil {
+		// Shutting down: the claim expires and the delivery is retried
+		return
+	}
+
+	var responseCode *int
+	if code != 0 {
+		responseCode = &code
+	}
+
+	if sendErr == nil {
+		s.recordSuccess(ctx, d, responseCode, body, duration)
+		return
+	}
+	s.recordFailure(ctx, d, responseCode, body, duration, sendErr)
+}
+
+// send posts the delivery's payload signed as of now and returns the
+// response status and the start of its body.
+func (s *WebhookService) send(ctx context.Context, d *pendingDelivery, now time.Time) (int, string, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FinTrack-Webhooks/1")
+	req.Header.Set("X-Webhook-ID", d.webhookID)
+	req.Header.Set("X-Webhook-Event", d.eventType)
+	req.Header.Set("X-Webhook-Delivery", d.id)
+	req.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+webhookSignature(d.secret, timestamp, d.payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxBodyLog))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(raw), fmt.Errorf("endpoint responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(raw), nil
+}
+
+func webhookSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetry returns the status of a delivery after its attempts-th
+// failed attempt and how long until the next one.
+func webhookRetry(attempts int) (string, time.Duration) {
+	backoff := webhookBaseBackoff * time.Duration(1<<uint(attempts-1))
+	if attempts >= webhookMaxAttempts {
+		return "failed", backoff
+	}
+	return "pending", backoff
+}
+
+func (s *WebhookService) recordSuccess(ctx context.Context, d *pendingDelivery, code *int, body string, duration int) {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = 'succeeded', attempts = attempts + 1,
+			response_code = $1, response_body = $2, error = NULL, duration_ms = $3, delivered_at = NOW()
+		WHERE id = $4`,
+		code, body, duration, d.id)
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", d.id, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE webhooks SET failure_count = 0 WHERE id = $1 AND failure_count > 0`, d.webhookID); err != nil {
+		log.Printf("Failed to reset failure count of webhook %s: %v", d.webhookID, err)
+	}
+}
+
+// recordFailure schedules a retry with exponential backoff, gives up on the
+// delivery after webhookMaxAttempts and disables the endpoint after
+// webhookDisableAfter consecutive failures.
+func (s *WebhookService) recordFailure(ctx context.Context, d *pendingDelivery, code *int, body string, duration int, cause error) {
+	attempts := d.attempts + 1
+	status, backoff := webhookRetry(attempts)
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $1, attempts = $2,
+			response_code = $3, response_body = $4, error = $5, duration_ms = $6,
+			next_attempt_at = NOW() + $7 * INTERVAL '1 second'
+		WHERE id = $8`,
+		status, attempts, code, body, cause.Error(), duration, int(backoff.Seconds()), d.id)
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", d.id, err)
+	}
+
+	var (
+		userID   string
+		disabled bool
+	)
+	err = s.db.QueryRowContext(ctx,
+		`UPDATE webhooks SET failure_count = failure_count + 1,
+			is_active = failure_count + 1 < $2,
+			disabled_at = CASE WHEN failure_count + 1 >= $2 THEN NOW() ELSE disabled_at END,
+			disabled_reason = CASE WHEN failure_count + 1 >= $2 THEN $3 ELSE disabled_reason END
+		WHERE id = $1 AND is_active
+		RETURNING user_id, NOT is_active`,
+		d.webhookID, webhookDisableAfter,
+		fmt.Sprintf("disabled after %d consecutive failed deliveries", webhookDisableAfter)).Scan(&userID, &disabled)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to update failure count of webhook %s: %v", d.webhookID, err)
+		}
+		return
+	}
+
+	if disabled {
+		log.Printf("Disabled webhook %s after %d consecutive failures", d.webhookID, webhookDisableAfter)
+
+		detailsJSON, _ := json.Marshal(map[string]interface{}{
+			"action": "disabled",
+			"data": map[string]interface{}{
+				"url":        d.url,
+				"last_error": cause.Error(),
+			},
+		})
+		if err := s.logService.Log(ctx, &UserAction{
+			UserID:   userID,
+			Action:   "update",
+			Entity:   "webhook",
+			EntityID: d.webhookID,
+			Details:  string(detailsJSON),
+		}); err != nil {
+			log.Printf("Failed to log disabling of webhook %s: %v", d.webhookID, err)
+		}
+	}
+}
+
+func (s *WebhookService) validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https url")
+	}
+	return s.guard.checkHost(ctx, u.Hostname())
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-service/internal/config"
+)
+
+func newTestWebhookService(t *testing.T, allowed ...string) *WebhookService {
+	t.Helper()
+	s, err := NewWebhookService(nil, nil, &config.Config{WebhookAllowedNetworks: allowed})
+	if err != nil {
+		t.Fatalf("NewWebhookService: %v", err)
+	}
+	return s
+}
+
+func testDelivery(url string) *pendingDelivery {
+	return &pendingDelivery{
+		id:        "delivery-1",
+		webhookID: "webhook-1",
+		url:       url,
+		secret:    "whsec_test",
+		eventType: "transaction.created",
+		payload:   []byte(`{"id":"event-1","type":"transaction.created"}`),
+	}
+}
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header.Clone(), body: body}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, "127.0.0.1", "::1")
+	d := testDelivery(server.URL)
+	now := time.Unix(1700000000, 0)
+
+	code, body, err := s.send(context.Background(), d, now)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if code != http.StatusOK || body != "ok" {
+		t.Fatalf("send = %d %q, want 200 \"ok\"", code, body)
+	}
+
+	r := <-got
+	if string(r.body) != string(d.payload) {
+		t.Errorf("body = %s, want %s", r.body, d.payload)
+	}
+	for header, want := range map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-ID":       d.webhookID,
+		"X-Webhook-Event":    d.eventType,
+		"X-Webhook-Delivery": d.id,
+	} {
+		if value := r.header.Get(header); value != want {
+			t.Errorf("%s = %q, want %q", header, value, want)
+		}
+	}
+
+	// Checked the way a receiver would
+	signature := r.header.Get("X-Webhook-Signature")
+	timestamp, mac, ok := strings.Cut(strings.TrimPrefix(signature, "t="), ",v1=")
+	if !ok || timestamp != "1700000000" {
+		t.Fatalf("X-Webhook-Signature = %q, want t=1700000000,v1=<mac>", signature)
+	}
+	expected := hmac.New(sha256.New, []byte(d.secret))
+	expected.Write([]byte(timestamp + "."))
+	expected.Write(r.body)
+	if !hmac.Equal([]byte(mac), []byte(hex.EncodeToString(expected.Sum(nil)))) {
+		t.Errorf("signature %s does not match the payload", mac)
+	}
+}
+
+func TestWebhookSendFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", 2*webhookMaxBodyLog)))
+		}
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, "127.0.0.1", "::1")
+
+	code, body, err := s.send(context.Background(), testDelivery(server.URL+"/error"), time.Now())
+	if err == nil || code != http.StatusInternalServerError {
+		t.Errorf("send to failing endpoint = %d, %v; want 500 and an error", code, err)
+	}
+	if len(body) != webhookMaxBodyLog {
+		t.Errorf("kept %d bytes of the response, want %d", len(body), webhookMaxBodyLog)
+	}
+
+	code, _, err = s.send(context.Background(), testDelivery(server.URL+"/redirect"), time.Now())
+	if err == nil || code != http.StatusFound {
+		t.Errorf("send to redirecting endpoint = %d, %v; want 302 and an error", code, err)
+	}
+}
+
+func TestWebhookSendRefusesNonPublicAddresses(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t)
+
+	_, _, err := s.send(context.Background(), testDelivery(server.URL), time.Now())
+	if !errors.Is(err, errWebhookAddress) {
+		t.Errorf("send to %s: err = %v, want %v", server.URL, err, errWebhookAddress)
+	}
+	if hits != 0 {
+		t.Errorf("endpoint was reached %d times", hits)
+	}
+}
+
+func TestWebhookValidateURL(t *testing.T) {
+	s := newTestWebhookService(t)
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"http://[2606:4700:4700::1111]/hook", true},
+		{"ftp://93.184.216.34/hook", false},
+		{"/relative", false},
+		{"http://127.0.0.1:8082/api/v1/accounts", false},
+		{"http://localhost/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.18.0.3:8081/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://0.0.0.0:6379/", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[64:ff9b::7f00:1]/hook", false},
+	}
+	for _, tt := range tests {
+		err := s.validateURL(context.Background(), tt.url)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateURL(%q) = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestWebhookAllowedNetworks(t *testing.T) {
+	s := newTestWebhookService(t, "127.0.0.1", "172.16.0.0/12")
+
+	for _, url := range []string{"http://127.0.0.1:9000/hook", "http://172.18.0.3/hook"} {
+		if err := s.validateURL(context.Background(), url); err != nil {
+			t.Errorf("validateURL(%q) = %v, want allowed", url, err)
+		}
+	}
+	if err := s.validateURL(context.Background(), "http://127.0.0.2/hook"); err == nil {
+		t.Error("validateURL allowed an address outside the allowed networks")
+	}
+
+	if _, err := NewWebhookService(nil, nil, &config.Config{WebhookAllowedNetworks: []string{"not-a-network"}}); err == nil {
+		t.Error("NewWebhookService accepted an invalid allowed network")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		attempts int
+		status   string
+		backoff  time.Duration
+	}{
+		{1, "pending", 30 * time.Second},
+		{2, "pending", time.Minute},
+		{3, "pending", 2 * time.Minute},
+		{webhookMaxAttempts - 1, "pending", webhookBaseBackoff << (webhookMaxAttempts - 2)},
+		{webhookMaxAttempts, "failed", webhookBaseBackoff << (webhookMaxAttempts - 1)},
+	}
+	for _, tt := range tests {
+		status, backoff := webhookRetry(tt.attempts)
+		if status != tt.status || backoff != tt.backoff {
+			t.Errorf("webhookRetry(%d) = %s, %s; want %s, %s", tt.attempts, status, backoff, tt.status, tt.backoff)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"api-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Workspace roles from least to most privileged. The owner is also stored
+// in workspace_members so that membership checks need a single lookup.
+var workspaceRoleRank = map[string]int{
+	"viewer": 1,
+	"member": 2,
+	"admin":  3,
+	"owner":  4,
+}
+
+// Scope is the space a request works in. An empty WorkspaceID is the user's
+// personal space: rows without a workspace that belong to the user.
+type Scope struct {
+	UserID      string
+	WorkspaceID string
+}
+
+// arg is the value bound to the placeholder used by owns and accounts.
+func (sc Scope) arg() interface{} {
+	if sc.WorkspaceID == "" {
+		return sc.UserID
+	}
+	return sc.WorkspaceID
+}
+
+// workspace is the value stored in workspace_id columns.
+func (sc Scope) workspace() *string {
+	if sc.WorkspaceID == "" {
+		return nil
+	}
+	return &sc.WorkspaceID
+}
+
+// owns returns a condition limiting the rows of alias to the scope.
+func (sc Scope) owns(alias, param string) string {
+	if alias != "" {
+		alias += "."
+	}
+	if sc.WorkspaceID == "" {
+		return fmt.Sprintf("(%[1]sworkspace_id IS NULL AND %[1]suser_id = %[2]s)", alias, param)
+	}
+	return fmt.Sprintf("%sworkspace_id = %s", alias, param)
+}
+
+// accounts returns a subquery of account IDs visible in the scope. Accounts
+// shared with the user directly show up in their personal space.
+func (sc Scope) accounts(param string) string {
+	if sc.WorkspaceID == "" {
+		return fmt.Sprintf(
+			`(SELECT id FROM accounts WHERE workspace_id IS NULL AND user_id = %[1]s UNION SELECT account_id FROM account_members WHERE user_id = %[1]s)`,
+			param)
+	}
+	return fmt.Sprintf(`(SELECT id FROM accounts WHERE workspace_id = %s)`, param)
+}
+
+// requireWorkspaceRole checks the user has at least minRole in the scope's
+// workspace. The personal space is always writable by its user.
+func requireWorkspaceRole(ctx context.Context, q rowQuerier, scope Scope, minRole string) error {
+	if scope.WorkspaceID == "" {
+		return nil
+	}
+	role, err := workspaceRole(ctx, q, scope.UserID, scope.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	if workspaceRoleRank[role] < workspaceRoleRank[minRole] {
+		return fmt.Errorf("insufficient workspace permissions")
+	}
+	return nil
+}
+
+// accountSpace returns the space an account belongs to: its workspace, or
+// its owner's personal space. Data derived from an account's transactions,
+// such as payees, lives there.
+func accountSpace(ctx context.Context, q rowQuerier, accountID string) (Scope, error) {
+	var space Scope
+	var workspaceID sql.NullString
+	err := q.QueryRowContext(ctx,
+		`SELECT user_id, workspace_id FROM accounts WHERE id = $1`,
+		accountID).Scan(&space.UserID, &workspaceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Scope{}, fmt.Errorf("account not found")
+		}
+		return Scope{}, fmt.Errorf("failed to get account: %w", err)
+	}
+	space.WorkspaceID = workspaceID.String
+	return space, nil
+}
+
+func workspaceRole(ctx context.Context, q rowQuerier, userID, workspaceID string) (string, error) {
+	if _, err := uuid.Parse(workspaceID); err != nil {
+		return "", fmt.Errorf("workspace not found")
+	}
+
+	var role string
+	err := q.QueryRowContext(ctx,
+		`SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("workspace not found")
+		}
+		return "", fmt.Errorf("failed to check workspace access: %w", err)
+	}
+	return role, nil
+}
+
+type WorkspaceService struct {
+	db         *sql.DB
+	logService *LogService
+}
+
+func NewWorkspaceService(db *sql.DB, logService *LogService) *WorkspaceService {
+	return &WorkspaceService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+// VerifyMember reports whether the user belongs to the workspace. It backs
+// the middleware that selects the active workspace.
+func (s *WorkspaceService) VerifyMember(ctx context.Context, userID, workspaceID string) error {
+	_, err := workspaceRole(ctx, s.db, userID, workspaceID)
+	return err
+}
+
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userID string, req *models.CreateWorkspaceRequest) (*models.Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	workspace := &models.Workspace{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(req.Name),
+		OwnerID:     userID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Role:        "owner",
+		MemberCount: 1,
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO workspaces (id, name, owner_id, created_at, updated_at)
+         VALUES ($1, $2, $3, $4, $5)`,
+		workspace.ID, workspace.Name, workspace.OwnerID, workspace.CreatedAt, workspace.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role, joined_at)
+         VALUES ($1, $2, 'owner', $3)`,
+		workspace.ID, userID, workspace.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add workspace owner: %w", err)
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "create", workspace.ID, map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":   workspace.ID,
+			"name": workspace.Name,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return workspace, nil
+}
+
+const workspaceColumns = `w.id, w.name, w.owner_id, w.created_at, w.updated_at, wm.role,
+		(SELECT COUNT(*) FROM workspace_members c WHERE c.workspace_id = w.id)`
+
+func scanWorkspace(row rowScanner) (*models.Workspace, error) {
+	var w models.Workspace
+	err := row.Scan(&w.ID, &w.Name, &w.OwnerID, &w.CreatedAt, &w.UpdatedAt, &w.Role, &w.MemberCount)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *WorkspaceService) GetWorkspaces(ctx context.Context, userID string) ([]*models.Workspace, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+workspaceColumns+`
+         FROM workspaces w
+         JOIN workspace_members wm ON wm.workspace_id = w.id AND wm.user_id = $1
+         ORDER BY w.name ASC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := []*models.Workspace{}
+	for rows.Next() {
+		w, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		workspaces = append(workspaces, w)
+	}
+
+	return workspaces, nil
+}
+
+func (s *WorkspaceService) GetWorkspace(ctx context.Context, userID, workspaceID string) (*models.Workspace, error) {
+	if _, err := uuid.Parse(workspaceID); err != nil {
+		return nil, fmt.Errorf("workspace not found")
+	}
+
+	w, err := scanWorkspace(s.db.QueryRowContext(ctx,
+		`SELECT `+workspaceColumns+`
+         FROM workspaces w
+         JOIN workspace_members wm ON wm.workspace_id = w.id AND wm.user_id = $1
+         WHERE w.id = $2`,
+		userID, workspaceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workspace not found")
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	return w, nil
+}
+
+func (s *WorkspaceService) UpdateWorkspace(ctx context.Context, userID, workspaceID string, req *models.UpdateWorkspaceRequest) (*models.Workspace, error) {
+	old, err := s.GetWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceRoleRank[old.Role] < workspaceRoleRank["admin"] {
+		return nil, fmt.Errorf("insufficient workspace permissions")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == old.Name {
+		return old, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE workspaces SET name = $1, updated_at = $2 WHERE id = $3`,
+		name, time.Now(), workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workspace: %w", err)
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "update", workspaceID, map[string]interface{}{
+		"action": "updated",
+		"changes": map[string]interface{}{
+			"name": map[string]interface{}{
+				"old": old.Name,
+				"new": name,
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetWorkspace(ctx, userID, workspaceID)
+}
+
+// DeleteWorkspace removes a workspace with its categories, budgets and goals.
+// Accounts hold money and history, so they must be deleted first.
+func (s *WorkspaceService) DeleteWorkspace(ctx context.Context, userID, workspaceID string) error {
+	workspace, err := s.GetWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.Role != "owner" {
+		return fmt.Errorf("insufficient workspace permissions")
+	}
+
+	var accountCount int
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM accounts WHERE workspace_id = $1`,
+		workspaceID).Scan(&accountCount)
+	if err != nil {
+		return fmt.Errorf("failed to check accounts: %w", err)
+	}
+	if accountCount > 0 {
+		return fmt.Errorf("cannot delete workspace with existing accounts")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspaces WHERE id = $1`, workspaceID); err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "delete", workspaceID, map[string]interface{}{
+		"action": "deleted",
+		"data": map[string]interface{}{
+			"name":         workspace.Name,
+			"member_count": workspace.MemberCount,
+		},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WorkspaceService) GetMembers(ctx context.Context, userID, workspaceID string) ([]*models.WorkspaceMember, error) {
+	if _, err := workspaceRole(ctx, s.db, userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT wm.workspace_id, u.id, u.email, wm.role, wm.invited_by, wm.joined_at
+         FROM workspace_members wm JOIN users u ON wm.user_id = u.id
+         WHERE wm.workspace_id = $1
+         ORDER BY wm.joined_at ASC`,
+		workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.WorkspaceMember{}
+	for rows.Next() {
+		var m models.WorkspaceMember
+		var invitedBy sql.NullString
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Email, &m.Role, &invitedBy, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		if invitedBy.Valid {
+			m.InvitedBy = &invitedBy.String
+		}
+		members = append(members, &m)
+	}
+
+	return members, nil
+}
+
+// InviteMember adds a registered user to the workspace. Admins can invite
+// viewers and members; only the owner appoints admins.
+func (s *WorkspaceService) InviteMember(ctx context.Context, userID, workspaceID string, req *models.InviteWorkspaceMemberRequest) (*models.WorkspaceMember, error) {
+	role, err := workspaceRole(ctx, s.db, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspaceRoleRank[role] < workspaceRoleRank["admin"] ||
+		(req.Role == "admin" && role != "owner") {
+		return nil, fmt.Errorf("insufficient workspace permissions")
+	}
+
+	member := &models.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		Role:        req.Role,
+		InvitedBy:   &userID,
+		JoinedAt:    time.Now(),
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`SELECT id, email FROM users WHERE LOWER(email) = LOWER($1)`,
+		strings.TrimSpace(req.Email)).Scan(&member.UserID, &member.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role, invited_by, joined_at)
+         VALUES ($1, $2, $3, $4, $5)`,
+		member.WorkspaceID, member.UserID, member.Role, member.InvitedBy, member.JoinedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("user is already a member of this workspace")
+		}
+		return nil, fmt.Errorf("failed to add workspace member: %w", err)
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "update", workspaceID, map[string]interface{}{
+		"action": "member_added",
+		"data": map[string]interface{}{
+			"user_id": member.UserID,
+			"email":   member.Email,
+			"role":    member.Role,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return member, nil
+}
+
+func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, userID, workspaceID, memberID string, req *models.UpdateWorkspaceMemberRequest) error {
+	role, err := workspaceRole(ctx, s.db, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	oldRole, err := s.memberRole(ctx, workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	// Admins manage viewers and members; admins are managed by the owner
+	if oldRole == "owner" || workspaceRoleRank[role] < workspaceRoleRank["admin"] ||
+		(role != "owner" && (oldRole == "admin" || req.Role == "admin")) {
+		return fmt.Errorf("insufficient workspace permissions")
+	}
+
+	if oldRole == req.Role {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3`,
+		req.Role, workspaceID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace member: %w", err)
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "update", workspaceID, map[string]interface{}{
+		"action": "member_updated",
+		"changes": map[string]interface{}{
+			"role": map[string]interface{}{
+				"user_id": memberID,
+				"old":     oldRole,
+				"new":     req.Role,
+			},
+		},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember takes a user out of the workspace. Members can always remove
+// themselves to leave, except the owner, who has to delete the workspace.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error {
+	role, err := workspaceRole(ctx, s.db, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	memberRole, err := s.memberRole(ctx, workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if memberRole == "owner" {
+		return fmt.Errorf("workspace owner cannot leave the workspace")
+	}
+
+	if memberID != userID &&
+		(workspaceRoleRank[role] < workspaceRoleRank["admin"] || (role != "owner" && memberRole == "admin")) {
+		return fmt.Errorf("insufficient workspace permissions")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, memberID); err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	action := "member_removed"
+	if memberID == userID {
+		action = "member_left"
+	}
+
+	if err := s.logWorkspaceAction(ctx, tx, userID, "update", workspaceID, map[string]interface{}{
+		"action": action,
+		"data": map[string]interface{}{
+			"user_id": memberID,
+			"role":    memberRole,
+		},
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WorkspaceService) memberRole(ctx context.Context, workspaceID, memberID string) (string, error) {
+	if _, err := uuid.Parse(memberID); err != nil {
+		return "", fmt.Errorf("member not found")
+	}
+
+	var role string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`,
+		workspaceID, memberID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("member not found")
+		}
+		return "", fmt.Errorf("failed to get workspace member: %w", err)
+	}
+	return role, nil
+}
+
+func (s *WorkspaceService) logWorkspaceAction(ctx context.Context, q execer, userID, action, workspaceID string, details map[string]interface{}) error {
+	detailsJSON, _ := json.Marshal(details)
+
+	return s.logService.Record(ctx, q, &UserAction{
+		UserID:   userID,
+		Action:   action,
+		Entity:   "workspace",
+		EntityID: workspaceID,
+		Details:  string(detailsJSON),
+	})
+}
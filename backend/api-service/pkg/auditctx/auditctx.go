@@ -0,0 +1,45 @@
+// Package auditctx carries the details of the request being served through
+// context.Context, so audit entries written deep inside services can record
+// who made the request and from where.
+package auditctx
+
+import (
+	"context"
+	"regexp"
+)
+
+// Headers carrying request details between clients and services.
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderDeviceID  = "X-Device-ID" // set by clients that identify the device or session
+)
+
+// validID limits IDs taken from headers to something safe to store and log.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type Info struct {
+	IP        string
+	UserAgent string
+	RequestID string
+	SessionID string
+}
+
+type contextKey struct{}
+
+// WithInfo returns a copy of ctx carrying info.
+func WithInfo(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the request details stored in ctx, or an empty Info.
+func FromContext(ctx context.Context) *Info {
+	if info, ok := ctx.Value(contextKey{}).(*Info); ok && info != nil {
+		return info
+	}
+	return &Info{}
+}
+
+// ValidID reports whether an ID received in a header can be used as is.
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
@@ -0,0 +1,96 @@
+// Package events publishes domain events to Redis Streams and consumes them
+// through consumer groups.
+//
+// Every event is one stream entry whose "event" field holds the JSON
+// envelope below. Events of one aggregate share a stream, e.g. all
+// transaction.* events go to "events:transaction".
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the envelope version written by this package. Consumers
+// must ignore fields they don't know and reject versions they don't support.
+const SchemaVersion = 1
+
+// Event types.
+const (
+	TransactionCreated = "transaction.created"
+	TransactionUpdated = "transaction.updated"
+	TransactionDeleted = "transaction.deleted"
+	AccountCreated     = "account.created"
+	AccountUpdated     = "account.updated"
+	AccountDeleted     = "account.deleted"
+	UserRegistered     = "user.registered"
+	UserVerified       = "user.verified"
+)
+
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	Source     string          `json:"source"` // service that published it
+	UserID     string          `json:"user_id,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+
+	// StreamID is the Redis entry ID, set on consumed events
+	StreamID string `json:"-"`
+}
+
+// Decode unmarshals the event payload into v.
+func (e *Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Stream returns the stream an event type is published to.
+func Stream(eventType string) string {
+	aggregate := eventType
+	if i := strings.Index(eventType, "."); i > 0 {
+		aggregate = eventType[:i]
+	}
+	return "events:" + aggregate
+}
+
+// DeadLetterStream returns the stream receiving events a consumer group
+// gave up on.
+func DeadLetterStream(stream string) string {
+	return stream + ":dead"
+}
+
+// Payloads, version 1.
+
+type TransactionPayload struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	CategoryID  string    `json:"category_id"`
+	Type        string    `json:"type"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description,omitempty"`
+	PayeeID     *string   `json:"payee_id,omitempty"`
+	Date        time.Time `json:"date"`
+
+	// Previous is the state before a transaction.updated event
+	Previous *TransactionPayload `json:"previous,omitempty"`
+}
+
+type AccountPayload struct {
+	ID          string  `json:"id"`
+	OwnerID     string  `json:"owner_id"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Balance     float64 `json:"balance"`
+	IsDefault   bool    `json:"is_default"`
+}
+
+type UserPayload struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
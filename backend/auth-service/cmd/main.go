@@ -14,6 +14,10 @@ import (
 	"auth-service/internal/handlers"
 	"auth-service/internal/middleware"
 	"auth-service/internal/services"
+	"auth-service/pkg/events"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/ratelimit"
+	"auth-service/pkg/sessions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,9 +43,32 @@ func main() {
 	redisClient := database.ConnectRedis(cfg)
 	defer redisClient.Close()
 
+	// Rate limit counters are shared through Redis by all replicas
+	rateLimits, err := ratelimit.ParsePolicies(cfg.RateLimits)
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMITS:", err)
+	}
+	limiter := ratelimit.New(redisClient, cfg.ServiceName, rateLimits)
+
 	// Initialize services
 	emailService := services.NewEmailService(cfg)
-	authService := services.NewAuthService(db, redisClient, emailService, cfg)
+	publisher := events.NewPublisher(redisClient, cfg.ServiceName)
+	attemptGuard := services.NewAttemptGuard(redisClient, cfg)
+	authService := services.NewAuthService(db, redisClient, emailService, publisher, attemptGuard, cfg)
+
+	if err := authService.SeedAdmins(context.Background(), cfg.AdminEmails); err != nil {
+		log.Printf("Failed to seed admins: %v", err)
+	}
+
+	// Audit entries and events are written to the outbox with each change
+	// and delivered by the relay
+	outboxRelay := services.NewOutboxRelay(db, publisher, cfg)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		outboxRelay.Run(relayCtx)
+	}()
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
@@ -51,6 +78,10 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.AuditContextMiddleware())
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -58,15 +89,35 @@ func main() {
 	})
 
 	// Auth routes
+	requireAuth := middleware.AuthMiddleware(cfg.JWTSecret, sessions.NewChecker(redisClient))
+	userRateLimit := middleware.RateLimitMiddleware(limiter, ratelimit.ByUser)
+
 	api := router.Group("/api/v1/auth")
+	api.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByIP))
 	{
 		api.POST("/register", authHandler.Register)
 		api.POST("/verify-email", authHandler.VerifyEmail)
 		api.POST("/login", authHandler.Login)
-		api.POST("/logout", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.Logout)
+		api.POST("/refresh", authHandler.Refresh)
+		api.POST("/logout", requireAuth, userRateLimit, authHandler.Logout)
 		api.POST("/resend-code", authHandler.ResendVerificationCode)
-		api.POST("/change-password", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.ChangePassword)
-		api.GET("/me", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.GetCurrentUser)
+		api.POST("/change-password", requireAuth, userRateLimit, authHandler.ChangePassword)
+		api.GET("/me", requireAuth, userRateLimit, authHandler.GetCurrentUser)
+
+		// Sessions
+		api.GET("/sessions", requireAuth, userRateLimit, authHandler.GetSessions)
+		api.DELETE("/sessions/:id", requireAuth, userRateLimit, authHandler.RevokeSession)
+		api.POST("/sessions/revoke-others", requireAuth, userRateLimit, authHandler.RevokeOtherSessions)
+	}
+
+	// Admin routes
+	admin := router.Group("/api/v1/auth/users")
+	admin.Use(middleware.RateLimitMiddleware(limiter, ratelimit.ByIP))
+	admin.Use(requireAuth)
+	admin.Use(userRateLimit)
+	{
+		admin.GET("", middleware.RequireRole(jwt.RoleSupport, jwt.RoleAdmin), authHandler.GetUsers)
+		admin.PUT("/:id/role", middleware.RequireRole(jwt.RoleAdmin), authHandler.UpdateUserRole)
 	}
 
 	// Server setup
@@ -96,5 +147,8 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	stopRelay()
+	<-relayDone
+
 	log.Println("Server exiting")
 }
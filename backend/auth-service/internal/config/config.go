@@ -1,8 +1,12 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -22,9 +26,10 @@ type Config struct {
 	RedisPort     string
 	RedisPassword string
 
-	// JWT
-	JWTSecret      string
-	JWTExpiryHours int
+	// JWT access tokens are short-lived; refresh tokens renew them
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 
 	// Email
 	SMTPHost     string
@@ -33,13 +38,44 @@ type Config struct {
 	SMTPPassword string
 
 	// Verification
-	EmailCodeTTL int // in minutes
+	EmailCodeTTL    int    // in minutes
+	CodeMaxAttempts int    // wrong codes before the code is invalidated
+	CodeHashKey     string // HMAC key for stored verification codes, see codeHashKey
+
+	// Brute-force protection for login and email verification
+	AuthMaxAttempts   int // failures per email before a lockout
+	AuthIPMaxAttempts int // failures per IP before a lockout
+	AuthAttemptWindow time.Duration
+	AuthLockout       time.Duration
+
+	// Users promoted to admin on startup
+	AdminEmails []string
+
+	// Internal services
+	APIServiceURL string
+	ServiceSecret string // shared secret for signed service-to-service requests
+
+	// Proxies whose X-Forwarded-For is believed when taking the client IP.
+	// None by default: set it to the address of the reverse proxy in front
+	// of the service. Trusting whole private ranges would let anyone reaching
+	// a published port through the Docker gateway pick their own IP.
+	TrustedProxies []string
+
+	// Rate limit policies in the pkg/ratelimit format; empty for the defaults
+	RateLimits string
 }
 
 func Load() *Config {
-	jwtExpiry, _ := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
+	accessTokenMin, _ := strconv.Atoi(getEnv("ACCESS_TOKEN_TTL_MIN", "15"))
+	refreshTokenDays, _ := strconv.Atoi(getEnv("REFRESH_TOKEN_TTL_DAYS", "30"))
 	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "587"))
 	emailCodeTTL, _ := strconv.Atoi(getEnv("EMAIL_CODE_TTL_MINUTES", "10"))
+	codeMaxAttempts, _ := strconv.Atoi(getEnv("VERIFICATION_MAX_ATTEMPTS", "5"))
+	authMaxAttempts, _ := strconv.Atoi(getEnv("AUTH_MAX_ATTEMPTS", "10"))
+	authIPMaxAttempts, _ := strconv.Atoi(getEnv("AUTH_IP_MAX_ATTEMPTS", "50"))
+	authAttemptWindowMin, _ := strconv.Atoi(getEnv("AUTH_ATTEMPT_WINDOW_MIN", "15"))
+	authLockoutMin, _ := strconv.Atoi(getEnv("AUTH_LOCKOUT_MIN", "15"))
+	jwtSecret := getEnv("JWT_SECRET", "")
 
 	return &Config{
 		ServiceName:      getEnv("SERVICE_NAME", "auth-service"),
@@ -52,19 +88,56 @@ func Load() *Config {
 		RedisHost:        getEnv("REDIS_HOST", "redis"),
 		RedisPort:        getEnv("REDIS_PORT", "6379"),
 		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-		JWTSecret:        getEnv("JWT_SECRET", ""),
-		JWTExpiryHours:   jwtExpiry,
+		JWTSecret:        jwtSecret,
+		AccessTokenTTL:   time.Duration(accessTokenMin) * time.Minute,
+		RefreshTokenTTL:  time.Duration(refreshTokenDays) * 24 * time.Hour,
 		SMTPHost:         getEnv("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:         smtpPort,
 		SMTPEmail:        getEnv("SMTP_EMAIL", ""),
 		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
 		EmailCodeTTL:     emailCodeTTL,
+		CodeMaxAttempts:  codeMaxAttempts,
+		CodeHashKey:      codeHashKey(jwtSecret),
+		AdminEmails:      splitList(getEnv("ADMIN_EMAILS", "")),
+		APIServiceURL:    strings.TrimRight(getEnv("API_SERVICE_URL", "http://api-service:8082"), "/"),
+		ServiceSecret:    getEnv("SERVICE_SECRET", ""),
+		TrustedProxies:   splitList(getEnv("TRUSTED_PROXIES", "")),
+		RateLimits:       getEnv("RATE_LIMITS", ""),
+
+		AuthMaxAttempts:   authMaxAttempts,
+		AuthIPMaxAttempts: authIPMaxAttempts,
+		AuthAttemptWindow: time.Duration(authAttemptWindowMin) * time.Minute,
+		AuthLockout:       time.Duration(authLockoutMin) * time.Minute,
 	}
 }
 
+// codeHashKey is CODE_HASH_KEY, or else a key derived from the JWT secret
+// for this purpose only: the JWT secret itself never keys anything but
+// tokens, and the derived key doesn't reveal it.
+func codeHashKey(jwtSecret string) string {
+	if key := getEnv("CODE_HASH_KEY", ""); key != "" {
+		return key
+	}
+	key, err := hkdf.Key(sha256.New, []byte(jwtSecret), nil, "fintrack verification code hash", sha256.Size)
+	if err != nil {
+		panic(err) // only for keys longer than 255 hashes
+	}
+	return string(key)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -63,16 +63,76 @@ func createTables(db *sql.DB) error {
 
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);`,
 
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+            CHECK (role IN ('user', 'support', 'admin'));`,
+
 		`CREATE TABLE IF NOT EXISTS email_verifications (
             id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
             user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-            code VARCHAR(6) NOT NULL,
+            code_hash VARCHAR(64) NOT NULL,
+            attempts INT NOT NULL DEFAULT 0,
             expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
             created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
         );`,
 
+		// Codes used to be stored in plaintext; those still pending are
+		// dropped and have to be sent again
+		`ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS code_hash VARCHAR(64);`,
+		`ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;`,
+		`DELETE FROM email_verifications WHERE code_hash IS NULL;`,
+		`ALTER TABLE email_verifications ALTER COLUMN code_hash SET NOT NULL;`,
+		`ALTER TABLE email_verifications DROP COLUMN IF EXISTS code;`,
+
 		`CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);`,
-		`CREATE INDEX IF NOT EXISTS idx_email_verifications_code ON email_verifications(code);`,
+
+		// Refresh tokens are stored as SHA-256 hashes. Tokens rotated from
+		// one login share a family_id; used_at marks a rotated token.
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+            id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+            user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            family_id UUID NOT NULL,
+            token_hash VARCHAR(64) UNIQUE NOT NULL,
+            expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+            used_at TIMESTAMP WITH TIME ZONE,
+            revoked_at TIMESTAMP WITH TIME ZONE,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        );`,
+
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);`,
+
+		// One row per login; its ID is the family_id of its refresh tokens
+		`CREATE TABLE IF NOT EXISTS sessions (
+            id UUID PRIMARY KEY,
+            user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            device_name VARCHAR(100) NOT NULL DEFAULT '',
+            device_id VARCHAR(64) NOT NULL DEFAULT '',
+            ip VARCHAR(45) NOT NULL DEFAULT '',
+            user_agent TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            revoked_at TIMESTAMP WITH TIME ZONE
+        );`,
+
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);`,
+
+		// Audit entries and events written with each change, delivered by
+		// the outbox relay
+		`CREATE TABLE IF NOT EXISTS auth_outbox (
+            id BIGSERIAL PRIMARY KEY,
+            kind VARCHAR(10) NOT NULL CHECK (kind IN ('audit', 'event')),
+            aggregate_type VARCHAR(50) NOT NULL,
+            aggregate_id VARCHAR(64) NOT NULL,
+            payload JSONB NOT NULL,
+            attempts INTEGER NOT NULL DEFAULT 0,
+            last_error TEXT,
+            next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+            delivered_at TIMESTAMP WITH TIME ZONE,
+            failed_at TIMESTAMP WITH TIME ZONE
+        );`,
+
+		`CREATE INDEX IF NOT EXISTS idx_auth_outbox_pending ON auth_outbox(id) WHERE delivered_at IS NULL AND failed_at IS NULL;`,
 
 		`CREATE TABLE IF NOT EXISTS accounts (
             id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"auth-service/internal/models"
 	"auth-service/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -63,8 +66,13 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 
 	err := h.authService.VerifyEmail(c.Request.Context(), &req)
 	if err != nil {
+		if attemptLimited(c, err) {
+			return
+		}
+
 		statusCode := http.StatusBadRequest
-		if err.Error() == "invalid verification code" || err.Error() == "verification code has expired" {
+		if err.Error() == "invalid verification code" || err.Error() == "verification code has expired" ||
+			err.Error() == "too many wrong codes, request a new one" {
 			statusCode = http.StatusBadRequest
 		} else {
 			statusCode = http.StatusInternalServerError
@@ -93,6 +101,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	response, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
+		if attemptLimited(c, err) {
+			return
+		}
+
 		statusCode := http.StatusUnauthorized
 		if err.Error() == "email not verified" {
 			statusCode = http.StatusForbidden
@@ -105,16 +117,54 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   response.Token,
+		"message":       "Login successful",
+		"token":         response.Token,
+		"refresh_token": response.RefreshToken,
+		"expires_in":    response.ExpiresIn,
 		"user": gin.H{
 			"id":       response.User.ID,
 			"email":    response.User.Email,
 			"verified": response.User.Verified,
+			"role":     response.User.Role,
 		},
 	})
 }
 
+// Refresh - новая пара токенов в обмен на refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "invalid refresh token" || err.Error() == "refresh token has expired" ||
+			err.Error() == "refresh token reuse detected" {
+			statusCode = http.StatusUnauthorized
+		} else if err.Error() == "refresh token was already rotated" {
+			// Not revoked: the client should use the token it was rotated to
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         response.Token,
+		"refresh_token": response.RefreshToken,
+		"expires_in":    response.ExpiresIn,
+	})
+}
+
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -124,7 +174,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.Logout(c.Request.Context(), userID.(string))
+	err := h.authService.Logout(c.Request.Context(), userID.(string), c.GetString("sessionID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to logout",
@@ -194,6 +244,7 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 			"id":         user.ID,
 			"email":      user.Email,
 			"verified":   user.Verified,
+			"role":       user.Role,
 			"created_at": user.CreatedAt,
 		},
 	})
@@ -219,7 +270,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ChangePassword(c.Request.Context(), userID.(string), req.CurrentPassword, req.NewPassword)
+	err := h.authService.ChangePassword(c.Request.Context(), userID.(string), c.GetString("sessionID"), req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "current password is incorrect" {
@@ -234,3 +285,163 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		"message": "Password changed successfully",
 	})
 }
+
+// GetSessions - активные сессии пользователя
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(c.Request.Context(), userID.(string), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession - завершение одной сессии
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := h.authService.RevokeSession(c.Request.Context(), userID.(string), c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "session not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeOtherSessions - завершение всех сессий, кроме текущей
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(c.Request.Context(), userID.(string), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
+
+// GetUsers - список пользователей (для поддержки и админов)
+func (h *AuthHandler) GetUsers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	users, err := h.authService.GetUsers(c.Request.Context(), userID.(string), c.Query("email"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"count":  len(users),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// UpdateUserRole - смена роли пользователя (только для админов)
+func (h *AuthHandler) UpdateUserRole(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	targetID := c.Param("id")
+	if _, err := uuid.Parse(targetID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.authService.UpdateUserRole(c.Request.Context(), userID.(string), targetID, req.Role)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			statusCode = http.StatusNotFound
+		} else if err.Error() == "cannot change your own role" {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+		"user":    user,
+	})
+}
+
+// attemptLimited answers 429 with Retry-After when err says the client has
+// to wait before trying again.
+func attemptLimited(c *gin.Context, err error) bool {
+	var limitErr *services.AttemptLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	retryAfter := int(limitErr.RetryAfter.Seconds())
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": retryAfter,
+	})
+	return true
+}
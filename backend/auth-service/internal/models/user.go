@@ -9,6 +9,7 @@ type User struct {
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Verified     bool      `json:"verified" db:"verified"`
+	Role         string    `json:"role" db:"role"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -16,7 +17,8 @@ type User struct {
 type EmailVerification struct {
 	ID        string    `json:"id" db:"id"`
 	UserID    string    `json:"user_id" db:"user_id"`
-	Code      string    `json:"code" db:"code"`
+	CodeHash  string    `json:"-" db:"code_hash"`
+	Attempts  int       `json:"attempts" db:"attempts"`
 	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
@@ -27,8 +29,22 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name" binding:"max=100"` // shown in the session list
+}
+
+// Session is one login, kept until it's revoked or its refresh tokens
+// expire.
+type Session struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	DeviceID   string    `json:"device_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
 }
 
 type VerifyEmailRequest struct {
@@ -36,7 +52,17 @@ type VerifyEmailRequest struct {
 	Code  string `json:"code" binding:"required,len=6"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user support admin"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until Token expires
+	User         *User  `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Guarded actions
+const (
+	attemptLogin  = "login"
+	attemptVerify = "verify"
+)
+
+// Failures allowed before each further attempt has to wait, and the
+// longest wait. The wait doubles with every failure past the free ones.
+const (
+	emailFreeAttempts = 3
+	ipFreeAttempts    = 10
+	attemptDelayBase  = time.Second
+	attemptDelayMax   = time.Minute
+)
+
+// AttemptLimitError is returned while an email or IP has to wait before its
+// next attempt, or is locked out.
+type AttemptLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AttemptLimitError) Error() string {
+	return "too many attempts, try again later"
+}
+
+// AttemptGuard counts failed logins and email verifications per email and
+// per client IP in Redis. Past a few failures each attempt has to wait
+// longer; past the limit the email or IP is locked out for a while. It lets
+// attempts through when Redis is unavailable; the rate limiter still caps
+// them.
+type AttemptGuard struct {
+	redis  *redis.Client
+	config *config.Config
+}
+
+func NewAttemptGuard(redisClient *redis.Client, cfg *config.Config) *AttemptGuard {
+	return &AttemptGuard{
+		redis:  redisClient,
+		config: cfg,
+	}
+}
+
+type attemptSubject struct {
+	key         string
+	freeFails   int64
+	maxFailures int64
+}
+
+func (g *AttemptGuard) subjects(action, email, ip string) []attemptSubject {
+	subjects := []attemptSubject{{
+		key:         fmt.Sprintf("%s:email:%s", action, strings.ToLower(email)),
+		freeFails:   emailFreeAttempts,
+		maxFailures: int64(g.config.AuthMaxAttempts),
+	}}
+	if ip != "" {
+		subjects = append(subjects, attemptSubject{
+			key:         fmt.Sprintf("%s:ip:%s", action, ip),
+			freeFails:   ipFreeAttempts,
+			maxFailures: int64(g.config.AuthIPMaxAttempts),
+		})
+	}
+	return subjects
+}
+
+// Check returns an *AttemptLimitError while the email or the IP must not
+// try again yet.
+func (g *AttemptGuard) Check(ctx context.Context, action, email, ip string) error {
+	var wait time.Duration
+	for _, subject := range g.subjects(action, email, ip) {
+		for _, key := range []string{"auth_lock:" + subject.key, "auth_delay:" + subject.key} {
+			ttl, err := g.redis.PTTL(ctx, key).Result()
+			if err != nil {
+				log.Printf("Failed to check %s attempts: %v", action, err)
+				return nil
+			}
+			wait = max(wait, ttl)
+		}
+	}
+
+	if wait > 0 {
+		return &AttemptLimitError{RetryAfter: (wait + time.Second - 1).Truncate(time.Second)}
+	}
+	return nil
+}
+
+// Fail counts a failed attempt and reports whether it locked the email out.
+func (g *AttemptGuard) Fail(ctx context.Context, action, email, ip string) bool {
+	emailLocked := false
+	for i, subject := range g.subjects(action, email, ip) {
+		key := "auth_attempts:" + subject.key
+
+		pipe := g.redis.TxPipeline()
+		incr := pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, g.config.AuthAttemptWindow)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Failed to count %s attempt: %v", action, err)
+			return false
+		}
+		failures := incr.Val()
+
+		switch {
+		case failures >= subject.maxFailures:
+			// SetNX keeps a lockout from being extended, and notified, twice
+			locked, err := g.redis.SetNX(ctx, "auth_lock:"+subject.key, 1, g.config.AuthLockout).Result()
+			if err != nil {
+				log.Printf("Failed to lock out %s: %v", subject.key, err)
+				continue
+			}
+			g.redis.Del(ctx, key)
+			if locked {
+				log.Printf("Locked out %s after %d failed attempts", subject.key, failures)
+				emailLocked = emailLocked || i == 0
+			}
+		case failures > subject.freeFails:
+			doublings := min(failures-subject.freeFails-1, 10)
+			delay := min(attemptDelayBase<<doublings, attemptDelayMax)
+			if err := g.redis.Set(ctx, "auth_delay:"+subject.key, 1, delay).Err(); err != nil {
+				log.Printf("Failed to delay %s: %v", subject.key, err)
+			}
+		}
+	}
+	return emailLocked
+}
+
+// Succeed clears the email's failures. The IP's failures are kept, so a
+// client can't reset them by signing in to an account of its own.
+func (g *AttemptGuard) Succeed(ctx context.Context, action, email string) {
+	key := g.subjects(action, email, "")[0].key
+	if err := g.redis.Del(ctx, "auth_attempts:"+key, "auth_delay:"+key).Err(); err != nil {
+		log.Printf("Failed to reset %s attempts: %v", action, err)
+	}
+}
@@ -1,23 +1,27 @@
 package services
 
 import (
-	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
-	"net/http"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 
 	"auth-service/internal/config"
 	"auth-service/internal/models"
+	"auth-service/pkg/auditctx"
+	"auth-service/pkg/events"
 	"auth-service/pkg/jwt"
 )
 
@@ -25,14 +29,18 @@ type AuthService struct {
 	db           *sql.DB
 	redis        *redis.Client
 	emailService *EmailService
+	publisher    *events.Publisher
+	attempts     *AttemptGuard
 	config       *config.Config
 }
 
-func NewAuthService(db *sql.DB, redis *redis.Client, emailService *EmailService, cfg *config.Config) *AuthService {
+func NewAuthService(db *sql.DB, redis *redis.Client, emailService *EmailService, publisher *events.Publisher, attempts *AttemptGuard, cfg *config.Config) *AuthService {
 	return &AuthService{
 		db:           db,
 		redis:        redis,
 		emailService: emailService,
+		publisher:    publisher,
+		attempts:     attempts,
 		config:       cfg,
 	}
 }
@@ -68,6 +76,7 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
 		Verified:     false,
+		Role:         jwt.RoleUser,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -105,23 +114,32 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 	}
 
 	// Generate verification code
-	code := s.generateVerificationCode()
-	expiresAt := time.Now().Add(time.Duration(s.config.EmailCodeTTL) * time.Minute)
-
-	_, err = tx.ExecContext(ctx,
-		`INSERT INTO email_verifications (user_id, code, expires_at) 
-         VALUES ($1, $2, $3)`,
-		user.ID, code, expiresAt)
+	code, err := s.issueVerificationCode(ctx, tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
 
+	// The default account is recorded in the audit log
+	err = enqueueAudit(ctx, tx, user.ID, "create", "account", accountID, map[string]interface{}{
+		"action": "created",
+		"data": map[string]interface{}{
+			"id":         accountID,
+			"name":       "Основной счёт",
+			"balance":    0.00,
+			"is_default": true,
+			"source":     "registration",
+		},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to save verification code: %w", err)
+		return nil, err
 	}
 
-	// Store code in Redis with TTL
-	redisKey := fmt.Sprintf("email_verification:%s", user.Email)
-	err = s.redis.Set(ctx, redisKey, code, time.Duration(s.config.EmailCodeTTL)*time.Minute).Err()
+	err = enqueueEvent(ctx, tx, s.publisher, events.UserRegistered, user.ID, user.ID, &events.UserPayload{
+		ID:    user.ID,
+		Email: user.Email,
+	})
 	if err != nil {
-		log.Printf("Failed to store verification code in Redis: %v", err)
+		return nil, err
 	}
 
 	// Send verification email
@@ -135,51 +153,50 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// ✅ Логируем создание дефолтного аккаунта (асинхронно)
-	go s.logDefaultAccountCreation(user.ID, accountID)
-
 	return user, nil
 }
 
 func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRequest) error {
-	// Get code from Redis first
-	redisKey := fmt.Sprintf("email_verification:%s", req.Email)
-	storedCode, err := s.redis.Get(ctx, redisKey).Result()
-
-	if err == nil && storedCode == req.Code {
-		// Code is valid in Redis
-		_, err = s.db.ExecContext(ctx,
-			`UPDATE users SET verified = true WHERE email = $1`,
-			req.Email)
+	ip := auditctx.FromContext(ctx).IP
+	if err := s.attempts.Check(ctx, attemptVerify, req.Email, ip); err != nil {
+		return err
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to verify user: %w", err)
+	if err := s.checkVerificationCode(ctx, req); err != nil {
+		if err.Error() == "invalid verification code" || err.Error() == "too many wrong codes, request a new one" {
+			s.attempts.Fail(ctx, attemptVerify, req.Email, ip)
 		}
+		return err
+	}
 
-		// Delete code from Redis
-		s.redis.Del(ctx, redisKey)
+	s.attempts.Succeed(ctx, attemptVerify, req.Email)
 
-		// Delete from database
-		s.db.ExecContext(ctx,
-			`DELETE FROM email_verifications 
-             WHERE user_id = (SELECT id FROM users WHERE email = $1)`,
-			req.Email)
+	return nil
+}
 
-		return nil
+// checkVerificationCode verifies the user if the code matches their latest
+// one. Every wrong code counts against that code; after CodeMaxAttempts it
+// is deleted and a new one has to be requested.
+func (s *AuthService) checkVerificationCode(ctx context.Context, req *models.VerifyEmailRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Fallback to database check
-	var userID string
+	var verificationID, userID, codeHash string
+	var attempts int
 	var expiresAt time.Time
 
-	err = s.db.QueryRowContext(ctx,
-		`SELECT ev.user_id, ev.expires_at 
+	err = tx.QueryRowContext(ctx,
+		`SELECT ev.id, ev.user_id, ev.code_hash, ev.attempts, ev.expires_at
          FROM email_verifications ev
          JOIN users u ON u.id = ev.user_id
-         WHERE u.email = $1 AND ev.code = $2
+         WHERE u.email = $1
          ORDER BY ev.created_at DESC
-         LIMIT 1`,
-		req.Email, req.Code).Scan(&userID, &expiresAt)
+         LIMIT 1
+         FOR UPDATE OF ev`,
+		req.Email).Scan(&verificationID, &userID, &codeHash, &attempts, &expiresAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -192,8 +209,31 @@ func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRe
 		return errors.New("verification code has expired")
 	}
 
+	if !hmac.Equal([]byte(s.hashVerificationCode(userID, req.Code)), []byte(codeHash)) {
+		attempts++
+		if attempts >= s.config.CodeMaxAttempts {
+			_, err = tx.ExecContext(ctx, `DELETE FROM email_verifications WHERE id = $1`, verificationID)
+			if err == nil {
+				err = tx.Commit()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to invalidate verification code: %w", err)
+			}
+			return errors.New("too many wrong codes, request a new one")
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE email_verifications SET attempts = $1 WHERE id = $2`, attempts, verificationID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to count verification attempt: %w", err)
+		}
+		return errors.New("invalid verification code")
+	}
+
 	// Update user as verified
-	_, err = s.db.ExecContext(ctx,
+	_, err = tx.ExecContext(ctx,
 		`UPDATE users SET verified = true WHERE id = $1`,
 		userID)
 
@@ -202,23 +242,53 @@ func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRe
 	}
 
 	// Delete verification code
-	s.db.ExecContext(ctx,
+	_, err = tx.ExecContext(ctx,
 		`DELETE FROM email_verifications WHERE user_id = $1`,
 		userID)
 
+	if err != nil {
+		return fmt.Errorf("failed to delete verification code: %w", err)
+	}
+
+	err = enqueueEvent(ctx, tx, s.publisher, events.UserVerified, userID, userID, &events.UserPayload{
+		ID:    userID,
+		Email: req.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// dummyPasswordHash is compared against when logging in with an unknown
+// email. Only its cost matters, the same as real password hashes: the login
+// fails whether or not the password matches it.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	ip := auditctx.FromContext(ctx).IP
+	if err := s.attempts.Check(ctx, attemptLogin, req.Email, ip); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	var user models.User
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, email, password_hash, verified, created_at, updated_at 
+		`SELECT id, email, password_hash, verified, role, created_at, updated_at 
          FROM users WHERE email = $1`,
-		req.Email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Verified, &user.CreatedAt, &user.UpdatedAt)
+		req.Email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Verified, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
+			// Take as long as a wrong password, so the response time doesn't
+			// tell which emails have accounts
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+			s.attempts.Fail(ctx, attemptLogin, req.Email, ip)
 			return nil, errors.New("invalid email or password")
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -226,45 +296,71 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		if s.attempts.Fail(ctx, attemptLogin, req.Email, ip) {
+			go s.emailService.SendLockoutNotice(user.Email, ip, s.config.AuthLockout)
+		}
 		return nil, errors.New("invalid email or password")
 	}
+	s.attempts.Succeed(ctx, attemptLogin, req.Email)
 
 	// Check if user is verified
 	if !user.Verified {
 		return nil, errors.New("email not verified")
 	}
 
-	// Generate JWT token
-	token, err := jwt.GenerateToken(user.ID, user.Email, s.config.JWTSecret, s.config.JWTExpiryHours)
+	// Expired refresh tokens are no longer needed for reuse detection
+	_, err = s.db.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`,
+		user.ID)
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		log.Printf("Failed to delete expired refresh tokens: %v", err)
+	}
+
+	// Every login starts a new session and refresh token family
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sessionID, err := s.createSession(ctx, tx, user.ID, req.DeviceName)
+	if err != nil {
+		return nil, err
 	}
 
-	// Store session in Redis
-	sessionKey := fmt.Sprintf("session:%s", user.ID)
-	sessionData := fmt.Sprintf(`{"user_id":"%s","email":"%s","login_time":"%s"}`,
-		user.ID, user.Email, time.Now().Format(time.RFC3339))
+	refreshToken, err := s.issueRefreshToken(ctx, tx, user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
 
-	err = s.redis.Set(ctx, sessionKey, sessionData,
-		time.Duration(s.config.JWTExpiryHours)*time.Hour).Err()
+	// Generate JWT token
+	token, err := jwt.GenerateToken(user.ID, user.Email, user.Role, sessionID, s.config.JWTSecret, s.config.AccessTokenTTL)
 	if err != nil {
-		log.Printf("Failed to store session in Redis: %v", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  &user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.config.AccessTokenTTL.Seconds()),
+		User:         &user,
 	}, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, userID string) error {
-	// Remove session from Redis
-	sessionKey := fmt.Sprintf("session:%s", userID)
-	err := s.redis.Del(ctx, sessionKey).Err()
-	if err != nil {
-		return fmt.Errorf("failed to delete session: %w", err)
+// Logout revokes the session the access token belongs to; AuthMiddleware
+// rejects tokens without one.
+func (s *AuthService) Logout(ctx context.Context, userID, sessionID string) error {
+	if sessionID == "" {
+		return nil
 	}
-	return nil
+
+	_, err := s.revokeSessions(ctx, userID, []string{sessionID}, "", nil)
+	return err
 }
 
 func (s *AuthService) ResendVerificationCode(ctx context.Context, email string) error {
@@ -287,33 +383,20 @@ func (s *AuthService) ResendVerificationCode(ctx context.Context, email string)
 		return errors.New("email already verified")
 	}
 
-	// Delete old verification codes
-	_, err = s.db.ExecContext(ctx,
-		`DELETE FROM email_verifications WHERE user_id = $1`,
-		userID)
-
+	// Replace old verification codes with a new one
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("Failed to delete old verification codes: %v", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Generate new verification code
-	code := s.generateVerificationCode()
-	expiresAt := time.Now().Add(time.Duration(s.config.EmailCodeTTL) * time.Minute)
-
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO email_verifications (user_id, code, expires_at) 
-         VALUES ($1, $2, $3)`,
-		userID, code, expiresAt)
-
+	code, err := s.issueVerificationCode(ctx, tx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to save verification code: %w", err)
+		return err
 	}
 
-	// Store in Redis
-	redisKey := fmt.Sprintf("email_verification:%s", email)
-	err = s.redis.Set(ctx, redisKey, code, time.Duration(s.config.EmailCodeTTL)*time.Minute).Err()
-	if err != nil {
-		log.Printf("Failed to store verification code in Redis: %v", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Send email
@@ -327,9 +410,9 @@ func (s *AuthService) ResendVerificationCode(ctx context.Context, email string)
 func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, email, verified, created_at, updated_at 
+		`SELECT id, email, verified, role, created_at, updated_at 
          FROM users WHERE id = $1`,
-		userID).Scan(&user.ID, &user.Email, &user.Verified, &user.CreatedAt, &user.UpdatedAt)
+		userID).Scan(&user.ID, &user.Email, &user.Verified, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -341,13 +424,53 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*models.U
 	return &user, nil
 }
 
-func (s *AuthService) generateVerificationCode() string {
-	rand.Seed(time.Now().UnixNano())
-	code := fmt.Sprintf("%06d", rand.Intn(1000000))
-	return code
+// issueVerificationCode replaces the user's verification codes with a new
+// one and returns it. Only its hash is stored.
+func (s *AuthService) issueVerificationCode(ctx context.Context, tx *sql.Tx, userID string) (string, error) {
+	code, err := generateVerificationCode()
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(time.Duration(s.config.EmailCodeTTL) * time.Minute)
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM email_verifications WHERE user_id = $1`,
+		userID)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to delete old verification codes: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO email_verifications (user_id, code_hash, expires_at)
+         VALUES ($1, $2, $3)`,
+		userID, s.hashVerificationCode(userID, code), expiresAt)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save verification code: %w", err)
+	}
+
+	return code, nil
+}
+
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashVerificationCode keys the hash with CodeHashKey: a plain hash of a
+// six-digit code is reversed by trying all million of them.
+func (s *AuthService) hashVerificationCode(userID, code string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.CodeHashKey))
+	mac.Write([]byte(userID + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-func (s *AuthService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+// ChangePassword signs the user out of every session but the current one.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
 	// Get user
 	var passwordHash string
 	err := s.db.QueryRowContext(ctx,
@@ -372,8 +495,14 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID, currentPasswor
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Update password
-	_, err = s.db.ExecContext(ctx,
+	_, err = tx.ExecContext(ctx,
 		`UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
 		string(newHashedPassword), userID)
 
@@ -381,56 +510,129 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID, currentPasswor
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	// Invalidate all sessions
-	sessionKey := fmt.Sprintf("session:%s", userID)
-	s.redis.Del(ctx, sessionKey)
+	// Invalidate all other sessions
+	revoked, err := revokeSessionsTx(ctx, tx, userID, nil, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.markRevoked(ctx, revoked)
 
 	return nil
 }
 
-func (s *AuthService) logDefaultAccountCreation(userID, accountID string) {
-	// Отправляем HTTP запрос в API Service для логирования
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// GetUsers lists users for support staff and admins, optionally filtered by
+// an email substring. The access itself is recorded in the audit log.
+func (s *AuthService) GetUsers(ctx context.Context, staffID, email string, limit, offset int) ([]*models.User, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, email, verified, role, created_at, updated_at
+         FROM users
+         WHERE $1 = '' OR email ILIKE '%' || $1 || '%'
+         ORDER BY created_at DESC
+         LIMIT $2 OFFSET $3`,
+		email, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Verified, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
 
-	logData := map[string]interface{}{
-		"action": "created",
+	// Staff access is only returned once it is recorded
+	err = enqueueAudit(ctx, s.db, staffID, "view", "user", "", map[string]interface{}{
+		"action": "admin_access",
 		"data": map[string]interface{}{
-			"id":         accountID,
-			"name":       "Основной счёт",
-			"balance":    0.00,
-			"is_default": true,
-			"source":     "registration",
+			"route":  "users",
+			"email":  email,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(users),
 		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateUserRole changes another user's role. Admins can't change their own
+// role so the last admin can't lock everybody out.
+func (s *AuthService) UpdateUserRole(ctx context.Context, adminID, userID, role string) (*models.User, error) {
+	if adminID == userID {
+		return nil, errors.New("cannot change your own role")
 	}
 
-	jsonData, err := json.Marshal(logData)
+	user, err := s.GetUserByID(ctx, userID)
 	if err != nil {
-		log.Printf("Failed to marshal log data: %v", err)
-		return
+		return nil, err
 	}
 
-	// URL API Service (из env или hardcoded для внутренней сети Docker)
-	apiURL := "http://api-service:8082/api/v1/internal/logs"
+	oldRole := user.Role
+	if oldRole == role {
+		return user, nil
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("Failed to create log request: %v", err)
-		return
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Internal-Service", "auth-service") // Для проверки что запрос изнутри
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET role = $1 WHERE id = $2`,
+		role, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	err = enqueueAudit(ctx, tx, adminID, "update", "user", userID, map[string]interface{}{
+		"action": "role_changed",
+		"changes": map[string]interface{}{
+			"role": map[string]interface{}{
+				"old": oldRole,
+				"new": role,
+			},
+		},
+	})
 	if err != nil {
-		log.Printf("Failed to send log to api-service: %v", err)
-		return
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	user.Role = role
+
+	return user, nil
+}
+
+// SeedAdmins promotes the configured users to admin so a fresh installation
+// has somebody who can manage roles.
+func (s *AuthService) SeedAdmins(ctx context.Context, emails []string) error {
+	if len(emails) == 0 {
+		return nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API service returned non-OK status: %d", resp.StatusCode)
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET role = 'admin' WHERE email = ANY($1) AND role <> 'admin'`,
+		pq.Array(emails))
+	if err != nil {
+		return fmt.Errorf("failed to seed admins: %w", err)
 	}
+	return nil
 }
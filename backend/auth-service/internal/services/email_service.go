@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"auth-service/internal/config"
 
@@ -109,3 +110,46 @@ func (s *EmailService) SendPasswordReset(to, resetLink string) error {
 	log.Printf("Password reset email sent to %s", to)
 	return nil
 }
+
+// SendLockoutNotice tells the owner that sign-in to the account was locked
+// after repeated wrong passwords.
+func (s *EmailService) SendLockoutNotice(to, ip string, lockout time.Duration) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.config.SMTPEmail)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "auth-service - Вход временно заблокирован")
+
+	body := fmt.Sprintf(`
+        <html>
+        <body style="font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px;">
+            <div style="max-width: 600px; margin: 0 auto; background-color: white; padding: 30px; border-radius: 10px; box-shadow: 0 2px 4px rgba(0,0,0,0.1);">
+                <h1 style="color: #333; text-align: center;">auth-service</h1>
+                <h2 style="color: #555; text-align: center;">Вход временно заблокирован</h2>
+                <p style="color: #666; font-size: 16px; line-height: 1.5;">
+                    В вашу учётную запись несколько раз подряд пытались войти с неверным паролем (последняя попытка с IP-адреса %s).
+                    Вход заблокирован на %d мин.
+                </p>
+                <p style="color: #666; font-size: 14px; line-height: 1.5;">
+                    Если это были не вы, после окончания блокировки смените пароль.
+                </p>
+                <hr style="border: none; border-top: 1px solid #eee; margin: 30px 0;">
+                <p style="color: #999; font-size: 12px; text-align: center;">
+                    © 2024 auth-service. Система учёта личных финансов.
+                </p>
+            </div>
+        </body>
+        </html>
+    `, ip, int(lockout.Minutes()))
+
+	m.SetBody("text/html", body)
+
+	d := gomail.NewDialer(s.config.SMTPHost, s.config.SMTPPort, s.config.SMTPEmail, s.config.SMTPPassword)
+
+	if err := d.DialAndSend(m); err != nil {
+		log.Printf("Failed to send lockout notice to %s: %v", to, err)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("Lockout notice sent to %s", to)
+	return nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/pkg/auditctx"
+	"auth-service/pkg/events"
+	"auth-service/pkg/svcauth"
+)
+
+// Outbox entry kinds
+const (
+	outboxAudit = "audit" // delivered to the api-service audit log
+	outboxEvent = "event" // delivered to Redis Streams
+)
+
+func enqueue(ctx context.Context, q execer, kind, aggregateType, aggregateID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO auth_outbox (kind, aggregate_type, aggregate_id, payload) VALUES ($1, $2, $3, $4)`,
+		kind, aggregateType, aggregateID, string(body))
+	if err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// enqueueEvent stores a domain event in the outbox using q, normally the
+// transaction making the change the event describes.
+func enqueueEvent(ctx context.Context, q execer, publisher *events.Publisher, eventType, userID, aggregateID string, data interface{}) error {
+	event, err := publisher.NewEvent(eventType, userID, data)
+	if err != nil {
+		return err
+	}
+
+	return enqueue(ctx, q, outboxEvent, events.Stream(eventType), aggregateID, event)
+}
+
+// auditEntry is the body of an api-service internal log request, with the
+// request details AuditContextMiddleware put into ctx.
+type auditEntry struct {
+	UserID    string                 `json:"user_id"`
+	Action    string                 `json:"action"`
+	Entity    string                 `json:"entity"`
+	EntityID  string                 `json:"entity_id"`
+	Data      map[string]interface{} `json:"data"`
+	IP        string                 `json:"ip"`
+	UserAgent string                 `json:"user_agent"`
+	SessionID string                 `json:"session_id"`
+
+	// Sent in a header, ignored in the body
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// enqueueAudit stores an audit entry in the outbox using q, normally the
+// transaction making the change it records.
+func enqueueAudit(ctx context.Context, q execer, userID, action, entity, entityID string, details map[string]interface{}) error {
+	info := auditctx.FromContext(ctx)
+	aggregateID := entityID
+	if aggregateID == "" {
+		aggregateID = userID
+	}
+
+	return enqueue(ctx, q, outboxAudit, entity, aggregateID, &auditEntry{
+		UserID:    userID,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		Data:      details,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		SessionID: info.SessionID,
+		RequestID: info.RequestID,
+	})
+}
+
+const (
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 15
+	outboxMaxBackoff  = 5 * time.Minute
+	outboxRetention   = 7 * 24 * time.Hour
+	outboxLogTimeout  = 3 * time.Second
+
+	// Only one relay delivers at a time so entries keep their order. The
+	// database is shared: api-service uses 7291001 for its outbox relay and
+	// 7291002 for audit checkpoints
+	outboxLockKey = 7291003
+)
+
+// OutboxRelay delivers outbox entries at least once, in order per
+// aggregate: an entry waiting for a retry holds back later entries of the
+// same aggregate. It mirrors the api-service relay, but delivers audit
+// entries through the signed api-service internal log endpoint instead of
+// writing them to the database.
+type OutboxRelay struct {
+	db        *sql.DB
+	publisher *events.Publisher
+	config    *config.Config
+	interval  time.Duration
+}
+
+func NewOutboxRelay(db *sql.DB, publisher *events.Publisher, cfg *config.Config) *OutboxRelay {
+	return &OutboxRelay{
+		db:        db,
+		publisher: publisher,
+		config:    cfg,
+		interval:  time.Second,
+	}
+}
+
+// Run delivers entries until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		for {
+			delivered, err := r.relayBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Outbox relay failed: %v", err)
+			}
+			// Keep going while there is a backlog
+			if err != nil || delivered < outboxBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type outboxEntry struct {
+	id            int64
+	kind          string
+	aggregateType string
+	aggregateID   string
+	payload       []byte
+	attempts      int
+	due           bool
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, outboxLockKey)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, kind, aggregate_type, aggregate_id, payload, attempts, next_attempt_at <= NOW()
+		FROM auth_outbox
+		WHERE delivered_at IS NULL AND failed_at IS NULL
+		ORDER BY id
+		LIMIT $1`,
+		outboxBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	entries := []*outboxEntry{}
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.id, &e.kind, &e.aggregateType, &e.aggregateID, &e.payload, &e.attempts, &e.due); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	rows.Close()
+
+	delivered := 0
+	blocked := map[string]bool{}
+	for _, e := range entries {
+		aggregate := e.aggregateType + ":" + e.aggregateID
+		if blocked[aggregate] {
+			continue
+		}
+		if !e.due {
+			blocked[aggregate] = true
+			continue
+		}
+
+		if retry, err := r.deliver(ctx, e); err != nil {
+			blocked[aggregate] = true
+			r.markFailed(ctx, e, err, retry)
+			continue
+		}
+
+		if _, err := r.db.ExecContext(ctx,
+			`UPDATE auth_outbox SET delivered_at = NOW() WHERE id = $1`, e.id); err != nil {
+			return delivered, fmt.Errorf("failed to mark outbox entry delivered: %w", err)
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// deliver sends one entry and reports whether a failure is worth retrying.
+func (r *OutboxRelay) deliver(ctx context.Context, e *outboxEntry) (bool, error) {
+	switch e.kind {
+	case outboxEvent:
+		var event events.Event
+		if err := json.Unmarshal(e.payload, &event); err != nil {
+			return false, fmt.Errorf("failed to decode event: %w", err)
+		}
+		return true, r.publisher.PublishEvent(ctx, &event)
+	case outboxAudit:
+		var entry auditEntry
+		if err := json.Unmarshal(e.payload, &entry); err != nil {
+			return false, fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		body, err := withIdempotencyKey(e.payload, strconv.FormatInt(e.id, 10))
+		if err != nil {
+			return false, err
+		}
+		return r.sendLog(ctx, entry.RequestID, body)
+	default:
+		return false, fmt.Errorf("unknown outbox entry kind %q", e.kind)
+	}
+}
+
+// withIdempotencyKey adds the key to an audit entry's body, leaving the
+// rest of it as stored. api-service stores an entry delivered twice under
+// the same key once.
+func withIdempotencyKey(payload []byte, key string) ([]byte, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entry: %w", err)
+	}
+	body["idempotency_key"], _ = json.Marshal(key)
+	return json.Marshal(body)
+}
+
+// sendLog makes one signed request to the api-service internal log endpoint
+// and reports whether a failure is worth retrying.
+func (r *OutboxRelay) sendLog(ctx context.Context, requestID string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, outboxLogTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.config.APIServiceURL+"/api/v1/internal/logs", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create log request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set(auditctx.HeaderRequestID, requestID)
+	}
+	if err := svcauth.Sign(req, r.config.ServiceName, r.config.ServiceSecret, body); err != nil {
+		return false, fmt.Errorf("failed to sign log request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("api-service returned status %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
+
+// markFailed schedules a retry with exponential backoff, or gives up on the
+// entry after outboxMaxAttempts, or right away when retrying can't help, so
+// it stops holding back its aggregate.
+func (r *OutboxRelay) markFailed(ctx context.Context, e *outboxEntry, cause error, retry bool) {
+	attempts := e.attempts + 1
+	backoff := time.Duration(1<<uint(min(attempts, 16))) * time.Second
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+
+	var err error
+	if !retry || attempts >= outboxMaxAttempts {
+		log.Printf("Giving up on outbox entry %d (%s %s:%s) after %d attempts: %v",
+			e.id, e.kind, e.aggregateType, e.aggregateID, attempts, cause)
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE auth_outbox SET attempts = $1, last_error = $2, failed_at = NOW() WHERE id = $3`,
+			attempts, cause.Error(), e.id)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE auth_outbox SET attempts = $1, last_error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second' WHERE id = $4`,
+			attempts, cause.Error(), int(backoff.Seconds()), e.id)
+	}
+	if err != nil {
+		log.Printf("Failed to record outbox failure for entry %d: %v", e.id, err)
+	}
+}
+
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM auth_outbox WHERE delivered_at < NOW() - $1 * INTERVAL '1 second'`,
+		int(outboxRetention.Seconds()))
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Failed to clean up outbox: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/pkg/auditctx"
+	"auth-service/pkg/jwt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// refreshReuseGrace is how long after rotation a refresh token may still be
+// presented, so that a client racing itself (two tabs, a retried request
+// whose response was lost) gets the same successor instead of having its
+// session revoked as if the token had been stolen.
+const refreshReuseGrace = 20 * time.Second
+
+// refreshSuccessorKey holds the token a refresh token was rotated to, for
+// refreshReuseGrace, sealed by sealSuccessor. It is keyed by the hash of the
+// rotated token.
+func refreshSuccessorKey(tokenHash string) string {
+	return "refresh_successor:" + tokenHash
+}
+
+// successorCipher is keyed by the rotated token itself, which the server
+// keeps only hashed: reading Redis is not enough to recover the successor,
+// only presenting the rotated token is.
+func successorCipher(rotatedToken string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, []byte(rotatedToken), nil, "fintrack refresh token successor", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSuccessor(rotatedToken, successor string) ([]byte, error) {
+	aead, err := successorCipher(rotatedToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal refresh token: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to seal refresh token: %w", err)
+	}
+	return aead.Seal(nonce, nonce, []byte(successor), nil), nil
+}
+
+func openSuccessor(rotatedToken string, sealed []byte) (string, error) {
+	aead, err := successorCipher(rotatedToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to open refresh token: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("failed to open refresh token: too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	successor, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to open refresh token: %w", err)
+	}
+	return string(successor), nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Refresh tokens are random, so a plain hash is enough to keep a leaked
+// table from being usable.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken creates a refresh token in the family and returns it.
+func (s *AuthService) issueRefreshToken(ctx context.Context, q execer, userID, familyID string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+         VALUES ($1, $2, $3, $4)`,
+		userID, familyID, hashRefreshToken(token), time.Now().Add(s.config.RefreshTokenTTL))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token of the same family. A refresh token works once: presenting one that
+// was already rotated means somebody else has a copy, so the whole family is
+// revoked and its holders have to log in again. Within refreshReuseGrace of
+// the rotation the token instead gets the successor it was rotated to, as
+// long as that is still the family's current token.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tokenID, familyID string
+	var expiresAt time.Time
+	var usedAt, revokedAt sql.NullTime
+	var inGrace bool
+	var user models.User
+
+	tokenHash := hashRefreshToken(refreshToken)
+	err = tx.QueryRowContext(ctx,
+		`SELECT rt.id, rt.family_id, rt.expires_at, rt.used_at, rt.revoked_at,
+                COALESCE(rt.used_at > NOW() - $2 * INTERVAL '1 second', false),
+                u.id, u.email, u.verified, u.role, u.created_at, u.updated_at
+         FROM refresh_tokens rt
+         JOIN users u ON u.id = rt.user_id
+         WHERE rt.token_hash = $1
+         FOR UPDATE OF rt`,
+		tokenHash, refreshReuseGrace.Seconds()).Scan(&tokenID, &familyID, &expiresAt, &usedAt, &revokedAt, &inGrace,
+		&user.ID, &user.Email, &user.Verified, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invalid refresh token")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if revokedAt.Valid {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if usedAt.Valid && inGrace {
+		return s.refreshAgain(ctx, tx, &user, familyID, refreshToken)
+	}
+
+	if usedAt.Valid {
+		revoked, err := revokeSessionsTx(ctx, tx, user.ID, []string{familyID}, "")
+		if err != nil {
+			return nil, err
+		}
+		err = enqueueAudit(ctx, tx, user.ID, "revoke", "session", familyID, map[string]interface{}{
+			"action": "refresh_token_reused",
+			"data": map[string]interface{}{
+				"family_id": familyID,
+				"used_at":   usedAt.Time,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		s.markRevoked(ctx, revoked)
+
+		log.Printf("Refresh token reused, revoked token family %s of user %s", familyID, user.ID)
+
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, errors.New("refresh token has expired")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		tokenID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(ctx, tx, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set before the commit, so a concurrent request for the same token,
+	// which waits on the row lock, finds it
+	sealed, err := sealSuccessor(refreshToken, newRefreshToken)
+	if err == nil {
+		err = s.redis.Set(ctx, refreshSuccessorKey(tokenHash), sealed, refreshReuseGrace).Err()
+	}
+	if err != nil {
+		log.Printf("Failed to save successor of refresh token %s, presenting it again will fail: %v", tokenID, err)
+	}
+
+	return s.completeRefresh(ctx, tx, &user, familyID, newRefreshToken)
+}
+
+// refreshAgain answers a refresh token presented again within
+// refreshReuseGrace of its rotation with the token it was rotated to.
+func (s *AuthService) refreshAgain(ctx context.Context, tx *sql.Tx, user *models.User, familyID, refreshToken string) (*models.AuthResponse, error) {
+	sealed, err := s.redis.Get(ctx, refreshSuccessorKey(hashRefreshToken(refreshToken))).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Failed to get successor of a rotated refresh token: %v", err)
+		}
+		return nil, errors.New("refresh token was already rotated")
+	}
+	successor, err := openSuccessor(refreshToken, sealed)
+	if err != nil {
+		log.Printf("Failed to get successor of a rotated refresh token: %v", err)
+		return nil, errors.New("refresh token was already rotated")
+	}
+
+	// Only while the successor is unused: once the client has moved on, the
+	// old token gets nothing
+	var current bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+         FROM refresh_tokens
+         WHERE token_hash = $1 AND family_id = $2`,
+		hashRefreshToken(successor), familyID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if !current {
+		return nil, errors.New("refresh token was already rotated")
+	}
+
+	return s.completeRefresh(ctx, tx, user, familyID, successor)
+}
+
+// completeRefresh records the session as used, issues the access token and
+// commits tx.
+func (s *AuthService) completeRefresh(ctx context.Context, tx *sql.Tx, user *models.User, familyID, refreshToken string) (*models.AuthResponse, error) {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP, ip = $1 WHERE id = $2`,
+		auditctx.FromContext(ctx).IP, familyID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	// The role is read again, so a changed role takes effect on refresh
+	token, err := jwt.GenerateToken(user.ID, user.Email, user.Role, familyID, s.config.JWTSecret, s.config.AccessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.config.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
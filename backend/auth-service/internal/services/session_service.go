@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+
+	"auth-service/internal/models"
+	"auth-service/pkg/auditctx"
+	"auth-service/pkg/sessions"
+)
+
+// A session is one login: its ID is the family ID of the refresh tokens
+// rotated from that login, and the sid claim of their access tokens.
+
+// createSession records a new login from the client in ctx.
+func (s *AuthService) createSession(ctx context.Context, q execer, userID, deviceName string) (string, error) {
+	info := auditctx.FromContext(ctx)
+	sessionID := uuid.New().String()
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO sessions (id, user_id, device_name, device_id, ip, user_agent)
+         VALUES ($1, $2, $3, $4, $5, $6)`,
+		sessionID, userID, deviceName, info.SessionID, info.IP, info.UserAgent)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return sessionID, nil
+}
+
+// GetSessions lists the user's sessions that can still be refreshed, most
+// recently used first.
+func (s *AuthService) GetSessions(ctx context.Context, userID, currentID string) ([]*models.Session, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT s.id, s.device_name, s.device_id, s.ip, s.user_agent, s.created_at, s.last_seen_at
+         FROM sessions s
+         WHERE s.user_id = $1 AND s.revoked_at IS NULL
+           AND EXISTS (
+               SELECT 1 FROM refresh_tokens rt
+               WHERE rt.family_id = s.id AND rt.used_at IS NULL AND rt.revoked_at IS NULL
+                 AND rt.expires_at > CURRENT_TIMESTAMP
+           )
+         ORDER BY s.last_seen_at DESC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.DeviceName, &session.DeviceID, &session.IP, &session.UserAgent,
+			&session.CreatedAt, &session.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.Current = session.ID == currentID
+		list = append(list, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	s.mergeLastSeen(ctx, list)
+
+	return list, nil
+}
+
+// mergeLastSeen takes the last use the services recorded in Redis, which
+// is newer than the last refresh recorded in the database.
+func (s *AuthService) mergeLastSeen(ctx context.Context, list []*models.Session) {
+	if len(list) == 0 {
+		return
+	}
+
+	keys := make([]string, len(list))
+	for i, session := range list {
+		keys[i] = sessions.SeenKey(session.ID)
+	}
+
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("Failed to get sessions last seen: %v", err)
+		return
+	}
+
+	for i, value := range values {
+		seen, ok := value.(string)
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(seen, 10, 64)
+		if err != nil {
+			continue
+		}
+		if seenAt := time.Unix(unix, 0); seenAt.After(list[i].LastSeenAt) {
+			list[i].LastSeenAt = seenAt
+		}
+	}
+}
+
+// RevokeSession signs one of the user's sessions out.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errors.New("session not found")
+	}
+
+	revoked, err := s.revokeSessions(ctx, userID, []string{sessionID}, "", func(revoked []string) map[string]interface{} {
+		return map[string]interface{}{
+			"action": "session_revoked",
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(revoked) == 0 {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere but the current session
+// and returns how many sessions were revoked.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentID string) (int, error) {
+	revoked, err := s.revokeSessions(ctx, userID, nil, currentID, func(revoked []string) map[string]interface{} {
+		return map[string]interface{}{
+			"action": "other_sessions_revoked",
+			"data": map[string]interface{}{
+				"sessions": revoked,
+			},
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(revoked), nil
+}
+
+// revokeSessions revokes sessions like revokeSessionsTx. When audit is set
+// and sessions were revoked, the details it returns are recorded in the
+// audit log with the revocation.
+func (s *AuthService) revokeSessions(ctx context.Context, userID string, sessionIDs []string, keepID string,
+	audit func(revoked []string) map[string]interface{}) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revoked, err := revokeSessionsTx(ctx, tx, userID, sessionIDs, keepID)
+	if err != nil {
+		return nil, err
+	}
+
+	if audit != nil && len(revoked) > 0 {
+		entityID := ""
+		if len(sessionIDs) == 1 {
+			entityID = sessionIDs[0]
+		}
+		if err := enqueueAudit(ctx, tx, userID, "revoke", "session", entityID, audit(revoked)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.markRevoked(ctx, revoked)
+	return revoked, nil
+}
+
+// revokeSessionsTx revokes the user's sessions with the IDs, or when
+// sessionIDs is nil, all but keepID, together with their refresh tokens.
+// It returns the sessions revoked; call markRevoked with them after commit.
+func revokeSessionsTx(ctx context.Context, tx *sql.Tx, userID string, sessionIDs []string, keepID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+         WHERE user_id = $1 AND revoked_at IS NULL
+           AND ($2::uuid[] IS NULL OR id = ANY($2::uuid[]))
+           AND id::text <> $3
+         RETURNING id`,
+		userID, pq.Array(sessionIDs), keepID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	defer rows.Close()
+
+	revoked := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		revoked = append(revoked, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// The same condition covers refresh tokens issued before sessions existed
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+         WHERE user_id = $1 AND revoked_at IS NULL
+           AND ($2::uuid[] IS NULL OR family_id = ANY($2::uuid[]))
+           AND family_id::text <> $3`,
+		userID, pq.Array(sessionIDs), keepID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// markRevoked tells the services to reject the sessions' access tokens
+// until the last of them expires.
+func (s *AuthService) markRevoked(ctx context.Context, sessionIDs []string) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+
+	ttl := s.config.AccessTokenTTL + sessions.CacheTTL
+	_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range sessionIDs {
+			pipe.Set(ctx, sessions.RevokedKey(id), 1, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to mark %d session(s) revoked, their access tokens stay valid until they expire: %v", len(sessionIDs), err)
+	}
+}
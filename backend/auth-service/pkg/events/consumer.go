@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler processes one event. Returning an error leaves the event pending
+// so it is delivered again; after MaxRetries deliveries it is dead-lettered.
+type Handler func(ctx context.Context, event *Event) error
+
+type ConsumerConfig struct {
+	Stream   string // e.g. Stream(TransactionCreated)
+	Group    string // consumer group, usually the service name
+	Consumer string // unique per process, e.g. the hostname
+
+	// StartID is where a newly created group starts reading: "$" (the
+	// default) for new events only, "0" for the whole stream.
+	StartID string
+
+	BatchSize  int64         // entries read at once, default 10
+	Block      time.Duration // how long a read waits for new entries, default 5s
+	MaxRetries int64         // deliveries before dead-lettering, default 5
+	RetryAfter time.Duration // idle time before a failed entry is redelivered, default 30s
+}
+
+type Consumer struct {
+	redis   *redis.Client
+	config  ConsumerConfig
+	handler Handler
+}
+
+func NewConsumer(client *redis.Client, cfg ConsumerConfig, handler Handler) *Consumer {
+	if cfg.StartID == "" {
+		cfg.StartID = "$"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = 5 * time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 30 * time.Second
+	}
+
+	return &Consumer{
+		redis:   client,
+		config:  cfg,
+		handler: handler,
+	}
+}
+
+// Run consumes events until ctx is cancelled. Entries that failed earlier,
+// here or in a crashed consumer of the same group, are retried first.
+func (c *Consumer) Run(ctx context.Context) error {
+	if err := c.ensureGroup(ctx); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if err := c.retryPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to retry pending events on %s: %v", c.config.Stream, err)
+		}
+
+		streams, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.config.Group,
+			Consumer: c.config.Consumer,
+			Streams:  []string{c.config.Stream, ">"},
+			Count:    c.config.BatchSize,
+			Block:    c.config.Block,
+		}).Result()
+		if err != nil {
+			if err == redis.Nil || ctx.Err() != nil {
+				continue
+			}
+			log.Printf("Failed to read events from %s: %v", c.config.Stream, err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				c.handle(ctx, msg, 1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replay moves the group's offset so every entry after fromID is delivered
+// again. Use "0" to replay the whole stream.
+func (c *Consumer) Replay(ctx context.Context, fromID string) error {
+	if err := c.ensureGroup(ctx); err != nil {
+		return err
+	}
+
+	if err := c.redis.XGroupSetID(ctx, c.config.Stream, c.config.Group, fromID).Err(); err != nil {
+		return fmt.Errorf("failed to set consumer group offset: %w", err)
+	}
+	return nil
+}
+
+func (c *Consumer) ensureGroup(ctx context.Context) error {
+	err := c.redis.XGroupCreateMkStream(ctx, c.config.Stream, c.config.Group, c.config.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	return nil
+}
+
+// retryPending claims entries that have been pending longer than RetryAfter
+// and handles them again.
+func (c *Consumer) retryPending(ctx context.Context) error {
+	msgs, _, err := c.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   c.config.Stream,
+		Group:    c.config.Group,
+		Consumer: c.config.Consumer,
+		MinIdle:  c.config.RetryAfter,
+		Start:    "0-0",
+		Count:    c.config.BatchSize,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		pending, err := c.redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: c.config.Stream,
+			Group:  c.config.Group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		deliveries := c.config.MaxRetries
+		if len(pending) == 1 {
+			deliveries = pending[0].RetryCount
+		}
+		c.handle(ctx, msg, deliveries)
+	}
+
+	return nil
+}
+
+var errMalformed = errors.New("malformed event")
+
+func (c *Consumer) handle(ctx context.Context, msg redis.XMessage, deliveries int64) {
+	event, err := parseMessage(msg)
+	if err == nil {
+		err = c.handler(ctx, event)
+	}
+
+	if err == nil {
+		c.ack(ctx, msg.ID)
+		return
+	}
+
+	if errors.Is(err, errMalformed) || deliveries >= c.config.MaxRetries {
+		c.deadLetter(ctx, msg, err)
+		return
+	}
+
+	log.Printf("Failed to handle event %s on %s (attempt %d of %d): %v",
+		msg.ID, c.config.Stream, deliveries, c.config.MaxRetries, err)
+}
+
+func (c *Consumer) deadLetter(ctx context.Context, msg redis.XMessage, cause error) {
+	values := map[string]interface{}{
+		"stream":      c.config.Stream,
+		"group":       c.config.Group,
+		"original_id": msg.ID,
+		"error":       cause.Error(),
+		"failed_at":   time.Now().UTC().Format(time.RFC3339),
+	}
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+
+	err := c.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: DeadLetterStream(c.config.Stream),
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		// Keep it pending rather than lose it
+		log.Printf("Failed to dead-letter event %s on %s: %v", msg.ID, c.config.Stream, err)
+		return
+	}
+
+	log.Printf("Dead-lettered event %s on %s: %v", msg.ID, c.config.Stream, cause)
+	c.ack(ctx, msg.ID)
+}
+
+func (c *Consumer) ack(ctx context.Context, id string) {
+	if err := c.redis.XAck(ctx, c.config.Stream, c.config.Group, id).Err(); err != nil {
+		log.Printf("Failed to ack event %s on %s: %v", id, c.config.Stream, err)
+	}
+}
+
+func parseMessage(msg redis.XMessage) (*Event, error) {
+	body, ok := msg.Values["event"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: no event field", errMalformed)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", errMalformed, err)
+	}
+	if event.Version > SchemaVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errMalformed, event.Version)
+	}
+
+	event.StreamID = msg.ID
+	return &event, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
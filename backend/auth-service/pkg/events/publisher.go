@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Streams are trimmed to roughly this many entries.
+const streamMaxLen = 100000
+
+type Publisher struct {
+	redis  *redis.Client
+	source string
+}
+
+func NewPublisher(client *redis.Client, source string) *Publisher {
+	return &Publisher{
+		redis:  client,
+		source: source,
+	}
+}
+
+// NewEvent builds an event envelope without publishing it, e.g. to store it
+// in an outbox first.
+func (p *Publisher) NewEvent(eventType, userID string, data interface{}) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return &Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		Version:    SchemaVersion,
+		Source:     p.source,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
+
+// Publish appends an event to its stream. Failures are logged as well as
+// returned so callers publishing in the background can ignore the error.
+func (p *Publisher) Publish(ctx context.Context, eventType, userID string, data interface{}) error {
+	event, err := p.NewEvent(eventType, userID, data)
+	if err != nil {
+		return err
+	}
+
+	return p.PublishEvent(ctx, event)
+}
+
+// PublishEvent appends an already built event, keeping its ID so consumers
+// can deduplicate events published more than once.
+func (p *Publisher) PublishEvent(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	err = p.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: Stream(event.Type),
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":  event.Type,
+			"event": string(body),
+		},
+	}).Err()
+	if err != nil {
+		log.Printf("Failed to publish %s event %s: %v", event.Type, event.ID, err)
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
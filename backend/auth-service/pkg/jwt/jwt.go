@@ -6,20 +6,37 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// Claims of an access token. The token's own ID is the registered jti
+// claim; SessionID is the login session it belongs to. Tokens issued before
+// sessions existed have none and are rejected, since they can't be revoked.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, email, secret string, expiryHours int) (string, error) {
+// Roles a user can hold. Tokens issued before roles existed carry no role
+// and are treated as RoleUser.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+func GenerateToken(userID, email, role, sessionID, secret string, ttl time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiryHours) * time.Hour)),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "fintrack-auth-service",
@@ -52,5 +69,9 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,93 @@
+// Package sessions lets every service reject access tokens of revoked
+// sessions. auth-service marks a revoked session in Redis; services check
+// the mark through a short local cache, so a revocation takes effect within
+// CacheTTL without a Redis round trip on every request.
+//
+// The same package is copied into every service; keep the copies in sync.
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheTTL is how long a service trusts what it last read about a session.
+const CacheTTL = 15 * time.Second
+
+// seenTTL outlives any session that is still in use.
+const seenTTL = 90 * 24 * time.Hour
+
+// RevokedKey marks a revoked session until its last access token expires.
+func RevokedKey(sessionID string) string {
+	return "session_revoked:" + sessionID
+}
+
+// SeenKey holds the Unix time a session was last used, to within CacheTTL.
+func SeenKey(sessionID string) string {
+	return "session_seen:" + sessionID
+}
+
+type cacheEntry struct {
+	revoked bool
+	expires time.Time
+}
+
+type Checker struct {
+	redis *redis.Client
+
+	mu        sync.Mutex
+	cache     map[string]cacheEntry
+	lastSweep time.Time
+}
+
+func NewChecker(redisClient *redis.Client) *Checker {
+	return &Checker{
+		redis: redisClient,
+		cache: map[string]cacheEntry{},
+	}
+}
+
+// Revoked reports whether the session was revoked. Reading Redis also
+// records the session as seen.
+//
+// When Redis can't be read, Revoked returns false along with the error and
+// does not cache the result: whether to let the session through is up to
+// the caller. Letting it through (failing open) means a revoked session
+// keeps working until Redis is back or its access token expires; refresh
+// still checks the database.
+func (c *Checker) Revoked(ctx context.Context, sessionID string) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.cache[sessionID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.revoked, nil
+	}
+
+	pipe := c.redis.Pipeline()
+	exists := pipe.Exists(ctx, RevokedKey(sessionID))
+	pipe.Set(ctx, SeenKey(sessionID), now.Unix(), seenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to check session %s: %w", sessionID, err)
+	}
+	revoked := exists.Val() > 0
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) > time.Minute {
+		for id, cached := range c.cache {
+			if now.After(cached.expires) {
+				delete(c.cache, id)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.cache[sessionID] = cacheEntry{revoked: revoked, expires: now.Add(CacheTTL)}
+
+	return revoked, nil
+}
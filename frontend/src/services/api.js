@@ -39,9 +39,43 @@ const requestInterceptor = config => {
 // Response interceptor for error handling
 const responseInterceptor = response => response
 
-const errorInterceptor = error => {
+// Concurrent 401s share one refresh: a refresh token works only once
+let refreshRequest = null
+
+const refreshTokens = () => {
+	if (!refreshRequest) {
+		refreshRequest = axios
+			.post(`${AUTH_URL}/api/v1/auth/refresh`, {
+				refresh_token: localStorage.getItem('refreshToken'),
+			})
+			.then(response => {
+				localStorage.setItem('token', response.data.token)
+				localStorage.setItem('refreshToken', response.data.refresh_token)
+				return response.data.token
+			})
+			.finally(() => {
+				refreshRequest = null
+			})
+	}
+	return refreshRequest
+}
+
+const errorInterceptor = async error => {
+	const request = error.config
 	if (error.response?.status === 401) {
+		// Retry once with a fresh access token
+		if (request && !request._retried && localStorage.getItem('refreshToken')) {
+			request._retried = true
+			try {
+				const token = await refreshTokens()
+				request.headers.Authorization = `Bearer ${token}`
+				return axios(request)
+			} catch (refreshError) {
+				// Fall through to a new login
+			}
+		}
 		localStorage.removeItem('token')
+		localStorage.removeItem('refreshToken')
 		window.location.href = '/login'
 	}
 	return Promise.reject(error)
@@ -88,6 +88,7 @@ export const checkAuth = createAsyncThunk(
 			return response.data
 		} catch (error) {
 			localStorage.removeItem('token')
+			localStorage.removeItem('refreshToken')
 			return rejectWithValue(
 				error.response?.data?.error || 'Authentication check failed'
 			)
@@ -132,6 +133,7 @@ const authSlice = createSlice({
 			state.user = action.payload.user
 			state.token = action.payload.token
 			localStorage.setItem('token', action.payload.token)
+			localStorage.setItem('refreshToken', action.payload.refresh_token)
 		})
 		builder.addCase(login.rejected, (state, action) => {
 			state.isLoading = false
@@ -172,6 +174,7 @@ const authSlice = createSlice({
 			state.token = null
 			state.isAuthenticated = false
 			localStorage.removeItem('token')
+			localStorage.removeItem('refreshToken')
 		})
 
 		// Check Auth